@@ -1,32 +1,62 @@
 package main
 
 import (
+	"flag"
 	"log"
+	"strings"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/brigade/drakefile"
 	"github.com/lovethedrake/canard/pkg/brigade/executor"
+	"github.com/lovethedrake/canard/pkg/githubapi"
 	"github.com/lovethedrake/canard/pkg/signals"
 	"github.com/lovethedrake/canard/pkg/version"
-	"github.com/lovethedrake/go-drake/config"
+	"github.com/lovethedrake/canard/pkg/workerconfig"
 )
 
 func main() {
+	eventPath := flag.String(
+		"event",
+		brigade.EventPath(),
+		"path to the event JSON file; use \"-\" to read the event from stdin",
+	)
+	flag.Parse()
 
 	log.Printf(
 		"Starting Canard worker -- version %s -- commit %s -- supports "+
-			"DrakeSpec %s",
+			"DrakeSpec %s (deprecated: %s)",
 		version.Version(),
 		version.Commit(),
-		config.SupportedSpecVersions,
+		strings.Join(drakefile.SupportedSpecVersions(), ", "),
+		strings.Join(drakefile.DeprecatedSpecVersions(), ", "),
 	)
 
-	event, err := brigade.LoadEvent()
+	event, err := brigade.LoadEventFromFile(*eventPath)
 	if err != nil {
 		log.Fatal(err)
 	}
 
 	ctx := signals.Context()
-	if err = executor.ExecuteBuild(ctx, event); err != nil {
+
+	// Whether the event's head is trusted is determined just once, since doing
+	// so may require use of the GitHub API. Configuration in the checkout isn't
+	// trusted for heads that may come from forks.
+	head := githubapi.HeadOf(
+		ctx,
+		event,
+		githubapi.NewGitHubBranchChecker(),
+		githubapi.NewGitHubPullRequestGetter(),
+	)
+	workerCfg, err := workerconfig.Load(
+		event,
+		drakefile.DefaultVCSRoot,
+		head.Trusted,
+	)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err = executor.ExecuteBuild(ctx, event, head, workerCfg); err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,16 @@
+package drakefile
+
+type notFoundError struct {
+	msg string
+}
+
+func (n *notFoundError) Error() string {
+	return n.msg
+}
+
+// IsNotFound returns true if the provided error indicates that no Drakefile
+// applies to an event.
+func IsNotFound(err error) bool {
+	_, ok := err.(*notFoundError)
+	return ok
+}
@@ -0,0 +1,133 @@
+package drakefile
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/pkg/errors"
+)
+
+// RevisionFetcher retrieves files as they exist at a specific revision of a
+// repository.
+type RevisionFetcher interface {
+	// Fetch returns the contents of the file at the specified path and revision
+	// of the specified repository. The repository is identified by its full name
+	// (e.g. owner/name). If no such file exists, nil is returned without error.
+	Fetch(
+		ctx context.Context,
+		event brigade.Event,
+		repo string,
+		revision string,
+		path string,
+	) ([]byte, error)
+}
+
+// gitHubFetcher is a RevisionFetcher that uses the GitHub API.
+type gitHubFetcher struct {
+	// baseURL, if non-empty, overrides the GitHub API's default base URL
+	baseURL string
+}
+
+// NewGitHubFetcher returns a RevisionFetcher that retrieves files using the
+// GitHub API. See githubapi.NewClient() for how it authenticates.
+func NewGitHubFetcher() RevisionFetcher {
+	return &gitHubFetcher{}
+}
+
+func (g *gitHubFetcher) Fetch(
+	ctx context.Context,
+	event brigade.Event,
+	repo string,
+	revision string,
+	path string,
+) ([]byte, error) {
+	repoParts := strings.SplitN(repo, "/", 2)
+	if len(repoParts) != 2 {
+		return nil, errors.Errorf("%q is not a valid repository name", repo)
+	}
+	client, err := githubapi.NewClient(event, g.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	fileContent, _, resp, err := client.Repositories.GetContents(
+		ctx,
+		repoParts[0],
+		repoParts[1],
+		path,
+		&github.RepositoryContentGetOptions{
+			Ref: revision,
+		},
+	)
+	if resp != nil && resp.StatusCode == http.StatusNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error retrieving %s from %s at %s",
+			path,
+			repo,
+			revision,
+		)
+	}
+	if fileContent == nil {
+		// It's a directory
+		return nil, nil
+	}
+	contents, err := fileContent.GetContent()
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error decoding %s from %s at %s",
+			path,
+			repo,
+			revision,
+		)
+	}
+	return []byte(contents), nil
+}
+
+// revisionLocation returns the Location of a file retrieved from the provided
+// path, relative to the root of the repository, at the specified revision of
+// the specified repository.
+func revisionLocation(repo string, revision string, path string) string {
+	return fmt.Sprintf("%s@%s:%s", repo, revision, path)
+}
+
+// fetchFirst returns the first non-empty file found among the provided paths
+// at the specified revision of the specified repository. The boolean return
+// value indicates whether any such file was found.
+func fetchFirst(
+	ctx context.Context,
+	fetcher RevisionFetcher,
+	event brigade.Event,
+	repo string,
+	revision string,
+	paths []string,
+) (Drakefile, bool, error) {
+	for _, path := range paths {
+		contents, err := fetcher.Fetch(ctx, event, repo, revision, path)
+		if err != nil {
+			return Drakefile{}, false, err
+		}
+		location := revisionLocation(repo, revision, path)
+		if len(contents) == 0 {
+			log.Printf(
+				"skipping Drakefile candidate %q: does not exist or is empty",
+				location,
+			)
+			continue
+		}
+		return Drakefile{
+			Location: location,
+			Contents: contents,
+		}, true, nil
+	}
+	return Drakefile{}, false, nil
+}
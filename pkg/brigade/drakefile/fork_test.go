@@ -0,0 +1,429 @@
+package drakefile
+
+import (
+	"context"
+	"encoding/base64"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/stretchr/testify/require"
+)
+
+// nolint: lll
+const (
+	testForkPullRequestPayload        = `{"action":"opened","pull_request":{"head":{"ref":"patch-1","repo":{"full_name":"mallory/canard"}},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}}`
+	testDeletedForkPullRequestPayload = `{"action":"opened","pull_request":{"head":{"ref":"patch-1","sha":"bad666","repo":null},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}}`
+	testPullRequestPayload            = `{"action":"opened","pull_request":{"head":{"ref":"patch-1","repo":{"full_name":"lovethedrake/canard"}},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}}`
+	// A check suite for a pull request from a fork whose head branch is named
+	// master, and one for a push to master. Since the Resolvers in these tests
+	// don't use the GitHub API, the latter is trusted only if they're told so.
+	testForkCheckSuitePayload = `{"action":"requested","check_suite":{"head_branch":"master","head_sha":"bad666","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard","default_branch":"main"}}`
+	testCheckSuitePayload     = `{"action":"requested","check_suite":{"head_branch":"master","head_sha":"def456","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard","default_branch":"main"}}`
+	// A comment on a pull request, whose head, without use of the GitHub API,
+	// isn't known.
+	testPullRequestCommentPayload = `{"action":"created","issue":{"number":42,"pull_request":{}},"comment":{"body":"/drake retest"},"repository":{"full_name":"lovethedrake/canard","default_branch":"main"}}`
+)
+
+type mockRevisionFetcher struct {
+	files map[string]string
+	err   error
+}
+
+func (m *mockRevisionFetcher) Fetch(
+	_ context.Context,
+	_ brigade.Event,
+	repo string,
+	revision string,
+	path string,
+) ([]byte, error) {
+	if m.err != nil {
+		return nil, m.err
+	}
+	contents, ok := m.files[fmt.Sprintf("%s@%s:%s", repo, revision, path)]
+	if !ok {
+		return nil, nil
+	}
+	return []byte(contents), nil
+}
+
+func TestResolveForkPullRequest(t *testing.T) {
+	const (
+		forkDrakefile = "specUri: github.com/lovethedrake/drakespec\n" +
+			"specVersion: v0.6.0\n" +
+			"jobs:\n  evil:\n    primaryContainer:\n      name: evil\n" +
+			"      image: evil\n      mountDockerSocket: true\n"
+		baseDrakefile = "specUri: github.com/lovethedrake/drakespec\n" +
+			"specVersion: v0.6.0\n"
+	)
+	forkEvent := brigade.Event{
+		Type:    "pull_request:opened",
+		Payload: testForkPullRequestPayload,
+	}
+	testCases := []struct {
+		name       string
+		resolver   func(vcsRoot string) *Resolver
+		event      brigade.Event
+		assertions func(*testing.T, Drakefile, error)
+	}{
+		{
+			name: "uses Drakefile from base",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot: vcsRoot,
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@abc123:Drakefile.yaml": baseDrakefile,
+						},
+					},
+				}
+			},
+			event: forkEvent,
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "lovethedrake/canard@abc123:Drakefile.yaml", df.Location)
+				require.Equal(t, baseDrakefile, string(df.Contents))
+			},
+		},
+		{
+			name: "uses Drakefile from base for pull request from deleted fork",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot: vcsRoot,
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@abc123:Drakefile.yaml": baseDrakefile,
+						},
+					},
+				}
+			},
+			event: brigade.Event{
+				Type:    "pull_request:opened",
+				Payload: testDeletedForkPullRequestPayload,
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "lovethedrake/canard@abc123:Drakefile.yaml", df.Location)
+			},
+		},
+		{
+			name: "falls back to project default when base can't be retrieved",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot: vcsRoot,
+					Fetcher: &mockRevisionFetcher{
+						err: fmt.Errorf("not authorized"),
+					},
+				}
+			},
+			event: brigade.Event{
+				Type:    forkEvent.Type,
+				Payload: forkEvent.Payload,
+				Worker: brigade.Worker{
+					DefaultConfigFiles: map[string]string{
+						"Drakefile.yaml": baseDrakefile,
+					},
+				},
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, ProjectWorkerTemplateLocation, df.Location)
+			},
+		},
+		{
+			name: "refuses Drakefile from fork when nothing else is available",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{VCSRoot: vcsRoot}
+			},
+			event: forkEvent,
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"refusing to use Drakefile from fork mallory/canard",
+				)
+			},
+		},
+		{
+			name: "retrieves local includes from base",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot:  vcsRoot,
+					Includes: &IncludeResolver{Root: vcsRoot},
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@abc123:.drake/Drakefile.yaml": baseDrakefile +
+								"includes:\n- path: ../shared/scan.yaml\n",
+							"lovethedrake/canard@abc123:shared/scan.yaml": testFragment,
+						},
+					},
+				}
+			},
+			event: forkEvent,
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Contains(t, string(df.Contents), "scanner:v1")
+				require.NotContains(t, string(df.Contents), "evil")
+			},
+		},
+		{
+			name: "retrieves includes manifest from base",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot:  vcsRoot,
+					Includes: &IncludeResolver{Root: vcsRoot},
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@abc123:Drakefile.yaml": baseDrakefile,
+							"lovethedrake/canard@abc123:Drakefile.includes.yaml": "includes:\n" +
+								"- path: shared/scan.yaml\n",
+							"lovethedrake/canard@abc123:shared/scan.yaml": testFragment,
+						},
+					},
+				}
+			},
+			event: forkEvent,
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Contains(t, string(df.Contents), "scanner:v1")
+			},
+		},
+		{
+			name: "rejects local includes outside base repository",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot:  vcsRoot,
+					Includes: &IncludeResolver{Root: vcsRoot},
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@abc123:Drakefile.yaml": baseDrakefile +
+								"includes:\n- path: ../Drakefile.yaml\n",
+						},
+					},
+				}
+			},
+			event: forkEvent,
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "outside the repository")
+			},
+		},
+		{
+			name: "rejects local includes without means of retrieving them",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot:  vcsRoot,
+					Includes: &IncludeResolver{Root: vcsRoot},
+				}
+			},
+			event: brigade.Event{
+				Type:    forkEvent.Type,
+				Payload: forkEvent.Payload,
+				Worker: brigade.Worker{
+					DefaultConfigFiles: map[string]string{
+						"Drakefile.yaml": baseDrakefile +
+							"includes:\n- path: Drakefile.yaml\n",
+					},
+				},
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "local includes are disabled")
+			},
+		},
+		{
+			name: "rejects local includes when base can't be retrieved",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot:  vcsRoot,
+					Includes: &IncludeResolver{Root: vcsRoot},
+					Fetcher: &mockRevisionFetcher{
+						err: fmt.Errorf("not authorized"),
+					},
+				}
+			},
+			event: brigade.Event{
+				Type:    forkEvent.Type,
+				Payload: forkEvent.Payload,
+				Worker: brigade.Worker{
+					DefaultConfigFiles: map[string]string{
+						"Drakefile.yaml": baseDrakefile +
+							"includes:\n- path: Drakefile.yaml\n",
+					},
+				},
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "local includes are disabled")
+			},
+		},
+		{
+			name: "uses Drakefile from default branch for check suite from fork",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot: vcsRoot,
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@main:Drakefile.yaml": baseDrakefile,
+						},
+					},
+				}
+			},
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: testForkCheckSuitePayload,
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "lovethedrake/canard@main:Drakefile.yaml", df.Location)
+				require.Equal(t, baseDrakefile, string(df.Contents))
+			},
+		},
+		{
+			name: "uses Drakefile from checkout for check suite on branch",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot: vcsRoot,
+					Head:    &githubapi.Head{Trusted: true},
+				}
+			},
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: testCheckSuitePayload,
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, forkDrakefile, string(df.Contents))
+			},
+		},
+		{
+			name: "uses Drakefile from default branch for comment on pull request",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot: vcsRoot,
+					Fetcher: &mockRevisionFetcher{
+						files: map[string]string{
+							"lovethedrake/canard@main:Drakefile.yaml": baseDrakefile,
+						},
+					},
+				}
+			},
+			event: brigade.Event{
+				Type:    "issue_comment:created",
+				Payload: testPullRequestCommentPayload,
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "lovethedrake/canard@main:Drakefile.yaml", df.Location)
+				require.Equal(t, baseDrakefile, string(df.Contents))
+			},
+		},
+		{
+			name: "uses checkout Drakefile for pull request from same repository",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{VCSRoot: vcsRoot}
+			},
+			event: brigade.Event{
+				Type:    "pull_request:opened",
+				Payload: testPullRequestPayload,
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, forkDrakefile, string(df.Contents))
+			},
+		},
+		{
+			name: "trusts fork when configured to",
+			resolver: func(vcsRoot string) *Resolver {
+				return &Resolver{
+					VCSRoot:               vcsRoot,
+					TrustForkPullRequests: true,
+				}
+			},
+			event: forkEvent,
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, forkDrakefile, string(df.Contents))
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			vcsRoot := t.TempDir()
+			writeFile(t, vcsRoot, "Drakefile.yaml", forkDrakefile)
+			df, err := testCase.resolver(vcsRoot).Resolve(
+				context.Background(),
+				testCase.event,
+			)
+			testCase.assertions(t, df, err)
+		})
+	}
+}
+
+func TestGitHubFetcher(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/lovethedrake/canard/contents/Drakefile.yaml":
+				if r.URL.Query().Get("ref") != "abc123" {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				fmt.Fprintf(
+					w,
+					`{"type":"file","encoding":"base64","content":%q}`,
+					base64.StdEncoding.EncodeToString([]byte("foo")),
+				)
+			case "/api/v3/repos/lovethedrake/canard/contents/forbidden.yaml":
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer server.Close()
+	fetcher := &gitHubFetcher{baseURL: server.URL + "/"}
+
+	contents, err := fetcher.Fetch(
+		context.Background(),
+		brigade.Event{},
+		"lovethedrake/canard",
+		"abc123",
+		"Drakefile.yaml",
+	)
+	require.NoError(t, err)
+	require.Equal(t, "foo", string(contents))
+
+	contents, err = fetcher.Fetch(
+		context.Background(),
+		brigade.Event{},
+		"lovethedrake/canard",
+		"abc123",
+		"Drakefile.yml",
+	)
+	require.NoError(t, err)
+	require.Nil(t, contents)
+
+	_, err = fetcher.Fetch(
+		context.Background(),
+		brigade.Event{},
+		"lovethedrake/canard",
+		"abc123",
+		"forbidden.yaml",
+	)
+	require.Error(t, err)
+
+	_, err = fetcher.Fetch(
+		context.Background(),
+		brigade.Event{},
+		"canard",
+		"abc123",
+		"Drakefile.yaml",
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not a valid repository name")
+}
@@ -0,0 +1,534 @@
+package drakefile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+const (
+	// IncludesManifestFileName is the name of an optional sidecar file that may
+	// accompany a Drakefile (in the same directory) to enumerate the fragments
+	// it includes. This is an alternative to the includes field within the
+	// Drakefile itself.
+	IncludesManifestFileName = "Drakefile.includes.yaml"
+
+	includesField   = "includes"
+	maxIncludeDepth = 10
+	maxIncludeSize  = 1 << 20 // 1 MiB
+)
+
+// Include is a reference to a Drakefile fragment that is to be merged into
+// the Drakefile that references it. Exactly one of Path or URL must be
+// specified.
+type Include struct {
+	// Path is the location of a local fragment, relative to the directory of
+	// the Drakefile that includes it.
+	Path string `json:"path,omitempty"`
+	// URL is the location of a remote fragment. Only HTTPS URLs are permitted.
+	URL string `json:"url,omitempty"`
+	// SHA256 is the expected, hex-encoded SHA-256 checksum of the fragment. This
+	// is required for remote fragments and optional for local ones.
+	SHA256 string `json:"sha256,omitempty"`
+}
+
+// IncludeResolver expands the includes of a Drakefile.
+type IncludeResolver struct {
+	// Root is the directory that local fragments must reside within. Local
+	// fragments referenced by Drakefiles that didn't originate from a file are
+	// resolved relative to this directory.
+	Root string
+	// CacheDir is a directory in which remote fragments are cached, indexed by
+	// their checksums. If empty, remote fragments are not cached.
+	CacheDir string
+	// HTTPClient is used to fetch remote fragments.
+	HTTPClient *http.Client
+	// DisableLocal, if true, causes any local include to be rejected. This is
+	// useful when the local filesystem isn't to be trusted.
+	DisableLocal bool
+
+	// revision, if non-nil, is the source of local fragments in place of the
+	// local filesystem.
+	revision *revisionIncludes
+}
+
+// revisionIncludes retrieves local fragments, and includes manifests, from a
+// revision of a repository. This is how the includes of a Drakefile that was,
+// itself, retrieved from a revision of a repository are resolved.
+type revisionIncludes struct {
+	// prefix is prepended to the path, relative to the root of the repository,
+	// of every file retrieved to form its location
+	prefix string
+	// fetch returns the contents of the file at the provided path, relative to
+	// the root of the repository, or nil if no such file exists
+	fetch func(path string) ([]byte, error)
+}
+
+// pathOf returns the path, relative to the root of the repository, of the
+// provided Drakefile if it was retrieved from the revision. The boolean return
+// value indicates whether that was the case.
+func (r *revisionIncludes) pathOf(df Drakefile) (string, bool) {
+	if !strings.HasPrefix(df.Location, r.prefix) {
+		return "", false
+	}
+	return strings.TrimPrefix(df.Location, r.prefix), true
+}
+
+// NewIncludeResolver returns an IncludeResolver that confines local fragments
+// to the specified root directory and caches remote fragments in a directory
+// beneath the system's temporary directory.
+func NewIncludeResolver(root string) *IncludeResolver {
+	return &IncludeResolver{
+		Root:     root,
+		CacheDir: filepath.Join(os.TempDir(), "canard-includes"),
+		HTTPClient: &http.Client{
+			Timeout: 30 * time.Second,
+		},
+	}
+}
+
+// Expand returns a Drakefile produced by merging all of the provided
+// Drakefile's includes, in the order they are declared, with the Drakefile
+// itself. Includes are declared either in a top-level includes field or in a
+// sidecar includes manifest. The including Drakefile takes precedence over
+// all its includes, and later includes take precedence over earlier ones.
+// Included fragments may, themselves, include other fragments. If the provided
+// Drakefile has no includes, it is returned unaltered.
+func (i *IncludeResolver) Expand(df Drakefile) (Drakefile, error) {
+	return i.expand(df, []string{})
+}
+
+func (i *IncludeResolver) expand(
+	df Drakefile,
+	ancestors []string,
+) (Drakefile, error) {
+	if len(ancestors) > maxIncludeDepth {
+		return Drakefile{}, errors.Errorf(
+			"includes of %s are nested more than %d levels deep",
+			df.Location,
+			maxIncludeDepth,
+		)
+	}
+	for _, ancestor := range ancestors {
+		if ancestor == df.Location {
+			return Drakefile{}, errors.Errorf(
+				"include cycle detected: %s -> %s",
+				strings.Join(ancestors, " -> "),
+				df.Location,
+			)
+		}
+	}
+	// Only a top-level Drakefile may be accompanied by an includes manifest.
+	// Fragments declare their own includes inline.
+	includes, df, err := i.extractIncludes(df, len(ancestors) == 0)
+	if err != nil {
+		return Drakefile{}, err
+	}
+	if len(includes) == 0 {
+		return df, nil
+	}
+	ancestors = append(ancestors, df.Location)
+	layers := make([]Drakefile, 0, len(includes)+1)
+	for _, include := range includes {
+		fragment, err := i.load(df, include)
+		if err != nil {
+			return Drakefile{}, errors.Wrapf(
+				err,
+				"error loading include of %s",
+				df.Location,
+			)
+		}
+		if fragment, err = Upgrade(fragment); err != nil {
+			return Drakefile{}, err
+		}
+		if fragment, err = i.expand(fragment, ancestors); err != nil {
+			return Drakefile{}, err
+		}
+		log.Printf("including %s in %s", fragment.Location, df.Location)
+		layers = append(layers, fragment)
+	}
+	layers = append(layers, df)
+	merged, err := Merge(layers...)
+	if err != nil {
+		return Drakefile{}, err
+	}
+	// Keep reporting the including Drakefile's location so that relative paths
+	// and logs still make sense to anyone looking at it
+	merged.Location = df.Location
+	return merged, nil
+}
+
+// extractIncludes returns the includes declared by the provided Drakefile,
+// inline or, optionally, in a sidecar manifest, along with a copy of the
+// Drakefile that has had any inline includes field removed.
+func (i *IncludeResolver) extractIncludes(
+	df Drakefile,
+	useManifest bool,
+) ([]Include, Drakefile, error) {
+	includes := []Include{}
+	if useManifest && i.revision != nil {
+		if dfPath, ok := i.revision.pathOf(df); ok {
+			manifestPath := path.Join(path.Dir(dfPath), IncludesManifestFileName)
+			manifestBytes, err := i.revision.fetch(manifestPath)
+			if err != nil {
+				return nil, df, errors.Wrapf(
+					err,
+					"error retrieving includes manifest %s%s",
+					i.revision.prefix,
+					manifestPath,
+				)
+			}
+			if manifestBytes != nil {
+				manifestIncludes, err := parseIncludesManifest(manifestBytes)
+				if err != nil {
+					return nil, df, errors.Wrapf(
+						err,
+						"error parsing includes manifest %s%s",
+						i.revision.prefix,
+						manifestPath,
+					)
+				}
+				log.Printf(
+					"using includes manifest %s%s",
+					i.revision.prefix,
+					manifestPath,
+				)
+				includes = append(includes, manifestIncludes...)
+			}
+		}
+	} else if useManifest && isFile(df) {
+		manifestPath :=
+			filepath.Join(filepath.Dir(df.Location), IncludesManifestFileName)
+		manifestBytes, err := ioutil.ReadFile(manifestPath)
+		if err != nil && !os.IsNotExist(err) {
+			return nil, df, errors.Wrapf(
+				err,
+				"error reading includes manifest %s",
+				manifestPath,
+			)
+		}
+		if err == nil {
+			manifestIncludes, err := parseIncludesManifest(manifestBytes)
+			if err != nil {
+				return nil, df, errors.Wrapf(
+					err,
+					"error parsing includes manifest %s",
+					manifestPath,
+				)
+			}
+			log.Printf("using includes manifest %s", manifestPath)
+			includes = append(includes, manifestIncludes...)
+		}
+	}
+	jsonBytes, err := yaml.YAMLToJSON(df.Contents)
+	if err != nil {
+		return nil, df, errors.Wrapf(
+			err,
+			"error converting Drakefile from %s to JSON",
+			df.Location,
+		)
+	}
+	doc := map[string]json.RawMessage{}
+	// If this fails, it's not the job of this function to complain about it
+	if json.Unmarshal(jsonBytes, &doc) != nil {
+		return includes, df, nil
+	}
+	inlineIncludesJSON, ok := doc[includesField]
+	if !ok {
+		return includes, df, nil
+	}
+	inlineIncludes := []Include{}
+	if err = json.Unmarshal(inlineIncludesJSON, &inlineIncludes); err != nil {
+		return nil, df, errors.Wrapf(
+			err,
+			"error parsing includes of Drakefile from %s",
+			df.Location,
+		)
+	}
+	includes = append(includes, inlineIncludes...)
+	delete(doc, includesField)
+	if jsonBytes, err = json.Marshal(doc); err != nil {
+		return nil, df, errors.Wrapf(
+			err,
+			"error marshaling Drakefile from %s",
+			df.Location,
+		)
+	}
+	yamlBytes, err := yaml.JSONToYAML(jsonBytes)
+	if err != nil {
+		return nil, df, errors.Wrapf(
+			err,
+			"error converting Drakefile from %s to YAML",
+			df.Location,
+		)
+	}
+	return includes, Drakefile{
+		Location: df.Location,
+		Contents: yamlBytes,
+	}, nil
+}
+
+func parseIncludesManifest(manifestBytes []byte) ([]Include, error) {
+	manifest := struct {
+		Includes []Include `json:"includes"`
+	}{}
+	if err := yaml.Unmarshal(manifestBytes, &manifest); err != nil {
+		return nil, err
+	}
+	return manifest.Includes, nil
+}
+
+// load retrieves the fragment referenced by the provided Include.
+func (i *IncludeResolver) load(
+	parent Drakefile,
+	include Include,
+) (Drakefile, error) {
+	switch {
+	case include.Path != "" && include.URL != "":
+		return Drakefile{}, errors.Errorf(
+			"include specifies both path %q and url %q; only one is permitted",
+			include.Path,
+			include.URL,
+		)
+	case include.Path != "":
+		return i.loadLocal(parent, include)
+	case include.URL != "":
+		return i.loadRemote(include)
+	default:
+		return Drakefile{}, errors.New("include specifies neither path nor url")
+	}
+}
+
+func (i *IncludeResolver) loadLocal(
+	parent Drakefile,
+	include Include,
+) (Drakefile, error) {
+	if i.DisableLocal {
+		return Drakefile{}, errors.Errorf(
+			"local include %q is not permitted; local includes are disabled",
+			include.Path,
+		)
+	}
+	if isRemote(parent) {
+		return Drakefile{}, errors.Errorf(
+			"local include %q is not permitted in remote fragment",
+			include.Path,
+		)
+	}
+	if filepath.IsAbs(include.Path) {
+		return Drakefile{}, errors.Errorf(
+			"include path %q must be relative",
+			include.Path,
+		)
+	}
+	if i.revision != nil {
+		return i.loadFromRevision(parent, include)
+	}
+	baseDir := i.Root
+	if isFile(parent) {
+		baseDir = filepath.Dir(parent.Location)
+	}
+	path := filepath.Join(baseDir, include.Path)
+	if rel, err := filepath.Rel(i.Root, path); err != nil ||
+		rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return Drakefile{}, errors.Errorf(
+			"include path %q resolves to %s, which is outside %s",
+			include.Path,
+			path,
+			i.Root,
+		)
+	}
+	contents, err := ioutil.ReadFile(path)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(err, "error reading include %s", path)
+	}
+	if include.SHA256 != "" {
+		if err = verifyChecksum(contents, include.SHA256); err != nil {
+			return Drakefile{}, errors.Wrapf(err, "error verifying %s", path)
+		}
+	}
+	return Drakefile{
+		Location: path,
+		Contents: contents,
+	}, nil
+}
+
+// loadFromRevision retrieves a local fragment from the revision of a
+// repository that the IncludeResolver's local fragments come from. Paths are
+// relative to the directory of the including Drakefile if it came from the
+// same revision and to the root of the repository otherwise.
+func (i *IncludeResolver) loadFromRevision(
+	parent Drakefile,
+	include Include,
+) (Drakefile, error) {
+	baseDir := "."
+	if parentPath, ok := i.revision.pathOf(parent); ok {
+		baseDir = path.Dir(parentPath)
+	}
+	fragmentPath := path.Join(baseDir, filepath.ToSlash(include.Path))
+	location := i.revision.prefix + fragmentPath
+	if fragmentPath == ".." || strings.HasPrefix(fragmentPath, "../") {
+		return Drakefile{}, errors.Errorf(
+			"include path %q resolves to %s, which is outside the repository",
+			include.Path,
+			location,
+		)
+	}
+	contents, err := i.revision.fetch(fragmentPath)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error retrieving include %s",
+			location,
+		)
+	}
+	if contents == nil {
+		return Drakefile{}, errors.Errorf("include %s does not exist", location)
+	}
+	if include.SHA256 != "" {
+		if err = verifyChecksum(contents, include.SHA256); err != nil {
+			return Drakefile{}, errors.Wrapf(err, "error verifying %s", location)
+		}
+	}
+	return Drakefile{
+		Location: location,
+		Contents: contents,
+	}, nil
+}
+
+func (i *IncludeResolver) loadRemote(include Include) (Drakefile, error) {
+	u, err := url.Parse(include.URL)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error parsing include url %q",
+			include.URL,
+		)
+	}
+	if u.Scheme != "https" {
+		return Drakefile{}, errors.Errorf(
+			"include url %q is not permitted; only https urls are supported",
+			include.URL,
+		)
+	}
+	if include.SHA256 == "" {
+		return Drakefile{}, errors.Errorf(
+			"include url %q must be pinned using a sha256 checksum",
+			include.URL,
+		)
+	}
+	expectedSum := strings.ToLower(include.SHA256)
+	if contents, ok := i.fromCache(expectedSum); ok {
+		log.Printf("using cached copy of include %s", include.URL)
+		return Drakefile{
+			Location: include.URL,
+			Contents: contents,
+		}, nil
+	}
+	httpClient := i.HTTPClient
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	resp, err := httpClient.Get(include.URL)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(err, "error fetching %s", include.URL)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return Drakefile{}, errors.Errorf(
+			"error fetching %s: received status code %d",
+			include.URL,
+			resp.StatusCode,
+		)
+	}
+	contents, err := ioutil.ReadAll(
+		&io.LimitedReader{R: resp.Body, N: maxIncludeSize + 1},
+	)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(err, "error reading %s", include.URL)
+	}
+	if len(contents) > maxIncludeSize {
+		return Drakefile{}, errors.Errorf(
+			"%s exceeds the maximum permitted size of %d bytes",
+			include.URL,
+			maxIncludeSize,
+		)
+	}
+	if err = verifyChecksum(contents, expectedSum); err != nil {
+		return Drakefile{}, errors.Wrapf(err, "error verifying %s", include.URL)
+	}
+	i.toCache(expectedSum, contents)
+	return Drakefile{
+		Location: include.URL,
+		Contents: contents,
+	}, nil
+}
+
+// fromCache returns cached contents having the specified checksum. The
+// boolean return value indicates whether the cache contained such contents.
+func (i *IncludeResolver) fromCache(sum string) ([]byte, bool) {
+	if i.CacheDir == "" {
+		return nil, false
+	}
+	contents, err := ioutil.ReadFile(filepath.Join(i.CacheDir, sum))
+	if err != nil {
+		return nil, false
+	}
+	// Don't trust the cache blindly
+	if verifyChecksum(contents, sum) != nil {
+		return nil, false
+	}
+	return contents, true
+}
+
+// toCache makes a best effort at caching the provided contents. Failures are
+// logged, but are otherwise inconsequential.
+func (i *IncludeResolver) toCache(sum string, contents []byte) {
+	if i.CacheDir == "" {
+		return
+	}
+	if err := os.MkdirAll(i.CacheDir, 0755); err != nil {
+		log.Printf("error creating include cache %s: %s", i.CacheDir, err)
+		return
+	}
+	cachePath := filepath.Join(i.CacheDir, sum)
+	if err := ioutil.WriteFile(cachePath, contents, 0644); err != nil {
+		log.Printf("error caching include at %s: %s", cachePath, err)
+	}
+}
+
+func verifyChecksum(contents []byte, expectedSum string) error {
+	sum := sha256.Sum256(contents)
+	actualSum := hex.EncodeToString(sum[:])
+	if actualSum != strings.ToLower(expectedSum) {
+		return errors.Errorf(
+			"sha256 checksum %s does not match expected checksum %s",
+			actualSum,
+			expectedSum,
+		)
+	}
+	return nil
+}
+
+// isFile returns true if the provided Drakefile originated from a local file.
+func isFile(df Drakefile) bool {
+	return filepath.IsAbs(df.Location)
+}
+
+// isRemote returns true if the provided Drakefile originated from a URL.
+func isRemote(df Drakefile) bool {
+	return strings.HasPrefix(df.Location, "https://")
+}
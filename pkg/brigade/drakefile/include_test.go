@@ -0,0 +1,325 @@
+package drakefile
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"testing"
+
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/stretchr/testify/require"
+)
+
+const (
+	testIncludingDrakefile = `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang:1.15
+`
+	testFragment = `
+jobs:
+  scan:
+    primaryContainer:
+      name: scanner
+      image: scanner:v1
+  test:
+    primaryContainer:
+      name: go
+      image: golang:1.14
+`
+)
+
+func TestExpand(t *testing.T) {
+	fragmentSum := sha256.Sum256([]byte(testFragment))
+	fragmentSHA256 := hex.EncodeToString(fragmentSum[:])
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/scan.yaml" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			fmt.Fprint(w, testFragment)
+		}),
+	)
+	defer server.Close()
+
+	testCases := []struct {
+		name       string
+		setup      func(t *testing.T, root string)
+		drakefile  func(root string) Drakefile
+		assertions func(t *testing.T, df Drakefile, err error)
+	}{
+		{
+			name: "no includes",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte(testIncludingDrakefile),
+				}
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, testIncludingDrakefile, string(df.Contents))
+			},
+		},
+		{
+			name: "inline local include",
+			setup: func(t *testing.T, root string) {
+				writeFile(t, root, "shared/scan.yaml", testFragment)
+			},
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte(
+						testIncludingDrakefile +
+							"includes:\n- path: shared/scan.yaml\n",
+					),
+				}
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				jobs, err := cfg.Jobs("scan", "test")
+				require.NoError(t, err)
+				require.Equal(t, "scanner:v1", jobs[0].PrimaryContainer().Image())
+				// The including Drakefile takes precedence over the fragment
+				require.Equal(t, "golang:1.15", jobs[1].PrimaryContainer().Image())
+			},
+		},
+		{
+			name: "includes manifest",
+			setup: func(t *testing.T, root string) {
+				writeFile(t, root, "shared/scan.yaml", testFragment)
+				writeFile(
+					t,
+					root,
+					IncludesManifestFileName,
+					"includes:\n- path: shared/scan.yaml\n",
+				)
+			},
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte(testIncludingDrakefile),
+				}
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				require.Len(t, cfg.AllJobs(), 2)
+			},
+		},
+		{
+			name: "local include with checksum mismatch",
+			setup: func(t *testing.T, root string) {
+				writeFile(t, root, "shared/scan.yaml", testFragment)
+			},
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte(
+						"includes:\n- path: shared/scan.yaml\n  sha256: abc\n",
+					),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not match expected checksum")
+			},
+		},
+		{
+			name: "local include outside root",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte("includes:\n- path: ../scan.yaml\n"),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "which is outside")
+			},
+		},
+		{
+			name: "include cycle",
+			setup: func(t *testing.T, root string) {
+				writeFile(t, root, "a.yaml", "includes:\n- path: b.yaml\n")
+				writeFile(t, root, "b.yaml", "includes:\n- path: a.yaml\n")
+			},
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte("includes:\n- path: a.yaml\n"),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "include cycle detected")
+			},
+		},
+		{
+			name: "include with both path and url",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: filepath.Join(root, "Drakefile.yaml"),
+					Contents: []byte(
+						"includes:\n- path: a.yaml\n  url: https://example.com/a.yaml\n",
+					),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "only one is permitted")
+			},
+		},
+		{
+			name: "remote include",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: ProjectWorkerTemplateLocation,
+					Contents: []byte(
+						fmt.Sprintf(
+							"%sincludes:\n- url: %s/scan.yaml\n  sha256: %s\n",
+							testIncludingDrakefile,
+							server.URL,
+							fragmentSHA256,
+						),
+					),
+				}
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, ProjectWorkerTemplateLocation, df.Location)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				require.Len(t, cfg.AllJobs(), 2)
+			},
+		},
+		{
+			name: "remote include without checksum",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: ProjectWorkerTemplateLocation,
+					Contents: []byte(
+						fmt.Sprintf("includes:\n- url: %s/scan.yaml\n", server.URL),
+					),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "must be pinned")
+			},
+		},
+		{
+			name: "remote include with checksum mismatch",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: ProjectWorkerTemplateLocation,
+					Contents: []byte(
+						fmt.Sprintf(
+							"includes:\n- url: %s/scan.yaml\n  sha256: %x\n",
+							server.URL,
+							sha256.Sum256([]byte("foo")),
+						),
+					),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "does not match expected checksum")
+			},
+		},
+		{
+			name: "remote include not found",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: ProjectWorkerTemplateLocation,
+					Contents: []byte(
+						fmt.Sprintf(
+							"includes:\n- url: %s/nope.yaml\n  sha256: %s\n",
+							server.URL,
+							fragmentSHA256,
+						),
+					),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "received status code 404")
+			},
+		},
+		{
+			name: "non-https remote include",
+			drakefile: func(root string) Drakefile {
+				return Drakefile{
+					Location: ProjectWorkerTemplateLocation,
+					Contents: []byte(
+						"includes:\n- url: http://example.com/a.yaml\n  sha256: abc\n",
+					),
+				}
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "only https urls are supported")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			root := t.TempDir()
+			if testCase.setup != nil {
+				testCase.setup(t, root)
+			}
+			i := &IncludeResolver{
+				Root:       root,
+				HTTPClient: server.Client(),
+			}
+			df, err := i.Expand(testCase.drakefile(root))
+			testCase.assertions(t, df, err)
+		})
+	}
+}
+
+func TestExpandUsesCache(t *testing.T) {
+	fragmentSum := sha256.Sum256([]byte(testFragment))
+	fragmentSHA256 := hex.EncodeToString(fragmentSum[:])
+	var requests int
+	server := httptest.NewTLSServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			requests++
+			fmt.Fprint(w, testFragment)
+		}),
+	)
+	defer server.Close()
+	i := &IncludeResolver{
+		Root:       t.TempDir(),
+		CacheDir:   t.TempDir(),
+		HTTPClient: server.Client(),
+	}
+	df := Drakefile{
+		Location: ProjectWorkerTemplateLocation,
+		Contents: []byte(
+			fmt.Sprintf(
+				"%sincludes:\n- url: %s/scan.yaml\n  sha256: %s\n",
+				testIncludingDrakefile,
+				server.URL,
+				fragmentSHA256,
+			),
+		),
+	}
+	for j := 0; j < 2; j++ {
+		expanded, err := i.Expand(df)
+		require.NoError(t, err)
+		require.Contains(t, string(expanded.Contents), "scanner:v1")
+	}
+	require.Equal(t, 1, requests)
+}
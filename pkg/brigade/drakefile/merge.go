@@ -0,0 +1,138 @@
+package drakefile
+
+import (
+	"encoding/json"
+	"log"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/pkg/errors"
+)
+
+// mergeableSections are the top-level sections of a Drakefile whose entries
+// are merged, by name, across layers.
+var mergeableSections = []string{"snippets", "jobs", "pipelines"}
+
+// Merge combines the provided Drakefiles into a single Drakefile. Drakefiles
+// are provided in order of increasing precedence. The following rules apply:
+//
+//  1. specUri and specVersion may be omitted from any Drakefile, but wherever
+//     they are specified, they must agree with one another. Disagreement is an
+//     error.
+//  2. Entries in the snippets, jobs, and pipelines sections are merged by
+//     name. When more than one Drakefile defines an entry having the same
+//     name, the definition from the Drakefile with the greatest precedence
+//     replaces the others wholesale. Such overrides are logged.
+//  3. Any other top-level field is taken from the Drakefile with the greatest
+//     precedence that defines it. (Schema validation, which happens later,
+//     will reject any such field that isn't permitted.)
+//
+// If exactly one Drakefile is provided, it is returned unaltered.
+func Merge(drakefiles ...Drakefile) (Drakefile, error) {
+	if len(drakefiles) == 0 {
+		return Drakefile{}, errors.New("no Drakefiles to merge")
+	}
+	if len(drakefiles) == 1 {
+		return drakefiles[0], nil
+	}
+	merged := map[string]interface{}{}
+	// Tracks where each top-level field or section entry was defined so that
+	// conflicts and overrides can be reported meaningfully
+	origins := map[string]string{}
+	locations := make([]string, len(drakefiles))
+	for i, df := range drakefiles {
+		locations[i] = df.Location
+		jsonBytes, err := yaml.YAMLToJSON(df.Contents)
+		if err != nil {
+			return Drakefile{}, errors.Wrapf(
+				err,
+				"error converting Drakefile from %s to JSON",
+				df.Location,
+			)
+		}
+		doc := map[string]interface{}{}
+		if err = json.Unmarshal(jsonBytes, &doc); err != nil {
+			return Drakefile{}, errors.Wrapf(
+				err,
+				"error parsing Drakefile from %s; it may not be a map",
+				df.Location,
+			)
+		}
+		for key, value := range doc {
+			switch {
+			case key == "specUri" || key == "specVersion":
+				if existing, ok := merged[key]; ok && existing != value {
+					return Drakefile{}, errors.Errorf(
+						"%s %v from %s conflicts with %s %v from %s",
+						key,
+						value,
+						df.Location,
+						key,
+						existing,
+						origins[key],
+					)
+				}
+				merged[key] = value
+				origins[key] = df.Location
+			case isMergeableSection(key):
+				entries, ok := value.(map[string]interface{})
+				if !ok {
+					if value == nil {
+						continue
+					}
+					return Drakefile{}, errors.Errorf(
+						"field %q of Drakefile from %s is not a map",
+						key,
+						df.Location,
+					)
+				}
+				mergedEntries, ok := merged[key].(map[string]interface{})
+				if !ok {
+					mergedEntries = map[string]interface{}{}
+					merged[key] = mergedEntries
+				}
+				for name, entry := range entries {
+					originKey := key + "." + name
+					if origin, ok := origins[originKey]; ok {
+						log.Printf(
+							"%s %q from %s overrides definition from %s",
+							strings.TrimSuffix(key, "s"),
+							name,
+							df.Location,
+							origin,
+						)
+					}
+					mergedEntries[name] = entry
+					origins[originKey] = df.Location
+				}
+			default:
+				merged[key] = value
+				origins[key] = df.Location
+			}
+		}
+	}
+	jsonBytes, err := json.Marshal(merged)
+	if err != nil {
+		return Drakefile{}, errors.Wrap(err, "error marshaling merged Drakefile")
+	}
+	yamlBytes, err := yaml.JSONToYAML(jsonBytes)
+	if err != nil {
+		return Drakefile{}, errors.Wrap(
+			err,
+			"error converting merged Drakefile to YAML",
+		)
+	}
+	return Drakefile{
+		Location: strings.Join(locations, " + "),
+		Contents: yamlBytes,
+	}, nil
+}
+
+func isMergeableSection(key string) bool {
+	for _, section := range mergeableSections {
+		if key == section {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,140 @@
+package drakefile
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMerge(t *testing.T) {
+	testCases := []struct {
+		name       string
+		drakefiles []Drakefile
+		assertions func(*testing.T, Drakefile, error)
+	}{
+		{
+			name: "no Drakefiles",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "no Drakefiles to merge")
+			},
+		},
+		{
+			name: "single Drakefile is returned unaltered",
+			drakefiles: []Drakefile{
+				{
+					Location: "foo",
+					Contents: []byte("not: [valid"),
+				},
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "foo", df.Location)
+				require.Equal(t, "not: [valid", string(df.Contents))
+			},
+		},
+		{
+			name: "conflicting spec versions",
+			drakefiles: []Drakefile{
+				{
+					Location: "foo",
+					Contents: []byte("specVersion: v0.6.0"),
+				},
+				{
+					Location: "bar",
+					Contents: []byte("specVersion: v0.7.0"),
+				},
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"specVersion v0.7.0 from bar conflicts with specVersion v0.6.0 "+
+						"from foo",
+				)
+			},
+		},
+		{
+			name: "section that isn't a map",
+			drakefiles: []Drakefile{
+				{
+					Location: "foo",
+					Contents: []byte("jobs: {}"),
+				},
+				{
+					Location: "bar",
+					Contents: []byte("jobs: [foo]"),
+				},
+			},
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), `field "jobs" of Drakefile from bar`)
+			},
+		},
+		{
+			name: "jobs and pipelines are merged by name",
+			drakefiles: []Drakefile{
+				{
+					Location: "project",
+					Contents: []byte(`
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+snippets:
+  base: &base
+    name: go
+    image: golang:1.15
+jobs:
+  scan:
+    primaryContainer:
+      name: scanner
+      image: scanner:v1
+  test:
+    primaryContainer:
+      <<: *base
+pipelines:
+  security:
+    jobs:
+    - name: scan
+`),
+				},
+				{
+					Location: "repo",
+					Contents: []byte(`
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang:1.16
+pipelines:
+  ci:
+    jobs:
+    - name: test
+`),
+				},
+			},
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "project + repo", df.Location)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				jobs := cfg.AllJobs()
+				require.Len(t, jobs, 2)
+				require.Equal(t, "scan", jobs[0].Name())
+				require.Equal(t, "test", jobs[1].Name())
+				require.Equal(t, "golang:1.16", jobs[1].PrimaryContainer().Image())
+				pipelines := cfg.AllPipelines()
+				require.Len(t, pipelines, 2)
+				require.Equal(t, "ci", pipelines[0].Name())
+				require.Equal(t, "security", pipelines[1].Name())
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			df, err := Merge(testCase.drakefiles...)
+			testCase.assertions(t, df, err)
+		})
+	}
+}
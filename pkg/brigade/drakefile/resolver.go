@@ -0,0 +1,465 @@
+package drakefile
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	// DefaultVCSRoot is the directory into which Brigade checks out a project's
+	// source code.
+	DefaultVCSRoot = "/var/vcs"
+	// ProjectWorkerTemplateLocation is the Location reported for a Drakefile
+	// that was found among the project worker template's default config files.
+	ProjectWorkerTemplateLocation = "project worker template"
+
+	branchRefPrefix = "refs/heads/"
+)
+
+// FileNames enumerates, in order of preference, the names of files that are
+// recognized as Drakefiles. These are relative to whatever directory is being
+// searched.
+var FileNames = []string{
+	"Drakefile.yaml",
+	"Drakefile.yml",
+	filepath.Join(".drake", "Drakefile.yaml"),
+	filepath.Join(".drake", "Drakefile.yml"),
+}
+
+// nolint: lll
+var defaultLegacyLocations = []string{
+	"/etc/brigade/script",                        // data mounted from event secret (e.g. brig run)
+	"/vcs/Drakefile.yaml",                        // checked out in repo
+	"/etc/brigade-project/defaultScript",         // data mounted from project.DefaultScript
+	"/etc/brigade-default-script/Drakefile.yaml", // mounted configmap named in brigade.sh/project.DefaultScriptName
+}
+
+// Drakefile represents the raw contents of a Drakefile along with a
+// description of where it was found.
+type Drakefile struct {
+	// Location is a path, or, for Drakefiles that didn't originate from a file,
+	// a description of where the Drakefile came from.
+	Location string
+	// Contents is the unparsed Drakefile.
+	Contents []byte
+}
+
+// Resolver locates the Drakefile that should drive a build.
+type Resolver struct {
+	// VCSRoot is the directory into which the project's source code has been
+	// checked out. Worker.ConfigFilesDirectory is resolved relative to this.
+	VCSRoot string
+	// LegacyLocations are absolute paths, inherited from Brigade v1, that are
+	// checked after all locations within VCSRoot.
+	LegacyLocations []string
+	// Includes, if non-nil, is used to expand the includes of every layer.
+	Includes *IncludeResolver
+	// TrustForkPullRequests, if true, permits Drakefiles from the checkout to be
+	// used even when the event's head may come from a fork. By default, such
+	// Drakefiles are ignored because anyone can author them, and the repository
+	// Drakefile is instead retrieved, using Fetcher, from the head's base.
+	TrustForkPullRequests bool
+	// Fetcher retrieves the repository Drakefile from the base of untrusted
+	// heads. If nil, only the project default Drakefile is used for them.
+	Fetcher RevisionFetcher
+	// Head, if non-nil, is the event's head as determined by
+	// githubapi.HeadOf(). If nil, it is determined without use of the GitHub
+	// API, so the heads of check suites and of pull requests commented on
+	// aren't trusted.
+	Head *githubapi.Head
+	// TemplateEnvAllowlist enumerates environment variables that are exposed to
+	// Drakefile templates.
+	TemplateEnvAllowlist []string
+	// FileNames, if non-empty, overrides the package-level FileNames.
+	FileNames []string
+	// DisableTemplates, if true, causes Drakefiles to be used as-is even if they
+	// opt into templating.
+	DisableTemplates bool
+	// DisableBranchOverlays, if true, causes branch overlays to be ignored.
+	DisableBranchOverlays bool
+	// Dir, if non-empty, is a directory, relative to VCSRoot, that is searched
+	// for Drakefiles instead of Worker.ConfigFilesDirectory and the root of the
+	// checkout. Neither the project default Drakefile nor legacy locations are
+	// used. This is how the Drakefiles of individual services within a monorepo
+	// are resolved. See Services().
+	Dir string
+}
+
+// NewResolver returns a Resolver that uses DefaultVCSRoot and Brigade v1's
+// well-known Drakefile locations, that expands includes, and that retrieves
+// Drakefiles for pull requests originating from forks from the pull request's
+// base using the GitHub API.
+func NewResolver() *Resolver {
+	legacyLocations := make([]string, len(defaultLegacyLocations))
+	copy(legacyLocations, defaultLegacyLocations)
+	return &Resolver{
+		VCSRoot:         DefaultVCSRoot,
+		LegacyLocations: legacyLocations,
+		Includes:        NewIncludeResolver(DefaultVCSRoot),
+		Fetcher:         NewGitHubFetcher(),
+	}
+}
+
+// Candidates returns, in order of preference, every path at which a Drakefile
+// will be searched for. Files within the directory indicated by
+// Worker.ConfigFilesDirectory are preferred, followed by files at the root of
+// the checkout, followed by legacy locations. If Dir is non-empty, only files
+// within that directory are candidates.
+func (r *Resolver) Candidates(event brigade.Event) []string {
+	candidates := []string{}
+	for _, dir := range r.searchDirs(event) {
+		for _, fileName := range r.fileNames() {
+			candidates = append(candidates, filepath.Join(dir, fileName))
+		}
+	}
+	if r.Dir != "" {
+		return candidates
+	}
+	return append(candidates, r.LegacyLocations...)
+}
+
+// BranchOverlayCandidates returns, in order of preference, every path at which
+// an overlay specific to the event's branch will be searched for. Branch
+// overlays live beneath .drake/branches/ in the same directories that are
+// searched for the repository's Drakefile. An overlay for the branch
+// release/v1, for instance, may be located at .drake/branches/release/v1.yaml.
+// If the event doesn't pertain to a branch, nil is returned.
+func (r *Resolver) BranchOverlayCandidates(event brigade.Event) []string {
+	branch := branchOf(event)
+	if branch == "" {
+		return nil
+	}
+	candidates := []string{}
+	for _, dir := range r.searchDirs(event) {
+		for _, ext := range []string{".yaml", ".yml"} {
+			candidates = append(
+				candidates,
+				filepath.Join(dir, ".drake", "branches", branch+ext),
+			)
+		}
+	}
+	return candidates
+}
+
+// Layers returns, in order of increasing precedence, every Drakefile that
+// applies to the event. These are:
+//
+//  1. The project default Drakefile, from the project worker template's
+//     default config files, if one exists.
+//  2. The repository Drakefile; the first non-empty file found among
+//     Candidates(), if any.
+//  3. The branch overlay; the first non-empty file found among
+//     BranchOverlayCandidates(), if any.
+//
+// Every candidate that is skipped is logged along with the reason for skipping
+// it. Each layer that opts into templating is rendered (see Render()), is
+// upgraded if it declares a deprecated specVersion (see Upgrade()), and then,
+// if the Resolver has an IncludeResolver, has its includes expanded. An error
+// is returned if no Drakefile applies to the event.
+//
+// Unless TrustForkPullRequests is true, if the event's head isn't trusted (see
+// githubapi.Head), nothing from the checkout is used. The repository Drakefile
+// is instead retrieved from the head's base and there is no branch overlay.
+// Local includes are retrieved from the base as well or, if there is no
+// Fetcher or the base's Drakefile couldn't be retrieved, rejected.
+func (r *Resolver) Layers(
+	ctx context.Context,
+	event brigade.Event,
+) ([]Drakefile, error) {
+	layers := []Drakefile{}
+	if r.Dir == "" {
+		if df, ok := projectDefault(event); ok {
+			log.Printf("using project default Drakefile from %s", df.Location)
+			layers = append(layers, df)
+		}
+	}
+	includes := r.Includes
+	head := r.head(ctx, event)
+	if !head.Trusted && !r.TrustForkPullRequests {
+		log.Printf(
+			"head of event may come from %s rather than %s; ignoring Drakefiles "+
+				"in the checkout",
+			head,
+			head.BaseRepo,
+		)
+		df, ok, baseErr := r.baseDrakefile(ctx, event, head)
+		if baseErr != nil {
+			log.Printf(
+				"error retrieving Drakefile from %s at %s; rejecting local includes: "+
+					"%s",
+				head.BaseRepo,
+				head.BaseRevision,
+				baseErr,
+			)
+		}
+		if ok {
+			log.Printf(
+				"using repository Drakefile %q from %s at %s",
+				df.Location,
+				head.BaseRepo,
+				head.BaseRevision,
+			)
+			layers = append(layers, df)
+		}
+		if len(layers) == 0 {
+			return nil, &notFoundError{
+				msg: fmt.Sprintf(
+					"refusing to use Drakefile from %s; could not locate a "+
+						"Drakefile in the project worker template or in %s at %s",
+					head,
+					head.BaseRepo,
+					head.BaseRevision,
+				),
+			}
+		}
+		if includes != nil {
+			untrustedIncludes := *includes
+			if r.Fetcher != nil && baseErr == nil {
+				untrustedIncludes.revision = &revisionIncludes{
+					prefix: revisionLocation(head.BaseRepo, head.BaseRevision, ""),
+					fetch: func(path string) ([]byte, error) {
+						return r.Fetcher.Fetch(
+							ctx,
+							event,
+							head.BaseRepo,
+							head.BaseRevision,
+							path,
+						)
+					},
+				}
+			} else {
+				untrustedIncludes.DisableLocal = true
+			}
+			includes = &untrustedIncludes
+		}
+	} else {
+		df, ok, err := firstFile(r.Candidates(event))
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			log.Printf("using repository Drakefile %q", df.Location)
+			layers = append(layers, df)
+		}
+		if !r.DisableBranchOverlays {
+			if df, ok, err = firstFile(r.BranchOverlayCandidates(event)); err != nil {
+				return nil, err
+			}
+			if ok {
+				log.Printf("using branch overlay %q", df.Location)
+				layers = append(layers, df)
+			}
+		}
+	}
+	if len(layers) == 0 {
+		return nil, &notFoundError{msg: "could not locate Drakefile.yaml"}
+	}
+	templateData := NewTemplateData(event, r.TemplateEnvAllowlist)
+	for i, layer := range layers {
+		var err error
+		if !r.DisableTemplates {
+			if layers[i], err = Render(layer, templateData); err != nil {
+				return nil, err
+			}
+		}
+		if layers[i], err = Upgrade(layers[i]); err != nil {
+			return nil, err
+		}
+	}
+	if includes != nil {
+		for i, layer := range layers {
+			var err error
+			if layers[i], err = includes.Expand(layer); err != nil {
+				return nil, errors.Wrapf(
+					err,
+					"error expanding includes of %s",
+					layer.Location,
+				)
+			}
+		}
+	}
+	return layers, nil
+}
+
+// Resolve returns a single Drakefile produced by merging all of the
+// Drakefiles returned by Layers(). See Merge() for details of how conflicts
+// are handled.
+func (r *Resolver) Resolve(
+	ctx context.Context,
+	event brigade.Event,
+) (Drakefile, error) {
+	layers, err := r.Layers(ctx, event)
+	if err != nil {
+		return Drakefile{}, err
+	}
+	return Merge(layers...)
+}
+
+// head returns the event's head. See Head.
+func (r *Resolver) head(
+	ctx context.Context,
+	event brigade.Event,
+) githubapi.Head {
+	if r.Head != nil {
+		return *r.Head
+	}
+	return githubapi.HeadOf(ctx, event, nil, nil)
+}
+
+// baseDrakefile retrieves the repository Drakefile from the base of the
+// provided head. The boolean return value indicates whether one was found.
+func (r *Resolver) baseDrakefile(
+	ctx context.Context,
+	event brigade.Event,
+	head githubapi.Head,
+) (Drakefile, bool, error) {
+	if r.Fetcher == nil {
+		log.Printf("no means of retrieving Drakefile from %s", head.BaseRepo)
+		return Drakefile{}, false, nil
+	}
+	rootDir := filepath.Clean(r.VCSRoot)
+	paths := []string{}
+	for _, dir := range r.searchDirs(event) {
+		relDir, err := filepath.Rel(rootDir, dir)
+		if err != nil {
+			continue
+		}
+		for _, fileName := range r.fileNames() {
+			paths = append(
+				paths,
+				filepath.ToSlash(filepath.Join(relDir, fileName)),
+			)
+		}
+	}
+	return fetchFirst(
+		ctx,
+		r.Fetcher,
+		event,
+		head.BaseRepo,
+		head.BaseRevision,
+		paths,
+	)
+}
+
+// fileNames returns the names of files that are recognized as Drakefiles.
+func (r *Resolver) fileNames() []string {
+	if len(r.FileNames) > 0 {
+		return r.FileNames
+	}
+	return FileNames
+}
+
+// searchDirs returns, in order of preference, every directory in which
+// Drakefiles are searched for.
+func (r *Resolver) searchDirs(event brigade.Event) []string {
+	rootDir := filepath.Clean(r.VCSRoot)
+	if r.Dir != "" {
+		return []string{filepath.Join(rootDir, r.Dir)}
+	}
+	dirs := []string{}
+	if event.Worker.ConfigFilesDirectory != "" {
+		configFilesDir :=
+			filepath.Join(rootDir, event.Worker.ConfigFilesDirectory)
+		if configFilesDir != rootDir {
+			dirs = append(dirs, configFilesDir)
+		}
+	}
+	return append(dirs, rootDir)
+}
+
+// firstFile returns the first non-empty file found among the provided
+// candidates. The boolean return value indicates whether any such file was
+// found.
+func firstFile(candidates []string) (Drakefile, bool, error) {
+	for _, candidate := range candidates {
+		fileInfo, err := os.Stat(candidate)
+		if err != nil {
+			if os.IsNotExist(err) {
+				log.Printf(
+					"skipping Drakefile candidate %q: does not exist",
+					candidate,
+				)
+				continue
+			}
+			return Drakefile{}, false, errors.Wrapf(
+				err,
+				"error getting info for file %q",
+				candidate,
+			)
+		}
+		if fileInfo.IsDir() {
+			log.Printf(
+				"skipping Drakefile candidate %q: is a directory",
+				candidate,
+			)
+			continue
+		}
+		if fileInfo.Size() == 0 {
+			log.Printf(
+				"skipping Drakefile candidate %q: is empty",
+				candidate,
+			)
+			continue
+		}
+		contents, err := ioutil.ReadFile(candidate)
+		if err != nil {
+			return Drakefile{}, false, errors.Wrapf(
+				err,
+				"error reading Drakefile at %s",
+				candidate,
+			)
+		}
+		return Drakefile{
+			Location: candidate,
+			Contents: contents,
+		}, true, nil
+	}
+	return Drakefile{}, false, nil
+}
+
+// projectDefault returns the Drakefile, if any, from the project worker
+// template's default config files. The boolean return value indicates whether
+// one was found.
+func projectDefault(event brigade.Event) (Drakefile, bool) {
+	for _, fileName := range []string{"Drakefile.yaml", "Drakefile.yml"} {
+		if contents, ok := event.Worker.DefaultConfigFiles[fileName]; ok &&
+			contents != "" {
+			return Drakefile{
+				Location: ProjectWorkerTemplateLocation,
+				Contents: []byte(contents),
+			}, true
+		}
+	}
+	return Drakefile{}, false
+}
+
+// branchOf returns the name of the branch the event pertains to, or an empty
+// string if the event doesn't pertain to a branch.
+func branchOf(event brigade.Event) string {
+	var ref string
+	if event.Git != nil {
+		ref = event.Git.Ref
+	}
+	if ref == "" {
+		ref = event.Worker.Git.Ref
+	}
+	if !strings.HasPrefix(ref, branchRefPrefix) {
+		return ""
+	}
+	branch := strings.TrimPrefix(ref, branchRefPrefix)
+	// Git won't permit this in a ref name anyway, but guard against escaping
+	// the directories being searched
+	if strings.Contains(branch, "..") {
+		return ""
+	}
+	return branch
+}
@@ -0,0 +1,308 @@
+package drakefile
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v2/core"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCandidates(t *testing.T) {
+	testCases := []struct {
+		name                 string
+		configFilesDirectory string
+		assertions           func(*testing.T, []string)
+	}{
+		{
+			name: "no config files directory",
+			assertions: func(t *testing.T, candidates []string) {
+				require.Equal(
+					t,
+					[]string{
+						"/vcs-root/Drakefile.yaml",
+						"/vcs-root/Drakefile.yml",
+						"/vcs-root/.drake/Drakefile.yaml",
+						"/vcs-root/.drake/Drakefile.yml",
+						"/legacy/Drakefile.yaml",
+					},
+					candidates,
+				)
+			},
+		},
+		{
+			name:                 "config files directory is the root",
+			configFilesDirectory: ".",
+			assertions: func(t *testing.T, candidates []string) {
+				require.Len(t, candidates, 5)
+				require.Equal(t, "/vcs-root/Drakefile.yaml", candidates[0])
+			},
+		},
+		{
+			name:                 "config files directory",
+			configFilesDirectory: "examples/01-hello-world/.brigade",
+			assertions: func(t *testing.T, candidates []string) {
+				require.Equal(
+					t,
+					[]string{
+						"/vcs-root/examples/01-hello-world/.brigade/Drakefile.yaml",
+						"/vcs-root/examples/01-hello-world/.brigade/Drakefile.yml",
+						"/vcs-root/examples/01-hello-world/.brigade/.drake/Drakefile.yaml",
+						"/vcs-root/examples/01-hello-world/.brigade/.drake/Drakefile.yml",
+						"/vcs-root/Drakefile.yaml",
+						"/vcs-root/Drakefile.yml",
+						"/vcs-root/.drake/Drakefile.yaml",
+						"/vcs-root/.drake/Drakefile.yml",
+						"/legacy/Drakefile.yaml",
+					},
+					candidates,
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r := &Resolver{
+				VCSRoot:         "/vcs-root",
+				LegacyLocations: []string{"/legacy/Drakefile.yaml"},
+			}
+			candidates := r.Candidates(
+				brigade.Event{
+					Worker: brigade.Worker{
+						ConfigFilesDirectory: testCase.configFilesDirectory,
+					},
+				},
+			)
+			testCase.assertions(t, candidates)
+		})
+	}
+}
+
+func TestResolve(t *testing.T) {
+	const drakefileContents = "specUri: github.com/lovethedrake/drakespec\n"
+	const projectDrakefile = `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  security-scan:
+    primaryContainer:
+      name: scanner
+      image: scanner:v1
+`
+	const repoDrakefile = `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang:1.15
+`
+	const branchOverlay = `
+jobs:
+  test:
+    primaryContainer:
+      name: go
+      image: golang:1.14
+`
+	testCases := []struct {
+		name       string
+		setup      func(t *testing.T, vcsRoot string)
+		event      brigade.Event
+		assertions func(t *testing.T, vcsRoot string, df Drakefile, err error)
+	}{
+		{
+			name: "no Drakefile anywhere",
+			assertions: func(t *testing.T, _ string, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "could not locate Drakefile.yaml")
+			},
+		},
+		{
+			name: "Drakefile in config files directory",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, ".brigade/Drakefile.yaml", drakefileContents)
+				writeFile(t, vcsRoot, "Drakefile.yaml", "root")
+			},
+			event: brigade.Event{
+				Worker: brigade.Worker{
+					ConfigFilesDirectory: ".brigade",
+				},
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					filepath.Join(vcsRoot, ".brigade", "Drakefile.yaml"),
+					df.Location,
+				)
+				require.Equal(t, drakefileContents, string(df.Contents))
+			},
+		},
+		{
+			name: "Drakefile.yml variant",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, "Drakefile.yml", drakefileContents)
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, filepath.Join(vcsRoot, "Drakefile.yml"), df.Location)
+			},
+		},
+		{
+			name: ".drake/Drakefile.yaml variant",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, ".drake/Drakefile.yaml", drakefileContents)
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					filepath.Join(vcsRoot, ".drake", "Drakefile.yaml"),
+					df.Location,
+				)
+			},
+		},
+		{
+			name: "empty files and directories are skipped",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, "Drakefile.yaml", "")
+				require.NoError(
+					t,
+					os.MkdirAll(filepath.Join(vcsRoot, "Drakefile.yml"), 0755),
+				)
+				writeFile(t, vcsRoot, ".drake/Drakefile.yml", drakefileContents)
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					filepath.Join(vcsRoot, ".drake", "Drakefile.yml"),
+					df.Location,
+				)
+			},
+		},
+		{
+			name: "Drakefile from project worker template",
+			event: brigade.Event{
+				Worker: brigade.Worker{
+					DefaultConfigFiles: map[string]string{
+						"Drakefile.yaml": drakefileContents,
+					},
+				},
+			},
+			assertions: func(t *testing.T, _ string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, ProjectWorkerTemplateLocation, df.Location)
+				require.Equal(t, drakefileContents, string(df.Contents))
+			},
+		},
+		{
+			name: "project default and repository Drakefile are layered",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, "Drakefile.yaml", repoDrakefile)
+			},
+			event: brigade.Event{
+				Worker: brigade.Worker{
+					DefaultConfigFiles: map[string]string{
+						"Drakefile.yaml": projectDrakefile,
+					},
+				},
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					ProjectWorkerTemplateLocation+" + "+
+						filepath.Join(vcsRoot, "Drakefile.yaml"),
+					df.Location,
+				)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				jobs, err := cfg.Jobs("security-scan", "test")
+				require.NoError(t, err)
+				require.Equal(t, "scanner:v1", jobs[0].PrimaryContainer().Image())
+				require.Equal(t, "golang:1.15", jobs[1].PrimaryContainer().Image())
+			},
+		},
+		{
+			name: "branch overlay",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, "Drakefile.yaml", repoDrakefile)
+				writeFile(
+					t,
+					vcsRoot,
+					".drake/branches/release/v1.yaml",
+					branchOverlay,
+				)
+			},
+			event: brigade.Event{
+				Git: &core.GitDetails{
+					Ref: "refs/heads/release/v1",
+				},
+				Worker: brigade.Worker{
+					DefaultConfigFiles: map[string]string{
+						"Drakefile.yaml": projectDrakefile,
+					},
+				},
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Contains(
+					t,
+					df.Location,
+					filepath.Join(vcsRoot, ".drake", "branches", "release", "v1.yaml"),
+				)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				jobs, err := cfg.Jobs("security-scan", "test")
+				require.NoError(t, err)
+				require.Equal(t, "scanner:v1", jobs[0].PrimaryContainer().Image())
+				require.Equal(t, "golang:1.14", jobs[1].PrimaryContainer().Image())
+			},
+		},
+		{
+			name: "branch overlay for another branch is ignored",
+			setup: func(t *testing.T, vcsRoot string) {
+				writeFile(t, vcsRoot, "Drakefile.yaml", repoDrakefile)
+				writeFile(
+					t,
+					vcsRoot,
+					".drake/branches/release/v1.yaml",
+					branchOverlay,
+				)
+			},
+			event: brigade.Event{
+				Git: &core.GitDetails{
+					Ref: "refs/heads/master",
+				},
+			},
+			assertions: func(t *testing.T, vcsRoot string, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, filepath.Join(vcsRoot, "Drakefile.yaml"), df.Location)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			vcsRoot := t.TempDir()
+			if testCase.setup != nil {
+				testCase.setup(t, vcsRoot)
+			}
+			r := &Resolver{VCSRoot: vcsRoot}
+			df, err := r.Resolve(context.Background(), testCase.event)
+			testCase.assertions(t, vcsRoot, df, err)
+		})
+	}
+}
+
+func writeFile(t *testing.T, dir, name, contents string) {
+	path := filepath.Join(dir, name)
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+	require.NoError(t, ioutil.WriteFile(path, []byte(contents), 0644))
+}
@@ -0,0 +1,77 @@
+package drakefile
+
+import (
+	"log"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Services returns, in lexical order, the directories, relative to VCSRoot, of
+// every service within a monorepo. A service is any directory matched by one
+// of the provided glob patterns (see filepath.Match for syntax) that contains a
+// Drakefile. Patterns are relative to VCSRoot and may not match anything
+// outside of it. Each returned directory is suitable for use as a Resolver's
+// Dir.
+func (r *Resolver) Services(patterns []string) ([]string, error) {
+	rootDir := filepath.Clean(r.VCSRoot)
+	services := []string{}
+	found := map[string]struct{}{}
+	for _, pattern := range patterns {
+		if filepath.IsAbs(pattern) {
+			return nil, errors.Errorf(
+				"service pattern %q is not relative to the root of the checkout",
+				pattern,
+			)
+		}
+		matches, err := filepath.Glob(filepath.Join(rootDir, pattern))
+		if err != nil {
+			return nil, errors.Wrapf(err, "error matching service pattern %q", pattern)
+		}
+		for _, match := range matches {
+			dir, err := filepath.Rel(rootDir, match)
+			if err != nil ||
+				dir == ".." ||
+				strings.HasPrefix(dir, ".."+string(filepath.Separator)) {
+				return nil, errors.Errorf(
+					"service pattern %q matches %q, which is outside the checkout",
+					pattern,
+					match,
+				)
+			}
+			if _, ok := found[dir]; ok {
+				continue
+			}
+			fileInfo, err := os.Stat(match)
+			if err != nil {
+				return nil, errors.Wrapf(err, "error getting info for %q", match)
+			}
+			if !fileInfo.IsDir() {
+				continue
+			}
+			if !r.hasDrakefile(match) {
+				log.Printf("skipping service directory %q: no Drakefile", match)
+				continue
+			}
+			found[dir] = struct{}{}
+			services = append(services, dir)
+		}
+	}
+	sort.Strings(services)
+	return services, nil
+}
+
+// hasDrakefile returns true if the provided directory contains a file that is
+// recognized as a Drakefile.
+func (r *Resolver) hasDrakefile(dir string) bool {
+	for _, fileName := range r.fileNames() {
+		if fileInfo, err := os.Stat(filepath.Join(dir, fileName)); err == nil &&
+			!fileInfo.IsDir() {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,147 @@
+package drakefile
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestServices(t *testing.T) {
+	testCases := []struct {
+		name       string
+		patterns   []string
+		assertions func(*testing.T, []string, error)
+	}{
+		{
+			name:     "glob",
+			patterns: []string{"services/*"},
+			assertions: func(t *testing.T, services []string, err error) {
+				require.NoError(t, err)
+				// services/docs has no Drakefile and services/README.md isn't a
+				// directory
+				require.Equal(
+					t,
+					[]string{
+						filepath.Join("services", "api"),
+						filepath.Join("services", "web"),
+					},
+					services,
+				)
+			},
+		},
+		{
+			name:     "overlapping patterns",
+			patterns: []string{"tools/cli", "services/*", "services/web"},
+			assertions: func(t *testing.T, services []string, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					[]string{
+						filepath.Join("services", "api"),
+						filepath.Join("services", "web"),
+						filepath.Join("tools", "cli"),
+					},
+					services,
+				)
+			},
+		},
+		{
+			name:     "no matches",
+			patterns: []string{"nope/*"},
+			assertions: func(t *testing.T, services []string, err error) {
+				require.NoError(t, err)
+				require.Empty(t, services)
+			},
+		},
+		{
+			name:     "absolute pattern",
+			patterns: []string{"/etc/*"},
+			assertions: func(t *testing.T, _ []string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is not relative")
+			},
+		},
+		{
+			name:     "pattern outside the checkout",
+			patterns: []string{"../*"},
+			assertions: func(t *testing.T, _ []string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "outside the checkout")
+			},
+		},
+		{
+			name:     "malformed pattern",
+			patterns: []string{"services/["},
+			assertions: func(t *testing.T, _ []string, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error matching service pattern")
+			},
+		},
+	}
+	vcsRoot, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(vcsRoot)
+	writeFile(t, vcsRoot, "services/api/Drakefile.yaml", "jobs: {}\n")
+	writeFile(t, vcsRoot, "services/web/.drake/Drakefile.yml", "jobs: {}\n")
+	writeFile(t, vcsRoot, "services/docs/README.md", "docs")
+	writeFile(t, vcsRoot, "services/README.md", "services")
+	writeFile(t, vcsRoot, "tools/cli/Drakefile.yaml", "jobs: {}\n")
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			services, err := (&Resolver{VCSRoot: vcsRoot}).Services(
+				testCase.patterns,
+			)
+			testCase.assertions(t, services, err)
+		})
+	}
+}
+
+func TestResolveService(t *testing.T) {
+	vcsRoot, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(vcsRoot)
+	writeFile(t, vcsRoot, "Drakefile.yaml", "jobs:\n  root: {}\n")
+	writeFile(t, vcsRoot, "services/api/Drakefile.yaml", "jobs:\n  api: {}\n")
+	event := brigade.Event{
+		Worker: brigade.Worker{
+			DefaultConfigFiles: map[string]string{
+				"Drakefile.yaml": "jobs:\n  default: {}\n",
+			},
+		},
+	}
+	r := &Resolver{
+		VCSRoot:         vcsRoot,
+		LegacyLocations: []string{"/legacy/Drakefile.yaml"},
+		Dir:             filepath.Join("services", "api"),
+	}
+
+	// Neither the project default nor legacy locations apply to a service
+	require.Equal(
+		t,
+		[]string{
+			filepath.Join(vcsRoot, "services", "api", "Drakefile.yaml"),
+			filepath.Join(vcsRoot, "services", "api", "Drakefile.yml"),
+			filepath.Join(vcsRoot, "services", "api", ".drake", "Drakefile.yaml"),
+			filepath.Join(vcsRoot, "services", "api", ".drake", "Drakefile.yml"),
+		},
+		r.Candidates(event),
+	)
+	df, err := r.Resolve(context.Background(), event)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		filepath.Join(vcsRoot, "services", "api", "Drakefile.yaml"),
+		df.Location,
+	)
+	require.Equal(t, "jobs:\n  api: {}\n", string(df.Contents))
+
+	r.Dir = filepath.Join("services", "web")
+	_, err = r.Resolve(context.Background(), event)
+	require.Error(t, err)
+	require.True(t, IsNotFound(err))
+}
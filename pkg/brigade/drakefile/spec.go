@@ -0,0 +1,200 @@
+package drakefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/pkg/errors"
+)
+
+// specShim upgrades a Drakefile, represented generically, from one revision of
+// the DrakeSpec to the next. It returns the version it upgraded the Drakefile
+// to along with deprecation warnings describing anything it had to change.
+type specShim func(doc map[string]interface{}) (string, []string, error)
+
+// specShims are indexed by the DrakeSpec revision they upgrade from. Chaining
+// them must always, eventually, arrive at config.SupportedSpecVersions.
+var specShims = map[string]specShim{
+	"v0.5.0": upgradeFromV050,
+}
+
+// SupportedSpecVersions returns the DrakeSpec revisions that Drakefiles may
+// declare without any upgrade being necessary.
+func SupportedSpecVersions() []string {
+	return []string{config.SupportedSpecVersions}
+}
+
+// DeprecatedSpecVersions returns the DrakeSpec revisions that Drakefiles may
+// still declare, but which are deprecated and are upgraded automatically.
+func DeprecatedSpecVersions() []string {
+	versions := make([]string, 0, len(specShims))
+	for version := range specShims {
+		versions = append(versions, version)
+	}
+	sort.Strings(versions)
+	return versions
+}
+
+// Upgrade examines the specVersion declared by the provided Drakefile without
+// fully parsing it. If the Drakefile declares no specVersion (as is common for
+// overlays and fragments) or declares a supported one, it is returned
+// unaltered. If it declares a deprecated specVersion, it is upgraded to a
+// supported one and deprecation warnings are logged. Any other specVersion is
+// rejected.
+func Upgrade(df Drakefile) (Drakefile, error) {
+	jsonBytes, err := yaml.YAMLToJSON(df.Contents)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error converting Drakefile from %s to JSON",
+			df.Location,
+		)
+	}
+	doc := map[string]interface{}{}
+	// If this fails, it's not the job of this function to complain about it
+	if json.Unmarshal(jsonBytes, &doc) != nil {
+		return df, nil
+	}
+	rawVersion, ok := doc["specVersion"]
+	if !ok {
+		return df, nil
+	}
+	version, ok := rawVersion.(string)
+	if !ok {
+		return Drakefile{}, errors.Errorf(
+			"specVersion %v of Drakefile from %s is not a string",
+			rawVersion,
+			df.Location,
+		)
+	}
+	if isSupportedSpecVersion(version) {
+		return df, nil
+	}
+	if _, ok = specShims[version]; !ok {
+		return Drakefile{}, errors.Errorf(
+			"Drakefile from %s declares unsupported specVersion %q; supported "+
+				"versions are: %s",
+			df.Location,
+			version,
+			strings.Join(
+				append(SupportedSpecVersions(), DeprecatedSpecVersions()...),
+				", ",
+			),
+		)
+	}
+	log.Printf(
+		"WARNING: Drakefile from %s declares deprecated specVersion %q; it will "+
+			"be upgraded automatically, but should be updated to declare %s",
+		df.Location,
+		version,
+		strings.Join(SupportedSpecVersions(), " or "),
+	)
+	for !isSupportedSpecVersion(version) {
+		shim, ok := specShims[version]
+		if !ok {
+			// This can only happen if the shims are misconfigured
+			return Drakefile{}, errors.Errorf(
+				"no means of upgrading Drakefile from %s from specVersion %q",
+				df.Location,
+				version,
+			)
+		}
+		var warnings []string
+		if version, warnings, err = shim(doc); err != nil {
+			return Drakefile{}, errors.Wrapf(
+				err,
+				"error upgrading Drakefile from %s",
+				df.Location,
+			)
+		}
+		for _, warning := range warnings {
+			log.Printf("WARNING: Drakefile from %s: %s", df.Location, warning)
+		}
+		doc["specVersion"] = version
+	}
+	if jsonBytes, err = json.Marshal(doc); err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error marshaling upgraded Drakefile from %s",
+			df.Location,
+		)
+	}
+	yamlBytes, err := yaml.JSONToYAML(jsonBytes)
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error converting upgraded Drakefile from %s to YAML",
+			df.Location,
+		)
+	}
+	return Drakefile{
+		Location: df.Location,
+		Contents: yamlBytes,
+	}, nil
+}
+
+func isSupportedSpecVersion(version string) bool {
+	for _, supportedVersion := range SupportedSpecVersions() {
+		if version == supportedVersion {
+			return true
+		}
+	}
+	return false
+}
+
+// upgradeFromV050 upgrades a v0.5.0 Drakefile to v0.6.0. Jobs that list all
+// their containers in a single containers field are converted to use the
+// primaryContainer and sidecarContainers fields instead. The first container
+// listed becomes the primary container.
+func upgradeFromV050(doc map[string]interface{}) (string, []string, error) {
+	warnings := []string{}
+	jobs, _ := doc["jobs"].(map[string]interface{})
+	jobNames := make([]string, 0, len(jobs))
+	for jobName := range jobs {
+		jobNames = append(jobNames, jobName)
+	}
+	// Sort so that warnings are logged in a predictable order
+	sort.Strings(jobNames)
+	for _, jobName := range jobNames {
+		job, ok := jobs[jobName].(map[string]interface{})
+		if !ok {
+			continue
+		}
+		rawContainers, ok := job["containers"]
+		if !ok {
+			continue
+		}
+		containers, ok := rawContainers.([]interface{})
+		if !ok || len(containers) == 0 {
+			return "", nil, errors.Errorf(
+				"containers field of job %q is not a non-empty list",
+				jobName,
+			)
+		}
+		if _, ok = job["primaryContainer"]; ok {
+			return "", nil, errors.Errorf(
+				"job %q specifies both containers and primaryContainer",
+				jobName,
+			)
+		}
+		delete(job, "containers")
+		job["primaryContainer"] = containers[0]
+		if len(containers) > 1 {
+			job["sidecarContainers"] = containers[1:]
+		}
+		warnings = append(
+			warnings,
+			fmt.Sprintf(
+				"the containers field of job %q is deprecated; use "+
+					"primaryContainer and sidecarContainers instead",
+				jobName,
+			),
+		)
+	}
+	return "v0.6.0", warnings, nil
+}
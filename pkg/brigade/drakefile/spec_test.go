@@ -0,0 +1,122 @@
+package drakefile
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestUpgrade(t *testing.T) {
+	testCases := []struct {
+		name       string
+		contents   string
+		assertions func(*testing.T, Drakefile, error)
+	}{
+		{
+			name:     "no spec version",
+			contents: "jobs: {}\n",
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "jobs: {}\n", string(df.Contents))
+			},
+		},
+		{
+			name: "supported spec version",
+			contents: "specUri: github.com/lovethedrake/drakespec\n" +
+				"specVersion: v0.6.0\n",
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					"specUri: github.com/lovethedrake/drakespec\n"+
+						"specVersion: v0.6.0\n",
+					string(df.Contents),
+				)
+			},
+		},
+		{
+			name:     "unsupported spec version",
+			contents: "specVersion: v0.1.0\n",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					`declares unsupported specVersion "v0.1.0"; supported versions `+
+						"are: v0.6.0, v0.5.0",
+				)
+			},
+		},
+		{
+			name:     "spec version that isn't a string",
+			contents: "specVersion: 6\n",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is not a string")
+			},
+		},
+		{
+			name: "deprecated spec version",
+			contents: `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.5.0
+jobs:
+  foo:
+    containers:
+    - name: foo
+      image: foo
+    - name: bar
+      image: bar
+  bar:
+    primaryContainer:
+      name: bar
+      image: bar
+`,
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				cfg, err := config.NewConfigFromYAML(df.Contents)
+				require.NoError(t, err)
+				jobs, err := cfg.Jobs("foo", "bar")
+				require.NoError(t, err)
+				require.Equal(t, "foo", jobs[0].PrimaryContainer().Name())
+				require.Len(t, jobs[0].SidecarContainers(), 1)
+				require.Equal(t, "bar", jobs[0].SidecarContainers()[0].Name())
+				require.Equal(t, "bar", jobs[1].PrimaryContainer().Name())
+			},
+		},
+		{
+			name: "deprecated spec version with conflicting container fields",
+			contents: `
+specVersion: v0.5.0
+jobs:
+  foo:
+    containers:
+    - name: foo
+      image: foo
+    primaryContainer:
+      name: foo
+      image: foo
+`,
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					`job "foo" specifies both containers and primaryContainer`,
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			df, err := Upgrade(
+				Drakefile{
+					Location: "Drakefile.yaml",
+					Contents: []byte(testCase.contents),
+				},
+			)
+			testCase.assertions(t, df, err)
+		})
+	}
+}
@@ -0,0 +1,176 @@
+package drakefile
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/template"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+// TemplateDirective is a comment that, if it appears as the first line of a
+// Drakefile, causes the Drakefile to be rendered as a Go template before it is
+// parsed.
+const TemplateDirective = "# canard: template"
+
+var (
+	tagRefRegex         = regexp.MustCompile("^refs/tags/(.+)$")
+	pullRequestRefRegex = regexp.MustCompile(`^refs/pull/(\d+)/(?:head|merge)$`)
+)
+
+// TemplateData is the context available to Drakefile templates. Referencing
+// anything that isn't defined, including keys absent from Labels or Env, is an
+// error.
+type TemplateData struct {
+	// Source is the event's source
+	Source string
+	// Type is the event's type
+	Type string
+	// Ref is the full git reference the event pertains to, if any
+	Ref string
+	// Branch is the name of the branch the event pertains to, if any
+	Branch string
+	// Tag is the name of the tag the event pertains to, if any
+	Tag string
+	// Commit is the SHA of the commit the event pertains to, if any
+	Commit string
+	// PullRequest is the number of the pull request the event pertains to, or
+	// zero if the event doesn't pertain to a pull request
+	PullRequest int
+	// Labels are the event's labels
+	Labels map[string]string
+	// Env contains the values of allowed environment variables
+	Env map[string]string
+}
+
+// NewTemplateData returns TemplateData derived from the provided event. Only
+// environment variables named in envAllowlist (and actually set) are exposed.
+func NewTemplateData(
+	event brigade.Event,
+	envAllowlist []string,
+) TemplateData {
+	data := TemplateData{
+		Source: event.Source,
+		Type:   event.Type,
+		Labels: map[string]string{},
+		Env:    map[string]string{},
+	}
+	if event.Git != nil {
+		data.Ref = event.Git.Ref
+		data.Commit = event.Git.Commit
+	}
+	if data.Ref == "" {
+		data.Ref = event.Worker.Git.Ref
+	}
+	if data.Commit == "" {
+		data.Commit = event.Worker.Git.Commit
+	}
+	data.Branch = branchOf(event)
+	if submatches :=
+		tagRefRegex.FindStringSubmatch(data.Ref); submatches != nil {
+		data.Tag = submatches[1]
+	}
+	data.PullRequest = pullRequestNumberOf(event, data.Ref)
+	for k, v := range event.Labels {
+		data.Labels[k] = v
+	}
+	for _, name := range envAllowlist {
+		if value, ok := os.LookupEnv(name); ok {
+			data.Env[name] = value
+		}
+	}
+	return data
+}
+
+// pullRequestNumberOf returns the number of the pull request the event pertains
+// to, or zero if it doesn't pertain to a pull request.
+func pullRequestNumberOf(event brigade.Event, ref string) int {
+	if strings.HasPrefix(event.Type, "pull_request") {
+		payload := struct {
+			Number      int `json:"number"`
+			PullRequest struct {
+				Number int `json:"number"`
+			} `json:"pull_request"`
+		}{}
+		if json.Unmarshal([]byte(event.Payload), &payload) == nil {
+			if payload.Number != 0 {
+				return payload.Number
+			}
+			if payload.PullRequest.Number != 0 {
+				return payload.PullRequest.Number
+			}
+		}
+	}
+	if submatches :=
+		pullRequestRefRegex.FindStringSubmatch(ref); submatches != nil {
+		// The regex guarantees this is numeric
+		number, _ := strconv.Atoi(submatches[1])
+		return number
+	}
+	return 0
+}
+
+// IsTemplate returns true if the provided Drakefile opts into rendering by
+// starting with TemplateDirective.
+func IsTemplate(df Drakefile) bool {
+	firstLine, err := bufio.NewReader(bytes.NewReader(df.Contents)).
+		ReadString('\n')
+	if err != nil && firstLine == "" {
+		return false
+	}
+	return strings.TrimSpace(firstLine) == TemplateDirective
+}
+
+// Render renders the provided Drakefile as a Go template using the provided
+// data if the Drakefile opts into rendering. Otherwise, the Drakefile is
+// returned unaltered.
+func Render(df Drakefile, data TemplateData) (Drakefile, error) {
+	if !IsTemplate(df) {
+		return df, nil
+	}
+	tmpl, err := template.New(df.Location).
+		Option("missingkey=error").
+		Funcs(
+			template.FuncMap{
+				"lower": strings.ToLower,
+				"upper": strings.ToUpper,
+				// The following all take the string being operated on as their last
+				// argument so they can be used in pipelines
+				"replace": func(old, new, s string) string {
+					return strings.ReplaceAll(s, old, new)
+				},
+				"trimPrefix": func(prefix, s string) string {
+					return strings.TrimPrefix(s, prefix)
+				},
+				"trimSuffix": func(suffix, s string) string {
+					return strings.TrimSuffix(s, suffix)
+				},
+			},
+		).
+		Parse(string(df.Contents))
+	if err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error parsing Drakefile template from %s",
+			df.Location,
+		)
+	}
+	rendered := &bytes.Buffer{}
+	if err = tmpl.Execute(rendered, data); err != nil {
+		return Drakefile{}, errors.Wrapf(
+			err,
+			"error rendering Drakefile template from %s",
+			df.Location,
+		)
+	}
+	return Drakefile{
+		Location: df.Location,
+		Contents: rendered.Bytes(),
+	}, nil
+}
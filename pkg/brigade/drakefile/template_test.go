@@ -0,0 +1,171 @@
+package drakefile
+
+import (
+	"os"
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v2/core"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewTemplateData(t *testing.T) {
+	const envVar = "CANARD_TEST_TEMPLATE_VAR"
+	os.Setenv(envVar, "foo")
+	defer os.Unsetenv(envVar)
+	testCases := []struct {
+		name       string
+		event      brigade.Event
+		assertions func(*testing.T, TemplateData)
+	}{
+		{
+			name: "push to branch",
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Git: &core.GitDetails{
+					Ref:    "refs/heads/master",
+					Commit: "abc123",
+				},
+				Labels: map[string]string{"foo": "bar"},
+			},
+			assertions: func(t *testing.T, data TemplateData) {
+				require.Equal(t, "brigade.sh/github", data.Source)
+				require.Equal(t, "push", data.Type)
+				require.Equal(t, "refs/heads/master", data.Ref)
+				require.Equal(t, "master", data.Branch)
+				require.Empty(t, data.Tag)
+				require.Equal(t, "abc123", data.Commit)
+				require.Zero(t, data.PullRequest)
+				require.Equal(t, map[string]string{"foo": "bar"}, data.Labels)
+				require.Equal(t, map[string]string{envVar: "foo"}, data.Env)
+			},
+		},
+		{
+			name: "push of tag",
+			event: brigade.Event{
+				Worker: brigade.Worker{
+					Git: core.GitConfig{
+						Ref: "refs/tags/v1.2.3",
+					},
+				},
+			},
+			assertions: func(t *testing.T, data TemplateData) {
+				require.Equal(t, "v1.2.3", data.Tag)
+				require.Empty(t, data.Branch)
+			},
+		},
+		{
+			name: "pull request",
+			event: brigade.Event{
+				Type:    "pull_request:opened",
+				Payload: `{"number":42}`,
+			},
+			assertions: func(t *testing.T, data TemplateData) {
+				require.Equal(t, 42, data.PullRequest)
+			},
+		},
+		{
+			name: "pull request ref",
+			event: brigade.Event{
+				Type: "check_suite:requested",
+				Git: &core.GitDetails{
+					Ref: "refs/pull/7/head",
+				},
+			},
+			assertions: func(t *testing.T, data TemplateData) {
+				require.Equal(t, 7, data.PullRequest)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(
+				t,
+				NewTemplateData(testCase.event, []string{envVar, "NOT_SET"}),
+			)
+		})
+	}
+}
+
+func TestRender(t *testing.T) {
+	data := TemplateData{
+		Tag:         "v1.2.3",
+		PullRequest: 42,
+		Labels:      map[string]string{"foo": "bar"},
+		Env:         map[string]string{},
+	}
+	testCases := []struct {
+		name       string
+		contents   string
+		assertions func(*testing.T, Drakefile, error)
+	}{
+		{
+			name:     "not a template",
+			contents: "image: foo:{{ .Tag }}\n",
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "image: foo:{{ .Tag }}\n", string(df.Contents))
+			},
+		},
+		{
+			name: "template",
+			contents: TemplateDirective + "\n" +
+				`image: foo:{{ .Tag | trimPrefix "v" }}` + "\n" +
+				"pr: {{ .PullRequest }}\n" +
+				"label: {{ .Labels.foo }}\n",
+			assertions: func(t *testing.T, df Drakefile, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					TemplateDirective+"\nimage: foo:1.2.3\npr: 42\nlabel: bar\n",
+					string(df.Contents),
+				)
+			},
+		},
+		{
+			name:     "undefined field",
+			contents: TemplateDirective + "\nimage: foo:{{ .Version }}\n",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error rendering Drakefile template")
+			},
+		},
+		{
+			name:     "undefined label",
+			contents: TemplateDirective + "\nimage: foo:{{ .Labels.nope }}\n",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "map has no entry for key")
+			},
+		},
+		{
+			name:     "undefined environment variable",
+			contents: TemplateDirective + "\nimage: foo:{{ .Env.HOME }}\n",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "map has no entry for key")
+			},
+		},
+		{
+			name:     "malformed template",
+			contents: TemplateDirective + "\nimage: foo:{{ .Tag\n",
+			assertions: func(t *testing.T, _ Drakefile, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error parsing Drakefile template")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			df, err := Render(
+				Drakefile{
+					Location: "Drakefile.yaml",
+					Contents: []byte(testCase.contents),
+				},
+				data,
+			)
+			testCase.assertions(t, df, err)
+		})
+	}
+}
@@ -3,7 +3,10 @@ package brigade
 import (
 	"encoding/json"
 	"fmt"
+	"io"
 	"io/ioutil"
+	"net/url"
+	"os"
 
 	"github.com/brigadecore/brigade/sdk/v2/core"
 	"github.com/pkg/errors"
@@ -12,65 +15,83 @@ import (
 // Event represents a Brigade event.
 type Event struct {
 	// ID is the unique identifier for the event.
-	ID string
+	ID string `json:"id"`
 
 	// Project that registered the handler being called for the event.
-	Project Project
+	Project Project `json:"project"`
 
 	// Source is the unique identifier of the gateway which created the event.
-	Source string
+	Source string `json:"source"`
+
+	// SourceState is an opaque collection of key/value pairs that the gateway
+	// which created the event may use to track the event's state.
+	SourceState *core.SourceState `json:"sourceState,omitempty"`
 
 	// Type of event. Values and meanings are source-specific.
-	Type string
+	Type string `json:"type"`
+
+	// Qualifiers provide additional context about the event. Like Source and
+	// Type, these are used by Brigade to route the event to subscribed
+	// projects. The GitHub gateway, for instance, sets a "repo" qualifier.
+	Qualifiers map[string]string `json:"qualifiers,omitempty"`
+
+	// Labels are key/value pairs that can be used to annotate the event. Unlike
+	// Qualifiers, these play no part in routing the event.
+	Labels map[string]string `json:"labels,omitempty"`
 
 	// ShortTitle for the event, suitable for display in space-limited UI such
 	// as lists.
-	ShortTitle string
+	ShortTitle string `json:"shortTitle"`
 
 	// LongTitle for the event, containing additional details.
-	LongTitle string
+	LongTitle string `json:"longTitle"`
+
+	// Git contains git-specific details of the event, such as the exact commit
+	// the gateway recorded. This is nil for events that aren't tied to a
+	// source code repository.
+	Git *core.GitDetails `json:"git,omitempty"`
 
 	// Payload is the content of the event. This is source- and type-specific.
-	Payload string
+	Payload string `json:"payload"`
 
 	// Worker assigned to handle the event.
-	Worker Worker
+	Worker Worker `json:"worker"`
 }
 
 type Project struct {
 	// ID is the unique identifier of the project.
-	ID string
+	ID string `json:"id"`
 
 	// Secrets is a map of secret key/value pairs defined in the project.
-	Secrets map[string]string
+	Secrets map[string]string `json:"secrets"`
 }
 
 type Worker struct {
 	// ApiAddress is the endpoint of the Brigade API server.
 	//nolint
-	ApiAddress string
+	ApiAddress string `json:"apiAddress"`
 
 	// ApiToken which can be used to authenticate to the API server.
 	// The token is specific to the current event and allows you to create
 	// jobs for that event. It has no other permissions.
 	//nolint
-	ApiToken string
+	ApiToken string `json:"apiToken"`
 
 	// ConfigFilesDirectory where the worker stores configuration files,
 	// including event handler code files such as brigade.js and brigade.json.
-	ConfigFilesDirectory string
+	ConfigFilesDirectory string `json:"configFilesDirectory"`
 
 	// DefaultConfigFiles to use for any configuration files that are not
 	// present.
-	DefaultConfigFiles map[string]string
+	DefaultConfigFiles map[string]string `json:"defaultConfigFiles"`
 
 	// LogLevel is the desired granularity of worker logs. Worker logs are
 	// distinct from job logs - the containers in a job will emit logs
 	// according to their own configuration.
-	LogLevel string
+	LogLevel string `json:"logLevel"`
 
 	// Git contains git-specific Worker configuration.
-	Git core.GitConfig
+	Git core.GitConfig `json:"git"`
 }
 
 // Revision represents VCS-related details.
@@ -81,16 +102,110 @@ type Revision struct {
 	Ref string `envconfig:"BRIGADE_COMMIT_REF"`
 }
 
-// LoadEvent returns an Event object with values derived from
-// /var/event/event.json
+const (
+	// DefaultEventPath is the location at which Brigade mounts the event a
+	// worker is handling.
+	DefaultEventPath = "/var/event/event.json"
+	// EventPathEnvVar is the name of an environment variable that, if set,
+	// overrides DefaultEventPath. This is useful for running the worker outside
+	// a Brigade worker pod.
+	EventPathEnvVar = "CANARD_EVENT_PATH"
+	// StdinEventPath is a special event path that indicates the event should be
+	// read from stdin.
+	StdinEventPath = "-"
+)
+
+// EventPath returns the path from which the event should be loaded if no other
+// path has been explicitly specified. This is the value of the
+// CANARD_EVENT_PATH environment variable if set and /var/event/event.json
+// otherwise.
+func EventPath() string {
+	if eventPath, ok := os.LookupEnv(EventPathEnvVar); ok && eventPath != "" {
+		return eventPath
+	}
+	return DefaultEventPath
+}
+
+// LoadEvent returns an Event object with values derived from the file
+// indicated by EventPath().
 func LoadEvent() (Event, error) {
-	eventPath := "/var/event/event.json"
-	contents, err := ioutil.ReadFile(eventPath)
+	return LoadEventFromFile(EventPath())
+}
+
+// LoadEventFromFile returns an Event object with values derived from the
+// specified file. If the path is "-", the event is read from stdin instead.
+func LoadEventFromFile(eventPath string) (Event, error) {
+	if eventPath == StdinEventPath {
+		evt, err := LoadEventFromReader(os.Stdin)
+		return evt, errors.Wrap(err, "error loading event from stdin")
+	}
+	eventFile, err := os.Open(eventPath)
 	if err != nil {
-		return Event{}, fmt.Errorf("error reading %s", eventPath)
+		return Event{}, errors.Wrapf(err, "error reading %s", eventPath)
 	}
+	defer eventFile.Close()
+	evt, err := LoadEventFromReader(eventFile)
+	return evt, errors.Wrapf(err, "error loading event from %s", eventPath)
+}
 
+// LoadEventFromReader returns an Event object with values derived from JSON
+// read from the provided io.Reader. The event is validated before it is
+// returned.
+func LoadEventFromReader(r io.Reader) (Event, error) {
+	contents, err := ioutil.ReadAll(r)
+	if err != nil {
+		return Event{}, errors.Wrap(err, "error reading event json")
+	}
 	evt := Event{}
-	err = json.Unmarshal(contents, &evt)
-	return evt, errors.Wrap(err, "error loading event json")
+	if err = json.Unmarshal(contents, &evt); err != nil {
+		return Event{}, errors.Wrap(err, "error loading event json")
+	}
+	if err = evt.Validate(); err != nil {
+		return Event{}, err
+	}
+	return evt, nil
+}
+
+// Validate checks that all fields required for the worker to communicate with
+// the Brigade API server are present and well-formed. All problems found are
+// reported together.
+func (e Event) Validate() error {
+	problems := []string{}
+	if e.ID == "" {
+		problems = append(problems, "id: event ID is required")
+	}
+	if e.Worker.ApiAddress == "" {
+		problems = append(
+			problems,
+			"worker.apiAddress: worker API address is required",
+		)
+	} else if u, err := url.Parse(e.Worker.ApiAddress); err != nil ||
+		(u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"worker.apiAddress: %q is not a valid http or https URL",
+				e.Worker.ApiAddress,
+			),
+		)
+	}
+	if e.Worker.ApiToken == "" {
+		problems = append(problems, "worker.apiToken: worker API token is required")
+	}
+	if len(problems) > 0 {
+		return &invalidEventError{problems: problems}
+	}
+	return nil
+}
+
+type invalidEventError struct {
+	problems []string
+}
+
+func (i *invalidEventError) Error() string {
+	str := fmt.Sprintf("event is invalid; %d problem(s) found:", len(i.problems))
+	for _, problem := range i.problems {
+		str = fmt.Sprintf("%s\n- %s", str, problem)
+	}
+	return str
 }
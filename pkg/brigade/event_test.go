@@ -0,0 +1,274 @@
+package brigade
+
+import (
+	"encoding/json"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v2/core"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLoadEventFromFile(t *testing.T) {
+	testCases := []struct {
+		name       string
+		path       string
+		assertions func(*testing.T, Event, error)
+	}{
+		{
+			name: "file does not exist",
+			path: "testdata/does-not-exist.json",
+			assertions: func(t *testing.T, _ Event, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error reading")
+			},
+		},
+		{
+			name: "github push event",
+			path: "testdata/github-push-event.json",
+			assertions: func(t *testing.T, event Event, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "5a5cf4d6-9a6e-4c2f-9e3b-4f0b8d8c6f1e", event.ID)
+				require.Equal(t, "canard", event.Project.ID)
+				require.Equal(
+					t,
+					map[string]string{"dockerhubPassword": "swordfish"},
+					event.Project.Secrets,
+				)
+				require.Equal(t, "brigade.sh/github", event.Source)
+				require.Equal(
+					t,
+					&core.SourceState{State: map[string]string{"tracking": "true"}},
+					event.SourceState,
+				)
+				require.Equal(t, "push", event.Type)
+				require.Equal(
+					t,
+					map[string]string{"repo": "lovethedrake/canard"},
+					event.Qualifiers,
+				)
+				require.Equal(
+					t,
+					map[string]string{"appID": "12345", "installationID": "67890"},
+					event.Labels,
+				)
+				require.Equal(t, "refs/heads/master", event.ShortTitle)
+				require.Equal(
+					t,
+					&core.GitDetails{
+						CloneURL: "https://github.com/lovethedrake/canard.git",
+						Commit:   "1a8d3a1a0b2c4e8d9f0e1d2c3b4a5f6e7d8c9b0a",
+						Ref:      "refs/heads/master",
+					},
+					event.Git,
+				)
+				require.Contains(t, event.Payload, `"ref":"refs/heads/master"`)
+				require.Equal(
+					t,
+					"https://brigade-apiserver.brigade.svc.cluster.local",
+					event.Worker.ApiAddress,
+				)
+				require.Equal(t, "b6b4e2b7c2a14a5e9d6f", event.Worker.ApiToken)
+				require.Equal(t, ".brigade", event.Worker.ConfigFilesDirectory)
+				require.Equal(
+					t,
+					"https://github.com/lovethedrake/canard.git",
+					event.Worker.Git.CloneURL,
+				)
+			},
+		},
+		{
+			name: "brig cli event",
+			path: "testdata/brig-cli-event.json",
+			assertions: func(t *testing.T, event Event, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "brigade.sh/cli", event.Source)
+				require.Equal(t, "exec", event.Type)
+				require.Nil(t, event.SourceState)
+				require.Nil(t, event.Git)
+				require.Empty(t, event.Qualifiers)
+				require.Empty(t, event.Labels)
+				require.Equal(t, "DEBUG", event.Worker.LogLevel)
+				require.Contains(t, event.Worker.DefaultConfigFiles, "Drakefile.yaml")
+				require.Equal(t, "refs/heads/v2", event.Worker.Git.Ref)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			event, err := LoadEventFromFile(testCase.path)
+			testCase.assertions(t, event, err)
+		})
+	}
+}
+
+func TestEventRoundTrip(t *testing.T) {
+	for _, path := range []string{
+		"testdata/github-push-event.json",
+		"testdata/brig-cli-event.json",
+	} {
+		t.Run(path, func(t *testing.T) {
+			event, err := LoadEventFromFile(path)
+			require.NoError(t, err)
+			eventJSON, err := json.Marshal(event)
+			require.NoError(t, err)
+			roundTrippedEvent := Event{}
+			err = json.Unmarshal(eventJSON, &roundTrippedEvent)
+			require.NoError(t, err)
+			require.Equal(t, event, roundTrippedEvent)
+		})
+	}
+}
+
+func TestEventPath(t *testing.T) {
+	originalEventPath, wasSet := os.LookupEnv(EventPathEnvVar)
+	defer func() {
+		if wasSet {
+			os.Setenv(EventPathEnvVar, originalEventPath)
+		} else {
+			os.Unsetenv(EventPathEnvVar)
+		}
+	}()
+	t.Run("env var not set", func(t *testing.T) {
+		os.Unsetenv(EventPathEnvVar)
+		require.Equal(t, DefaultEventPath, EventPath())
+	})
+	t.Run("env var set", func(t *testing.T) {
+		os.Setenv(EventPathEnvVar, "/tmp/event.json")
+		require.Equal(t, "/tmp/event.json", EventPath())
+	})
+}
+
+func TestLoadEventFromReader(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, Event, error)
+	}{
+		{
+			name: "malformed json",
+			json: "{",
+			assertions: func(t *testing.T, _ Event, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error loading event json")
+			},
+		},
+		{
+			name: "invalid event",
+			json: `{"worker":{"apiAddress":"not a url"}}`,
+			assertions: func(t *testing.T, _ Event, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "3 problem(s) found")
+				require.Contains(t, err.Error(), "id:")
+				require.Contains(t, err.Error(), "worker.apiAddress:")
+				require.Contains(t, err.Error(), "worker.apiToken:")
+			},
+		},
+		{
+			name: "valid event",
+			json: `{"id":"foo","worker":{"apiAddress":"https://brigade","apiToken":"bar"}}`, // nolint: lll
+			assertions: func(t *testing.T, event Event, err error) {
+				require.NoError(t, err)
+				require.Equal(t, "foo", event.ID)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			event, err := LoadEventFromReader(strings.NewReader(testCase.json))
+			testCase.assertions(t, event, err)
+		})
+	}
+}
+
+func TestValidateEvent(t *testing.T) {
+	validEvent := func() Event {
+		return Event{
+			ID: "foo",
+			Worker: Worker{
+				ApiAddress: "https://brigade-apiserver",
+				ApiToken:   "bar",
+			},
+		}
+	}
+	testCases := []struct {
+		name       string
+		event      func() Event
+		assertions func(*testing.T, error)
+	}{
+		{
+			name:  "valid event",
+			event: validEvent,
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "missing event ID",
+			event: func() Event {
+				event := validEvent()
+				event.ID = ""
+				return event
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "1 problem(s) found")
+				require.Contains(t, err.Error(), "id: event ID is required")
+			},
+		},
+		{
+			name: "missing API address",
+			event: func() Event {
+				event := validEvent()
+				event.Worker.ApiAddress = ""
+				return event
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"worker.apiAddress: worker API address is required",
+				)
+			},
+		},
+		{
+			name: "malformed API address",
+			event: func() Event {
+				event := validEvent()
+				event.Worker.ApiAddress = "brigade-apiserver:443"
+				return event
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					`worker.apiAddress: "brigade-apiserver:443" is not a valid`,
+				)
+			},
+		},
+		{
+			name: "missing API token",
+			event: func() Event {
+				event := validEvent()
+				event.Worker.ApiToken = ""
+				return event
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"worker.apiToken: worker API token is required",
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(t, testCase.event().Validate())
+		})
+	}
+}
@@ -3,140 +3,154 @@ package executor
 import (
 	"context"
 	"fmt"
-	"io/ioutil"
 	"log"
 	"os"
 	"sync"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/brigade/drakefile"
 	"github.com/lovethedrake/canard/pkg/drake"
-	"github.com/lovethedrake/canard/pkg/drake/brig"
-	"github.com/lovethedrake/canard/pkg/drake/github"
-	"github.com/lovethedrake/go-drake/config"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/lovethedrake/canard/pkg/workerconfig"
 	"github.com/pkg/errors"
 )
 
-var triggerBuilderFns = map[string]func([]byte) (drake.Trigger, error){
-	"github.com/lovethedrake/drakespec-github": github.NewTriggerFromJSON,
-	"github.com/lovethedrake/drakespec-brig":   brig.NewTriggerFromJSON,
-}
-
 // ExecuteBuild can execute a Brigade build driven via Drakefile.yaml when
-// supplied with a Brigade project, event, and worker configuration, as well
-// as a Kubernetes client.
-func ExecuteBuild(ctx context.Context, event brigade.Event) error {
-	// nolint: lll
-	possibleDrakefileLocations := []string{
-		"/etc/brigade/script",                        // data mounted from event secret (e.g. brig run)
-		"/vcs/Drakefile.yaml",                        // checked out in repo
-		"/etc/brigade-project/defaultScript",         // data mounted from project.DefaultScript
-		"/etc/brigade-default-script/Drakefile.yaml", // mounted configmap named in brigade.sh/project.DefaultScriptName
+// supplied with a Brigade event, the head of that event as determined by
+// githubapi.HeadOf(), and worker configuration.
+func ExecuteBuild(
+	ctx context.Context,
+	event brigade.Event,
+	head githubapi.Head,
+	workerCfg workerconfig.Config,
+) error {
+	// An empty service denotes the project as a whole
+	services := []string{""}
+	if len(workerCfg.Drakefile.Services) > 0 {
+		var err error
+		if services, err = newResolver(workerCfg, head, "").Services(
+			workerCfg.Drakefile.Services,
+		); err != nil {
+			return err
+		}
+		log.Printf("found services: %v", services)
+	}
+
+	triggers, err := newTriggerRegistry(workerCfg, head)
+	if err != nil {
+		return err
 	}
-	var drakefileLocation string
-	for _, possibleDrakefileLocation := range possibleDrakefileLocations {
-		fileInfo, err := os.Stat(possibleDrakefileLocation)
+
+	// Load every pipeline and build and validate all of their triggers before
+	// evaluating any of them. Each service's Drakefile is loaded independently
+	// of all the others.
+	pipelines := []pipelineTriggers{}
+	problems := []string{}
+	for _, service := range services {
+		servicePipelines, serviceProblems, err := loadPipelines(
+			ctx,
+			event,
+			head,
+			workerCfg,
+			triggers,
+			service,
+		)
 		if err != nil {
-			if os.IsNotExist(err) {
+			if service != "" && drakefile.IsNotFound(err) {
+				log.Printf("skipping service %q: %s", service, err)
 				continue
 			}
-			return errors.Wrapf(
-				err,
-				"error getting info for file %q",
-				possibleDrakefileLocation,
-			)
+			return err
 		}
-		if fileInfo.Size() == 0 {
-			continue
-		}
-		drakefileLocation = possibleDrakefileLocation
-		break
+		pipelines = append(pipelines, servicePipelines...)
+		problems = append(problems, serviceProblems...)
 	}
-
-	var drakefile string
-	var cfg config.Config
-	if drakefileLocation == "" {
-		var ok bool
-		if drakefile, ok = event.Worker.DefaultConfigFiles["Drakefile.yaml"]; ok {
-			log.Printf("loading configuration from project worker template")
-			var err error
-			cfg, err = config.NewConfigFromYAML([]byte(drakefile))
-			if err != nil {
-				return errors.Wrapf(err, "error reading Drakefile contents from project worker template\n%s", drakefile)
-			}
-		} else {
-			return errors.New("could not locate Drakefile.yaml")
-		}
-	} else {
-		log.Printf("loading configuration from %q", drakefileLocation)
-		drakefileB, err := ioutil.ReadFile(drakefileLocation)
-		if err != nil {
-			return errors.Wrapf(err, "error reading Drakefile at %s", drakefileLocation)
-		}
-		cfg, err = config.NewConfigFromYAML(drakefileB)
-		if err != nil {
-			return errors.Wrapf(err, "error reading %s", drakefileLocation)
-		}
-		drakefile = string(drakefileB)
+	if err = drake.NewValidationError(problems); err != nil {
+		return errors.Wrap(err, "error validating triggers")
 	}
 
-	log.Printf("loaded Drakefile configuration:\n%s", drakefile)
-
-	// Find all pipelines that are eligible for execution.
-	pipelinesToExecute := []config.Pipeline{}
-	for _, pipeline := range cfg.AllPipelines() {
-		log.Printf("evaluating triggers for pipeline %q", pipeline.Name())
-		for i, pipelineTrigger := range pipeline.Triggers() {
-			triggerBuilderFn, ok := triggerBuilderFns[pipelineTrigger.SpecURI()]
-			if !ok {
-				// Don't know what to do with this trigger...
-				log.Printf("skipping unregistered trigger %s", pipelineTrigger.SpecURI())
-				continue // Next trigger
-			}
-			trigger, err := triggerBuilderFn(pipelineTrigger.Config())
-			if err != nil {
-				return errors.Wrapf(
-					err,
-					"error parsing trigger %d (%q) configuration for pipeline %q",
-					i,
-					pipelineTrigger.SpecURI(),
-					pipeline.Name(),
-				)
-			}
-			meetsCriteria, err := trigger.Matches(event)
-			if err != nil {
-				return errors.Wrapf(
-					err,
-					"error evaluating execution criteria for trigger %d (%q) "+
-						"configuration for pipeline %q",
-					i,
-					pipelineTrigger.SpecURI(),
-					pipeline.Name(),
-				)
-			}
-			if meetsCriteria {
-				fmt.Printf("adding pipeline %s", pipeline.Name())
-				pipelinesToExecute = append(pipelinesToExecute, pipeline)
-				break // Stop iterating over triggers; move on to the next pipeline
-			}
-		}
+	// Find all pipelines that are eligible for execution. Decisions are printed
+	// even if some couldn't be reached.
+	pipelinesToExecute, decisions, evalErr := evaluateTriggers(event, pipelines)
+	if err = printDecisions(os.Stdout, decisions); err != nil {
+		return errors.Wrap(err, "error printing trigger decisions")
+	}
+	if evalErr != nil {
+		return evalErr
 	}
 
 	// Bail if we found no pipelines to execute
 	if len(pipelinesToExecute) == 0 {
 		fmt.Println("no pipelines were triggered by the event")
+		report(
+			workerCfg.Reporting.Targets,
+			newBuildSummary(event.ID, nil, decisions, nil),
+		)
 		return nil
 	}
 
-	// Execute all pipelines we have identified-- each in their own goroutine
+	// Names of jobs scoped to services must be checked before any job is run
+	if err = checkJobNames(pipelinesToExecute); err != nil {
+		return errors.Wrap(err, "error naming jobs")
+	}
+
+	err = executePipelines(
+		ctx,
+		event,
+		pipelinesToExecute,
+		newJobRunner(workerCfg),
+	)
+	pipelineNames := make([]string, len(pipelinesToExecute))
+	for i, pipeline := range pipelinesToExecute {
+		pipelineNames[i] = pipeline.name()
+	}
+	report(
+		workerCfg.Reporting.Targets,
+		newBuildSummary(event.ID, pipelineNames, decisions, err),
+	)
+	return err
+}
+
+// newResolver returns a drakefile.Resolver configured in accordance with the
+// provided worker configuration and head of the event. If service is
+// non-empty, the resolver locates that service's Drakefile.
+func newResolver(
+	workerCfg workerconfig.Config,
+	head githubapi.Head,
+	service string,
+) *drakefile.Resolver {
+	resolver := drakefile.NewResolver()
+	resolver.Dir = service
+	resolver.Head = &head
+	resolver.FileNames = workerCfg.Drakefile.FileNames
+	resolver.TrustForkPullRequests = workerCfg.Drakefile.TrustForkPullRequests
+	resolver.TemplateEnvAllowlist = workerCfg.Drakefile.TemplateEnv
+	if !workerCfg.Features.Includes {
+		resolver.Includes = nil
+	}
+	resolver.DisableTemplates = !workerCfg.Features.Templating
+	resolver.DisableBranchOverlays = !workerCfg.Features.BranchOverlays
+	return resolver
+}
+
+// executePipelines executes all the provided pipelines-- each in their own
+// goroutine-- and waits for all of them to complete.
+func executePipelines(
+	ctx context.Context,
+	event brigade.Event,
+	pipelines []servicePipeline,
+	runner *jobRunner,
+) error {
 	wg := &sync.WaitGroup{}
 	errCh := make(chan error)
-	for _, pipeline := range pipelinesToExecute {
+	for _, pipeline := range pipelines {
 		p := pipeline // Avoid closing over a variable we're using for iteration
 		wg.Add(1)
 		go executePipeline(
 			ctx,
 			event,
 			p,
+			runner,
 			wg,
 			errCh,
 		)
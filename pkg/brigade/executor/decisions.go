@@ -0,0 +1,51 @@
+package executor
+
+import (
+	"fmt"
+	"io"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/lovethedrake/canard/pkg/drake"
+)
+
+// triggerDecision records the decision reached by one of a pipeline's
+// triggers.
+type triggerDecision struct {
+	// Pipeline is the name of the pipeline, qualified by its service's
+	// directory, if any.
+	Pipeline string `json:"pipeline"`
+	// Trigger is the index of the trigger among the pipeline's triggers.
+	Trigger int `json:"trigger"`
+	// SpecURI identifies the trigger spec.
+	SpecURI string `json:"specUri"`
+	drake.Decision
+}
+
+// printDecisions writes a table of the provided decisions to the provided
+// writer.
+func printDecisions(w io.Writer, decisions []triggerDecision) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "PIPELINE\tTRIGGER\tSPEC URI\tMATCHED\tSELECTOR\tVALUE\tREASON")
+	for _, d := range decisions {
+		fmt.Fprintf(
+			tw,
+			"%s\t%d\t%s\t%s\t%s\t%s\t%s\n",
+			d.Pipeline,
+			d.Trigger,
+			d.SpecURI,
+			strconv.FormatBool(d.Matched),
+			orDash(d.Selector),
+			orDash(d.Value),
+			d.Reason,
+		)
+	}
+	return tw.Flush()
+}
+
+func orDash(str string) string {
+	if str == "" {
+		return "-"
+	}
+	return str
+}
@@ -0,0 +1,44 @@
+package executor
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPrintDecisions(t *testing.T) {
+	buf := &bytes.Buffer{}
+	err := printDecisions(
+		buf,
+		[]triggerDecision{
+			{
+				Pipeline: "ci",
+				Trigger:  0,
+				SpecURI:  "github.com/lovethedrake/drakespec-github",
+				Decision: drake.Matched(
+					"push.branches",
+					"refs/heads/master",
+					`"master" matches only "master"`,
+				),
+			},
+			{
+				Pipeline: "services/api:release",
+				Trigger:  1,
+				SpecURI:  "example.com/drakespec-foo",
+				Decision: drake.Decision{
+					Reason: "trigger is not registered",
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		"PIPELINE              TRIGGER  SPEC URI                                  MATCHED  SELECTOR       VALUE              REASON\n"+ // nolint: lll
+			`ci                    0        github.com/lovethedrake/drakespec-github  true     push.branches  refs/heads/master  "master" matches only "master"`+"\n"+ // nolint: lll
+			"services/api:release  1        example.com/drakespec-foo                 false    -              -                  trigger is not registered\n", // nolint: lll
+		buf.String(),
+	)
+}
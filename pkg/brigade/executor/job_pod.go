@@ -9,22 +9,74 @@ import (
 	"github.com/brigadecore/brigade/sdk/v2/restmachinery"
 	"github.com/lovethedrake/canard/pkg/brigade"
 	"github.com/lovethedrake/canard/pkg/brigade/drakespec"
+	"github.com/lovethedrake/canard/pkg/workerconfig"
 	"github.com/lovethedrake/go-drake/config"
 	"github.com/pkg/errors"
 )
 
-func runJob(
+// jobRunner runs jobs in accordance with worker configuration.
+type jobRunner struct {
+	allowInsecureConnections bool
+	defaultTimeout           time.Duration
+	// slots, if non-nil, limits how many jobs may execute concurrently. A job
+	// must send to it before starting and receive from it once done.
+	slots chan struct{}
+}
+
+func newJobRunner(workerCfg workerconfig.Config) *jobRunner {
+	j := &jobRunner{
+		allowInsecureConnections: workerCfg.TLS.AllowInsecureConnections,
+		defaultTimeout:           workerCfg.Jobs.DefaultTimeout.Duration,
+	}
+	if workerCfg.Jobs.MaxConcurrency > 0 {
+		j.slots = make(chan struct{}, workerCfg.Jobs.MaxConcurrency)
+	}
+	return j
+}
+
+// acquire blocks until the job may execute or the context is canceled. The
+// returned function must be called once the job has finished.
+func (j *jobRunner) acquire(ctx context.Context, job string) (func(), error) {
+	if j.slots == nil {
+		return func() {}, nil
+	}
+	select {
+	case j.slots <- struct{}{}:
+		return func() { <-j.slots }, nil
+	case <-ctx.Done():
+		return nil, &pendingJobCanceledError{job: job}
+	}
+}
+
+func (j *jobRunner) runJob(
 	ctx context.Context,
 	event brigade.Event,
-	pipelineName string,
+	servicePipeline servicePipeline,
 	jobDef config.Job,
 ) error {
+	pipelineName := servicePipeline.name()
 	job := drakespec.ToBrigadeJob(jobDef)
+	scopeJobToService(&job, servicePipeline.service)
+	addJobEnvironment(&job, servicePipeline.env)
+	if job.Spec.TimeoutSeconds == 0 && j.defaultTimeout > 0 {
+		job.Spec.TimeoutSeconds = int64(j.defaultTimeout / time.Second)
+	}
 
-	// TODO(carolynvs): Make allow insecure connections configurable
-	jobsClient := core.NewJobsClient(event.Worker.ApiAddress, event.Worker.ApiToken, &restmachinery.APIClientOptions{AllowInsecureConnections: true})
+	release, err := j.acquire(ctx, job.Name)
+	if err != nil {
+		return err
+	}
+	defer release()
+
+	jobsClient := core.NewJobsClient(
+		event.Worker.ApiAddress,
+		event.Worker.ApiToken,
+		&restmachinery.APIClientOptions{
+			AllowInsecureConnections: j.allowInsecureConnections,
+		},
+	)
 
-	err := jobsClient.Create(ctx, event.ID, job)
+	err = jobsClient.Create(ctx, event.ID, job)
 	if err != nil {
 		return errors.Wrapf(err, "could not create job %s for pipeline %s on event %s", jobDef.Name(), pipelineName, event.ID)
 	}
@@ -37,6 +89,37 @@ func runJob(
 	return waitForJobCompletion(ctx, job, jobStatus, jobErr)
 }
 
+// addJobEnvironment sets the provided environment variables in each of the
+// provided job's containers. Variables that a container already defines are
+// left alone.
+func addJobEnvironment(job *core.Job, env map[string]string) {
+	if len(env) == 0 {
+		return
+	}
+	job.Spec.PrimaryContainer =
+		addContainerEnvironment(job.Spec.PrimaryContainer, env)
+	for name, sidecar := range job.Spec.SidecarContainers {
+		job.Spec.SidecarContainers[name] = addContainerEnvironment(sidecar, env)
+	}
+}
+
+func addContainerEnvironment(
+	container core.JobContainerSpec,
+	env map[string]string,
+) core.JobContainerSpec {
+	// The container's environment may be shared with other jobs' containers, so
+	// it's copied rather than modified
+	environment := make(map[string]string, len(container.Environment)+len(env))
+	for k, v := range env {
+		environment[k] = v
+	}
+	for k, v := range container.Environment {
+		environment[k] = v
+	}
+	container.Environment = environment
+	return container
+}
+
 func waitForJobCompletion(
 	ctx context.Context,
 	job core.Job,
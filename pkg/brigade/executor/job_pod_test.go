@@ -136,3 +136,36 @@ func TestWaitForJobPodCompletionWithContextCanceled(t *testing.T) {
 		)
 	}
 }
+
+func TestAddJobEnvironment(t *testing.T) {
+	primaryEnv := map[string]string{"FOO": "primary"}
+	job := core.Job{
+		Spec: core.JobSpec{
+			PrimaryContainer: core.JobContainerSpec{
+				ContainerSpec: core.ContainerSpec{
+					Environment: primaryEnv,
+				},
+			},
+			SidecarContainers: map[string]core.JobContainerSpec{
+				"sidecar": {},
+			},
+		},
+	}
+	addJobEnvironment(&job, nil)
+	require.Nil(t, job.Spec.SidecarContainers["sidecar"].Environment)
+
+	addJobEnvironment(&job, map[string]string{"FOO": "foo", "BAR": "bar"})
+	// Variables defined by the container take precedence
+	require.Equal(
+		t,
+		map[string]string{"FOO": "primary", "BAR": "bar"},
+		job.Spec.PrimaryContainer.Environment,
+	)
+	require.Equal(
+		t,
+		map[string]string{"FOO": "foo", "BAR": "bar"},
+		job.Spec.SidecarContainers["sidecar"].Environment,
+	)
+	// The original environment is not modified
+	require.Equal(t, map[string]string{"FOO": "primary"}, primaryEnv)
+}
@@ -6,18 +6,19 @@ import (
 	"sync"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
-	"github.com/lovethedrake/go-drake/config"
 )
 
 func executePipeline(
 	ctx context.Context,
 	event brigade.Event,
-	pipeline config.Pipeline,
+	servicePipeline servicePipeline,
+	runner *jobRunner,
 	wg *sync.WaitGroup,
 	errCh chan<- error,
 ) {
 	defer wg.Done()
-	log.Printf("executing pipeline %q", pipeline.Name())
+	pipeline := servicePipeline.pipeline
+	log.Printf("executing pipeline %q", servicePipeline.name())
 	jobs := pipeline.Jobs()
 
 	// Build a map of channels that lets the job scheduler subscribe to the
@@ -59,10 +60,10 @@ func executePipeline(
 					return
 				}
 			}
-			if err := runJob(
+			if err := runner.runJob(
 				ctx,
 				event,
-				pipeline.Name(),
+				servicePipeline,
 				job.Job(),
 			); err != nil {
 				// This localErrCh write isn't in a select because we don't want it to
@@ -0,0 +1,92 @@
+package executor
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"log"
+	"strings"
+
+	"github.com/lovethedrake/canard/pkg/workerconfig"
+	"github.com/pkg/errors"
+)
+
+// buildSummary summarizes the outcome of a build for reporting purposes.
+type buildSummary struct {
+	EventID   string   `json:"eventID"`
+	Pipelines []string `json:"pipelines"`
+	Succeeded bool     `json:"succeeded"`
+	Errors    []string `json:"errors,omitempty"`
+	// Decisions are the decisions reached by every trigger of every pipeline.
+	Decisions []triggerDecision `json:"decisions"`
+}
+
+func newBuildSummary(
+	eventID string,
+	pipelines []string,
+	decisions []triggerDecision,
+	err error,
+) buildSummary {
+	summary := buildSummary{
+		EventID:   eventID,
+		Pipelines: pipelines,
+		Succeeded: err == nil,
+		Decisions: decisions,
+	}
+	if summary.Pipelines == nil {
+		summary.Pipelines = []string{}
+	}
+	if summary.Decisions == nil {
+		summary.Decisions = []triggerDecision{}
+	}
+	if merr, ok := err.(*multiError); ok {
+		for _, e := range merr.errs {
+			summary.Errors = append(summary.Errors, e.Error())
+		}
+	} else if err != nil {
+		summary.Errors = []string{err.Error()}
+	}
+	return summary
+}
+
+// report delivers the summary to every configured target. A failure to report
+// to any one target is logged, but does not affect the outcome of the build.
+func report(targets []workerconfig.ReportingTarget, summary buildSummary) {
+	for _, target := range targets {
+		if err := reportTo(target, summary); err != nil {
+			log.Printf("error reporting build summary: %s", err)
+		}
+	}
+}
+
+func reportTo(
+	target workerconfig.ReportingTarget,
+	summary buildSummary,
+) error {
+	switch target.Type {
+	case workerconfig.ReportingTargetLog:
+		outcome := "succeeded"
+		if !summary.Succeeded {
+			outcome = "failed"
+		}
+		log.Printf(
+			"build for event %s %s; pipelines executed: [%s]; errors: %d",
+			summary.EventID,
+			outcome,
+			strings.Join(summary.Pipelines, ", "),
+			len(summary.Errors),
+		)
+		return nil
+	case workerconfig.ReportingTargetFile:
+		summaryBytes, err := json.MarshalIndent(summary, "", "  ")
+		if err != nil {
+			return errors.Wrap(err, "error marshaling build summary")
+		}
+		return errors.Wrapf(
+			ioutil.WriteFile(target.Path, summaryBytes, 0644),
+			"error writing build summary to %s",
+			target.Path,
+		)
+	default:
+		return errors.Errorf("unrecognized reporting target type %q", target.Type)
+	}
+}
@@ -0,0 +1,73 @@
+package executor
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/workerconfig"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewBuildSummary(t *testing.T) {
+	summary := newBuildSummary("foo", nil, nil, nil)
+	require.True(t, summary.Succeeded)
+	require.Equal(t, []string{}, summary.Pipelines)
+	require.Equal(t, []triggerDecision{}, summary.Decisions)
+	require.Empty(t, summary.Errors)
+
+	summary = newBuildSummary(
+		"foo",
+		[]string{"bar"},
+		nil,
+		&multiError{errs: []error{errors.New("bat"), errors.New("baz")}},
+	)
+	require.False(t, summary.Succeeded)
+	require.Equal(t, []string{"bat", "baz"}, summary.Errors)
+}
+
+func TestReportTo(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	summary := newBuildSummary(
+		"foo",
+		[]string{"bar"},
+		[]triggerDecision{
+			{
+				Pipeline: "bar",
+				SpecURI:  "example.com/drakespec-foo",
+				Decision: drake.Matched("foo", "bar", "because"),
+			},
+		},
+		errors.New("bat"),
+	)
+
+	err = reportTo(
+		workerconfig.ReportingTarget{Type: workerconfig.ReportingTargetLog},
+		summary,
+	)
+	require.NoError(t, err)
+
+	path := filepath.Join(dir, "summary.json")
+	err = reportTo(
+		workerconfig.ReportingTarget{
+			Type: workerconfig.ReportingTargetFile,
+			Path: path,
+		},
+		summary,
+	)
+	require.NoError(t, err)
+	summaryBytes, err := ioutil.ReadFile(path)
+	require.NoError(t, err)
+	reported := buildSummary{}
+	require.NoError(t, json.Unmarshal(summaryBytes, &reported))
+	require.Equal(t, summary, reported)
+
+	err = reportTo(workerconfig.ReportingTarget{Type: "bogus"}, summary)
+	require.Error(t, err)
+}
@@ -0,0 +1,170 @@
+package executor
+
+import (
+	"fmt"
+	"path"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/brigadecore/brigade/sdk/v2/core"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/go-drake/config"
+)
+
+// Brigade uses job names as Kubernetes label values, so they are restricted to
+// lower case alphanumeric characters and hyphens and to 63 characters.
+const maxJobNameLength = 63
+
+var jobNameRegex = regexp.MustCompile(`^[a-z0-9]([a-z0-9-]*[a-z0-9])?$`)
+
+// servicePipeline is a pipeline along with the directory, relative to the root
+// of the checkout, of the service whose Drakefile defined it. For pipelines
+// defined by the project as a whole, service is empty.
+type servicePipeline struct {
+	service  string
+	pipeline config.Pipeline
+	// env holds environment variables, provided by the triggers that matched,
+	// to be set in every container of every job of the pipeline.
+	env map[string]string
+}
+
+// name returns the pipeline's name, qualified by its service's directory, if
+// any.
+func (s servicePipeline) name() string {
+	if s.service == "" {
+		return s.pipeline.Name()
+	}
+	return filepath.ToSlash(s.service) + ":" + s.pipeline.Name()
+}
+
+// scopedJobName returns the name, namespaced by the provided service, of the
+// job having the provided name. Since both service directories and job names
+// may contain hyphens, distinct services' jobs can have the same scoped name.
+// See checkJobNames(). If service is empty, the name is returned unaltered.
+func scopedJobName(service string, job string) string {
+	if service == "" {
+		return job
+	}
+	return strings.ReplaceAll(filepath.ToSlash(service), "/", "-") + "-" + job
+}
+
+// checkJobNames returns an error describing every job of the provided
+// pipelines whose scoped name (see scopedJobName()) is the same as that of a
+// different job or is not accepted by Brigade. Names of jobs that aren't
+// scoped to a service are used exactly as they are defined and aren't
+// checked.
+func checkJobNames(pipelines []servicePipeline) error {
+	type serviceJob struct {
+		service string
+		job     string
+	}
+	serviceJobs := map[string]serviceJob{}
+	problems := []string{}
+	for _, p := range pipelines {
+		for _, pipelineJob := range p.pipeline.Jobs() {
+			job := serviceJob{
+				service: filepath.ToSlash(p.service),
+				job:     pipelineJob.Job().Name(),
+			}
+			name := scopedJobName(job.service, job.job)
+			other, ok := serviceJobs[name]
+			if ok {
+				if other != job {
+					problems = append(
+						problems,
+						fmt.Sprintf(
+							"job %q of service %q and job %q of service %q would both be "+
+								"named %q",
+							other.job,
+							other.service,
+							job.job,
+							job.service,
+							name,
+						),
+					)
+				}
+				continue
+			}
+			serviceJobs[name] = job
+			if job.service == "" {
+				continue
+			}
+			if len(name) > maxJobNameLength {
+				problems = append(
+					problems,
+					fmt.Sprintf(
+						"job %q of service %q would be named %q, which exceeds %d "+
+							"characters",
+						job.job,
+						job.service,
+						name,
+						maxJobNameLength,
+					),
+				)
+			} else if !jobNameRegex.MatchString(name) {
+				problems = append(
+					problems,
+					fmt.Sprintf(
+						"job %q of service %q would be named %q, which may contain only "+
+							"lower case alphanumeric characters and hyphens and must begin "+
+							"and end with an alphanumeric character",
+						job.job,
+						job.service,
+						name,
+					),
+				)
+			}
+		}
+	}
+	return drake.NewValidationError(problems)
+}
+
+// scopeJobToService namespaces the provided job's name (see scopedJobName())
+// so it cannot collide with any job of the same name defined by another
+// service and scopes the working directory of each of the job's containers
+// that mount source to the service's directory. Absolute working directories
+// outside the source mount are left alone. If service is empty, the job is not
+// altered.
+func scopeJobToService(job *core.Job, service string) {
+	if service == "" {
+		return
+	}
+	service = filepath.ToSlash(service)
+	job.Name = scopedJobName(service, job.Name)
+	job.Spec.PrimaryContainer =
+		scopeContainerToService(job.Spec.PrimaryContainer, service)
+	for name, sidecar := range job.Spec.SidecarContainers {
+		job.Spec.SidecarContainers[name] =
+			scopeContainerToService(sidecar, service)
+	}
+}
+
+func scopeContainerToService(
+	container core.JobContainerSpec,
+	service string,
+) core.JobContainerSpec {
+	if container.SourceMountPath == "" {
+		return container
+	}
+	workingDirectory := container.WorkingDirectory
+	if path.IsAbs(workingDirectory) {
+		// e.g. /src/testdata is rebased onto /src/<service>/testdata
+		sourceMountPath := path.Clean(container.SourceMountPath)
+		workingDirectory = path.Clean(workingDirectory)
+		if workingDirectory == sourceMountPath {
+			workingDirectory = ""
+		} else if strings.HasPrefix(workingDirectory, sourceMountPath+"/") {
+			workingDirectory =
+				strings.TrimPrefix(workingDirectory, sourceMountPath+"/")
+		} else {
+			return container
+		}
+	}
+	container.WorkingDirectory = path.Join(
+		container.SourceMountPath,
+		service,
+		workingDirectory,
+	)
+	return container
+}
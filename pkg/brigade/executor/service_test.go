@@ -0,0 +1,203 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/brigadecore/brigade/sdk/v2/core"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/stretchr/testify/require"
+)
+
+func TestScopeJobToService(t *testing.T) {
+	newJob := func() core.Job {
+		return core.Job{
+			Name: "test",
+			Spec: core.JobSpec{
+				PrimaryContainer: core.JobContainerSpec{
+					SourceMountPath: "/src",
+				},
+				SidecarContainers: map[string]core.JobContainerSpec{
+					"relative": {
+						SourceMountPath:  "/src",
+						WorkingDirectory: "testdata",
+					},
+					"absolute": {
+						SourceMountPath:  "/src",
+						WorkingDirectory: "/tmp",
+					},
+					"source-mount-path": {
+						SourceMountPath:  "/src",
+						WorkingDirectory: "/src",
+					},
+					"within-source-mount-path": {
+						SourceMountPath:  "/src/",
+						WorkingDirectory: "/src/testdata",
+					},
+					"sibling-of-source-mount-path": {
+						SourceMountPath:  "/src",
+						WorkingDirectory: "/srcdata",
+					},
+					"no-source": {},
+				},
+			},
+		}
+	}
+
+	job := newJob()
+	scopeJobToService(&job, "")
+	require.Equal(t, newJob(), job)
+
+	scopeJobToService(&job, "services/api")
+	require.Equal(t, "services-api-test", job.Name)
+	require.Equal(
+		t,
+		"/src/services/api",
+		job.Spec.PrimaryContainer.WorkingDirectory,
+	)
+	require.Equal(
+		t,
+		"/src/services/api/testdata",
+		job.Spec.SidecarContainers["relative"].WorkingDirectory,
+	)
+	require.Equal(
+		t,
+		"/tmp",
+		job.Spec.SidecarContainers["absolute"].WorkingDirectory,
+	)
+	require.Equal(
+		t,
+		"/src/services/api",
+		job.Spec.SidecarContainers["source-mount-path"].WorkingDirectory,
+	)
+	require.Equal(
+		t,
+		"/src/services/api/testdata",
+		job.Spec.SidecarContainers["within-source-mount-path"].WorkingDirectory,
+	)
+	require.Equal(
+		t,
+		"/srcdata",
+		job.Spec.SidecarContainers["sibling-of-source-mount-path"].WorkingDirectory,
+	)
+	require.Empty(t, job.Spec.SidecarContainers["no-source"].WorkingDirectory)
+}
+
+func TestCheckJobNames(t *testing.T) {
+	newPipeline := func(service string, jobs ...string) servicePipeline {
+		yaml := `
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+`
+		for _, job := range jobs {
+			yaml += `
+  ` + job + `:
+    primaryContainer:
+      name: main
+      image: debian:stretch
+`
+		}
+		yaml += `
+pipelines:
+  ci:
+    jobs:
+`
+		for _, job := range jobs {
+			yaml += `
+    - name: ` + job + `
+`
+		}
+		cfg, err := config.NewConfigFromYAML([]byte(yaml))
+		require.NoError(t, err)
+		return servicePipeline{service: service, pipeline: cfg.AllPipelines()[0]}
+	}
+	testCases := []struct {
+		name       string
+		pipelines  []servicePipeline
+		assertions func(*testing.T, error)
+	}{
+		{
+			name: "distinct names",
+			pipelines: []servicePipeline{
+				newPipeline("", "test", "Build_All"),
+				newPipeline("services/api", "test"),
+				newPipeline("services/web", "test"),
+			},
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "same job of the same service in several pipelines",
+			pipelines: []servicePipeline{
+				newPipeline("services/api", "test"),
+				newPipeline("services/api", "test"),
+			},
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "colliding names",
+			pipelines: []servicePipeline{
+				newPipeline("a/b", "c"),
+				newPipeline("a-b", "c"),
+				newPipeline("a", "b-c"),
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.IsType(t, &drake.ValidationError{}, err)
+				require.Equal(
+					t,
+					[]string{
+						`job "c" of service "a/b" and job "c" of service "a-b" would ` +
+							`both be named "a-b-c"`,
+						`job "c" of service "a/b" and job "b-c" of service "a" would ` +
+							`both be named "a-b-c"`,
+					},
+					err.(*drake.ValidationError).Problems,
+				)
+			},
+		},
+		{
+			name: "name with invalid characters",
+			pipelines: []servicePipeline{
+				newPipeline("services/API", "unit_test"),
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.IsType(t, &drake.ValidationError{}, err)
+				problems := err.(*drake.ValidationError).Problems
+				require.Len(t, problems, 1)
+				require.Contains(
+					t,
+					problems[0],
+					`job "unit_test" of service "services/API" would be named `+
+						`"services-API-unit_test", which may contain only lower case`,
+				)
+			},
+		},
+		{
+			name: "name too long",
+			pipelines: []servicePipeline{
+				newPipeline(
+					"services/a-service-with-a-rather-long-name-indeed",
+					"integration-test",
+				),
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.IsType(t, &drake.ValidationError{}, err)
+				problems := err.(*drake.ValidationError).Problems
+				require.Len(t, problems, 1)
+				require.Contains(t, problems[0], "which exceeds 63 characters")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			testCase.assertions(t, checkJobNames(testCase.pipelines))
+		})
+	}
+}
@@ -0,0 +1,240 @@
+package executor
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/drake/brig"
+	"github.com/lovethedrake/canard/pkg/drake/github"
+	"github.com/lovethedrake/canard/pkg/drake/plugin"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/lovethedrake/canard/pkg/workerconfig"
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/pkg/errors"
+)
+
+// pipelineTriggers is a pipeline along with its built triggers.
+type pipelineTriggers struct {
+	servicePipeline
+	// triggers are indexed identically to the pipeline's triggers. Triggers
+	// whose spec URI isn't registered are nil.
+	triggers []drake.Trigger
+}
+
+// newTriggerRegistry returns a drake.TriggerRegistry having the built-in
+// triggers, with the GitHub trigger bound to the provided head of the event,
+// followed by all the registrations of drake.DefaultTriggerRegistry and then
+// any trigger plugins specified by the provided worker configuration, which is
+// expected to have been vetted by workerconfig.Load() so that those plugins
+// come from the project rather than the checkout. Later registrations take
+// precedence over earlier ones whose ranges are equally narrow (see
+// drake.TriggerRegistry's Register()), so embedders and plugins may supersede
+// the built-in triggers.
+func newTriggerRegistry(
+	workerCfg workerconfig.Config,
+	head githubapi.Head,
+) (*drake.TriggerRegistry, error) {
+	triggers := drake.NewTriggerRegistry()
+	if err := triggers.Register(
+		github.SpecURI,
+		github.SpecVersions,
+		github.NewTriggerBuilder(github.NewGitHubFileLister(), head),
+	); err != nil {
+		return nil, err
+	}
+	if err := triggers.Register(
+		brig.SpecURI,
+		brig.SpecVersions,
+		brig.NewTriggerFromJSON,
+	); err != nil {
+		return nil, err
+	}
+	triggers.RegisterAll(drake.DefaultTriggerRegistry)
+	for _, p := range workerCfg.Triggers.Plugins {
+		specVersions := p.SpecVersions
+		if specVersions == "" {
+			specVersions = "*"
+		}
+		if err := triggers.Register(
+			p.SpecURI,
+			specVersions,
+			plugin.NewTriggerBuilder(p.Path, p.Timeout.Duration),
+		); err != nil {
+			return nil, errors.Wrapf(err, "error registering plugin %s", p.Path)
+		}
+		log.Printf(
+			"registered plugin %s for trigger %s (versions %s)",
+			p.Path,
+			p.SpecURI,
+			specVersions,
+		)
+	}
+	return triggers, nil
+}
+
+// loadPipelines resolves the Drakefile of the provided service (or of the
+// project as a whole if service is empty) and returns every pipeline therein
+// along with its built triggers. Every trigger is validated and every problem
+// found with any of them is returned.
+func loadPipelines(
+	ctx context.Context,
+	event brigade.Event,
+	head githubapi.Head,
+	workerCfg workerconfig.Config,
+	triggers *drake.TriggerRegistry,
+	service string,
+) ([]pipelineTriggers, []string, error) {
+	df, err := newResolver(workerCfg, head, service).Resolve(ctx, event)
+	if err != nil {
+		return nil, nil, err
+	}
+	log.Printf("loading configuration from %s", df.Location)
+	cfg, err := config.NewConfigFromYAML(df.Contents)
+	if err != nil {
+		return nil, nil, errors.Wrapf(
+			err,
+			"error reading Drakefile contents from %s\n%s",
+			df.Location,
+			df.Contents,
+		)
+	}
+
+	log.Printf("loaded Drakefile configuration:\n%s", df.Contents)
+
+	pipelines := []pipelineTriggers{}
+	problems := []string{}
+	for _, pipeline := range cfg.AllPipelines() {
+		p := pipelineTriggers{
+			servicePipeline: servicePipeline{
+				service:  service,
+				pipeline: pipeline,
+			},
+			triggers: make([]drake.Trigger, len(pipeline.Triggers())),
+		}
+		for i, pipelineTrigger := range pipeline.Triggers() {
+			trigger, err := triggers.Build(
+				pipelineTrigger.SpecURI(),
+				pipelineTrigger.SpecVersion(),
+				pipelineTrigger.Config(),
+			)
+			if drake.IsUnregisteredTrigger(err) {
+				// Don't know what to do with this trigger...
+				continue // Next trigger
+			}
+			if err == nil {
+				err = drake.ValidateTrigger(trigger)
+			}
+			if err != nil {
+				problems = append(
+					problems,
+					triggerProblems(p.name(), i, pipelineTrigger.SpecURI(), err)...,
+				)
+				continue
+			}
+			p.triggers[i] = trigger
+		}
+		pipelines = append(pipelines, p)
+	}
+	return pipelines, problems, nil
+}
+
+// triggerProblems formats the problems described by the provided error, which
+// was encountered in building or validating a pipeline's trigger.
+func triggerProblems(
+	pipelineName string,
+	index int,
+	specURI string,
+	err error,
+) []string {
+	prefix := fmt.Sprintf(
+		"pipeline %q trigger %d (%s)",
+		pipelineName,
+		index,
+		specURI,
+	)
+	if verr, ok := errors.Cause(err).(*drake.ValidationError); ok {
+		problems := make([]string, len(verr.Problems))
+		for i, problem := range verr.Problems {
+			problems[i] = fmt.Sprintf("%s: %s", prefix, problem)
+		}
+		return problems
+	}
+	return []string{fmt.Sprintf("%s: %s", prefix, err)}
+}
+
+// evaluateTriggers returns every pipeline having any trigger that is
+// satisfied by the event, along with the decisions reached by every trigger of
+// every pipeline. Every trigger is evaluated, even after one has matched, so
+// that every decision can be reported. A trigger that fails to reach a
+// decision is recorded as not having matched, so it doesn't prevent its
+// pipeline from executing if another of the pipeline's triggers matched. If
+// none did, whether the pipeline should have executed is unknown, so the
+// failure is returned as an error along with all the decisions.
+func evaluateTriggers(
+	event brigade.Event,
+	pipelines []pipelineTriggers,
+) ([]servicePipeline, []triggerDecision, error) {
+	pipelinesToExecute := []servicePipeline{}
+	decisions := []triggerDecision{}
+	var evalErr error
+	for _, p := range pipelines {
+		matched := false
+		var pipelineErr error
+		env := map[string]string{}
+		for i, pipelineTrigger := range p.pipeline.Triggers() {
+			decision := triggerDecision{
+				Pipeline: p.name(),
+				Trigger:  i,
+				SpecURI:  pipelineTrigger.SpecURI(),
+			}
+			trigger := p.triggers[i]
+			if trigger == nil {
+				decision.Reason = "trigger is not registered"
+				decisions = append(decisions, decision)
+				continue // Next trigger
+			}
+			var err error
+			if decision.Decision, err = trigger.Matches(event); err != nil {
+				decision.Decision = drake.Decision{
+					Reason: fmt.Sprintf("error reaching a decision: %s", err),
+				}
+				if pipelineErr == nil {
+					pipelineErr = errors.Wrapf(
+						err,
+						"error evaluating execution criteria for trigger %d (%q) "+
+							"configuration for pipeline %q",
+						i,
+						pipelineTrigger.SpecURI(),
+						p.name(),
+					)
+				}
+			}
+			decisions = append(decisions, decision)
+			if decision.Matched {
+				matched = true
+				// Where more than one trigger sets the same variable, the first wins
+				for k, v := range decision.Env {
+					if _, ok := env[k]; !ok {
+						env[k] = v
+					}
+				}
+			}
+		}
+		if matched {
+			servicePipeline := p.servicePipeline
+			if len(env) > 0 {
+				servicePipeline.env = env
+			}
+			pipelinesToExecute = append(pipelinesToExecute, servicePipeline)
+		} else if evalErr == nil {
+			evalErr = pipelineErr
+		}
+	}
+	if evalErr != nil {
+		return nil, decisions, evalErr
+	}
+	return pipelinesToExecute, decisions, nil
+}
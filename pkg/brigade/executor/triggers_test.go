@@ -0,0 +1,247 @@
+package executor
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/drake/github"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/lovethedrake/canard/pkg/workerconfig"
+	"github.com/lovethedrake/go-drake/config"
+	"github.com/pkg/errors"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTriggerProblems(t *testing.T) {
+	require.Equal(
+		t,
+		[]string{
+			`pipeline "ci" trigger 1 (example.com/foo): foo`,
+			`pipeline "ci" trigger 1 (example.com/foo): bar`,
+		},
+		triggerProblems(
+			"ci",
+			1,
+			"example.com/foo",
+			drake.NewValidationError([]string{"foo", "bar"}),
+		),
+	)
+	require.Equal(
+		t,
+		[]string{`pipeline "ci" trigger 1 (example.com/foo): baz`},
+		triggerProblems("ci", 1, "example.com/foo", errors.New("baz")),
+	)
+}
+
+func TestBuiltInTriggersAreValidated(t *testing.T) {
+	triggers, err := newTriggerRegistry(workerconfig.Config{}, githubapi.Head{})
+	require.NoError(t, err)
+	_, err = triggers.Build(
+		github.SpecURI,
+		"v1.0.0",
+		[]byte(`{"push":{"branches":{"only":["/[/"]}}}`),
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "push.branches.only[0]")
+
+	trigger, err := triggers.Build(
+		github.SpecURI,
+		"v1.0.0",
+		[]byte(`{"push":{}}`),
+	)
+	require.NoError(t, err)
+	err = drake.ValidateTrigger(trigger)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "push.branches or push.tags")
+}
+
+func TestBuiltInGitHubTriggerUsesHead(t *testing.T) {
+	event := brigade.Event{
+		Source: github.GitHubEventSource,
+		Type:   "check_suite:requested",
+		Payload: `{"check_suite":{"head_branch":"master","head_sha":"abc123"},` +
+			`"repository":{"full_name":"lovethedrake/canard"}}`,
+	}
+	for _, trusted := range []bool{true, false} {
+		triggers, err := newTriggerRegistry(
+			workerconfig.Config{},
+			githubapi.Head{Trusted: trusted},
+		)
+		require.NoError(t, err)
+		trigger, err := triggers.Build(
+			github.SpecURI,
+			"v1.0.0",
+			[]byte(`{"checkSuite":{"headBranches":{"only":["master"]}}}`),
+		)
+		require.NoError(t, err)
+		decision, err := trigger.Matches(event)
+		require.NoError(t, err)
+		require.Equal(t, trusted, decision.Matched)
+	}
+}
+
+// decidedTrigger is a drake.Trigger that always reaches the same decision.
+type decidedTrigger drake.Decision
+
+func (d decidedTrigger) Matches(brigade.Event) (drake.Decision, error) {
+	return drake.Decision(d), nil
+}
+
+// failingTrigger is a drake.Trigger that never reaches a decision.
+type failingTrigger struct{}
+
+func (failingTrigger) Matches(brigade.Event) (drake.Decision, error) {
+	return drake.Decision{}, errors.New("something went wrong")
+}
+
+func TestEvaluateTriggersEnv(t *testing.T) {
+	cfg, err := config.NewConfigFromYAML([]byte(`
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  foo:
+    primaryContainer:
+      name: foo
+      image: debian:stretch
+pipelines:
+  ci:
+    triggers:
+    - specUri: example.com/drakespec-foo
+      specVersion: v1.0.0
+    - specUri: example.com/drakespec-foo
+      specVersion: v1.0.0
+    - specUri: example.com/drakespec-foo
+      specVersion: v1.0.0
+    jobs:
+    - name: foo
+`))
+	require.NoError(t, err)
+	pipelines, decisions, err := evaluateTriggers(
+		brigade.Event{},
+		[]pipelineTriggers{
+			{
+				servicePipeline: servicePipeline{
+					pipeline: cfg.AllPipelines()[0],
+				},
+				triggers: []drake.Trigger{
+					decidedTrigger{
+						Env: map[string]string{"FOO": "ignored"},
+					},
+					decidedTrigger{
+						Matched: true,
+						Env:     map[string]string{"FOO": "foo", "BAR": "bar"},
+					},
+					decidedTrigger{
+						Matched: true,
+						Env:     map[string]string{"BAR": "ignored", "BAZ": "baz"},
+					},
+				},
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Len(t, decisions, 3)
+	require.Len(t, pipelines, 1)
+	require.Equal(
+		t,
+		map[string]string{"FOO": "foo", "BAR": "bar", "BAZ": "baz"},
+		pipelines[0].env,
+	)
+}
+
+func TestEvaluateTriggersErrors(t *testing.T) {
+	cfg, err := config.NewConfigFromYAML([]byte(`
+specUri: github.com/lovethedrake/drakespec
+specVersion: v0.6.0
+jobs:
+  foo:
+    primaryContainer:
+      name: foo
+      image: debian:stretch
+pipelines:
+  ci:
+    triggers:
+    - specUri: example.com/drakespec-foo
+      specVersion: v1.0.0
+    - specUri: example.com/drakespec-foo
+      specVersion: v1.0.0
+    jobs:
+    - name: foo
+`))
+	require.NoError(t, err)
+	newPipeline := func(triggers ...drake.Trigger) pipelineTriggers {
+		return pipelineTriggers{
+			servicePipeline: servicePipeline{
+				pipeline: cfg.AllPipelines()[0],
+			},
+			triggers: triggers,
+		}
+	}
+	testCases := []struct {
+		name       string
+		pipeline   pipelineTriggers
+		assertions func(*testing.T, []servicePipeline, []triggerDecision, error)
+	}{
+		{
+			name: "another trigger matched",
+			pipeline: newPipeline(
+				failingTrigger{},
+				decidedTrigger{Matched: true, Reason: "matched"},
+			),
+			assertions: func(
+				t *testing.T,
+				pipelines []servicePipeline,
+				decisions []triggerDecision,
+				err error,
+			) {
+				require.NoError(t, err)
+				require.Len(t, pipelines, 1)
+				require.Len(t, decisions, 2)
+				require.False(t, decisions[0].Matched)
+				require.Equal(
+					t,
+					"error reaching a decision: something went wrong",
+					decisions[0].Reason,
+				)
+				require.True(t, decisions[1].Matched)
+			},
+		},
+		{
+			name: "no other trigger matched",
+			pipeline: newPipeline(
+				decidedTrigger{Reason: "not matched"},
+				failingTrigger{},
+			),
+			assertions: func(
+				t *testing.T,
+				pipelines []servicePipeline,
+				decisions []triggerDecision,
+				err error,
+			) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					`error evaluating execution criteria for trigger 1`,
+				)
+				require.Empty(t, pipelines)
+				require.Len(t, decisions, 2)
+				require.Equal(
+					t,
+					"error reaching a decision: something went wrong",
+					decisions[1].Reason,
+				)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pipelines, decisions, err := evaluateTriggers(
+				brigade.Event{},
+				[]pipelineTriggers{testCase.pipeline},
+			)
+			testCase.assertions(t, pipelines, decisions, err)
+		})
+	}
+}
@@ -1,8 +1,7 @@
 package brig
 
 import (
-	"encoding/json"
-	"log"
+	"fmt"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
 	"github.com/lovethedrake/canard/pkg/drake"
@@ -10,35 +9,70 @@ import (
 
 const BrigadeCLIEventSource = "brigade.sh/cli"
 
+const (
+	// SpecURI identifies the trigger spec implemented by this package.
+	SpecURI = "github.com/lovethedrake/drakespec-brig"
+	// SpecVersions is the range of versions of the trigger spec implemented by
+	// this package.
+	SpecVersions = "^1.0.0"
+)
+
 type trigger struct {
 	EventTypes []string `json:"eventTypes"`
 }
 
 // NewTriggerFromJSON takes a slice of bytes containing JSON as an argument and
 // returns a Trigger that implements the
-// github.com/lovethedrake/drakespec-github spec.
+// github.com/lovethedrake/drakespec-brig spec.
 func NewTriggerFromJSON(jsonBytes []byte) (drake.Trigger, error) {
 	t := &trigger{}
-	err := json.Unmarshal(jsonBytes, t)
+	err := drake.UnmarshalStrict(jsonBytes, t)
 	return t, err
 }
 
-func (t *trigger) Matches(event brigade.Event) (bool, error) {
+// Validate implements drake.Validator.
+func (t *trigger) Validate() error {
+	problems := []string{}
+	if len(t.EventTypes) == 0 {
+		problems = append(
+			problems,
+			"eventTypes must not be empty; without any, no event can match",
+		)
+	}
+	for i, eventType := range t.EventTypes {
+		if eventType == "" {
+			problems = append(
+				problems,
+				fmt.Sprintf("eventTypes[%d] must not be empty", i),
+			)
+		}
+	}
+	return drake.NewValidationError(problems)
+}
+
+func (t *trigger) Matches(event brigade.Event) (drake.Decision, error) {
 	if event.Source != BrigadeCLIEventSource {
-		log.Printf(
-			"event from source %q does not match brig trigger",
+		return drake.NotMatched(
+			"",
 			event.Source,
-		)
-		return false, nil
+			"event is not from the brig CLI",
+		), nil
 	}
 
 	for _, eventType := range t.EventTypes {
 		if event.Type == eventType {
-			log.Printf("%q event matches trigger", event.Type)
-			return true, nil
+			return drake.Matched(
+				"eventTypes",
+				event.Type,
+				"event type is %q",
+				eventType,
+			), nil
 		}
 	}
 
-	log.Printf("%q event does not match trigger", event.Type)
-	return false, nil
+	return drake.NotMatched(
+		"eventTypes",
+		event.Type,
+		"event type is not among those listed",
+	), nil
 }
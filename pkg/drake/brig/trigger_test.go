@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
 	"github.com/stretchr/testify/require"
 )
 
@@ -12,7 +13,7 @@ func TestMatches(t *testing.T) {
 		name       string
 		trigger    *trigger
 		event      brigade.Event
-		assertions func(*testing.T, bool, error)
+		assertions func(*testing.T, drake.Decision, error)
 	}{
 		{
 			name:    "unsupported event type",
@@ -21,9 +22,9 @@ func TestMatches(t *testing.T) {
 				Source: "github",
 				Type:   "pull_request:opened",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -35,9 +36,9 @@ func TestMatches(t *testing.T) {
 				Source: BrigadeCLIEventSource,
 				Type:   "bar",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -49,17 +50,35 @@ func TestMatches(t *testing.T) {
 				Source: BrigadeCLIEventSource,
 				Type:   "foo",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.True(t, matches)
+				require.True(t, decision.Matched)
 			},
 		},
 	}
 
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			matches, err := testCase.trigger.Matches(testCase.event)
-			testCase.assertions(t, matches, err)
+			decision, err := testCase.trigger.Matches(testCase.event)
+			testCase.assertions(t, decision, err)
 		})
 	}
 }
+
+func TestNewTriggerFromJSON(t *testing.T) {
+	_, err := NewTriggerFromJSON([]byte(`{"eventTypes":["foo"]}`))
+	require.NoError(t, err)
+	_, err = NewTriggerFromJSON([]byte(`{"eventtypes":["foo"]}`))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "eventtypes: unknown field")
+}
+
+func TestValidate(t *testing.T) {
+	require.NoError(t, (&trigger{EventTypes: []string{"foo"}}).Validate())
+	err := (&trigger{}).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "eventTypes must not be empty")
+	err = (&trigger{EventTypes: []string{""}}).Validate()
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "eventTypes[0] must not be empty")
+}
@@ -0,0 +1,103 @@
+package github
+
+import (
+	"fmt"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+)
+
+// checkSuiteEventSelector selects check suites that are requested or
+// re-requested and check runs that are re-requested. A re-requested check suite
+// or check run is selected if, and only if, the check suite would have been
+// selected when it was first requested, so re-requesting re-triggers the same
+// pipelines. Since a check suite for a pull request from a fork has a head
+// branch named by the fork's author, a check suite is only selected if its head
+// commit is trusted. See githubapi.IsCheckSuiteTrusted().
+type checkSuiteEventSelector struct {
+	HeadBranchSelector *refSelector `json:"headBranches,omitempty"`
+	// PullRequests, if specified, selects only check suites belonging to at
+	// least one pull request if true and only check suites belonging to none if
+	// false.
+	PullRequests *bool `json:"pullRequests,omitempty"`
+}
+
+func (c *checkSuiteEventSelector) validate(path string) []string {
+	if c.HeadBranchSelector == nil {
+		return []string{
+			fmt.Sprintf(
+				"%s.headBranches must be specified; without it, no check suite can "+
+					"match",
+				path,
+			),
+		}
+	}
+	return nil
+}
+
+func (c *checkSuiteEventSelector) compile(path string) []string {
+	if c.HeadBranchSelector == nil {
+		return nil
+	}
+	return c.HeadBranchSelector.compile(path + ".headBranches")
+}
+
+func (c *checkSuiteEventSelector) matches(
+	event brigade.Event,
+	head githubapi.Head,
+) (drake.Decision, error) {
+	const selector = "checkSuite.headBranches"
+	if c.HeadBranchSelector == nil {
+		return drake.NotMatched(
+			selector,
+			"",
+			"no head branch selector is configured",
+		), nil
+	}
+	checkSuite, repo, err := githubapi.CheckSuiteOf(event)
+	if err != nil {
+		return drake.Decision{}, err
+	}
+	branch := checkSuite.GetHeadBranch()
+	if branch == "" {
+		// This is the case, for instance, for check suites requested for tags
+		return drake.NotMatched(
+			selector,
+			checkSuite.GetHeadSHA(),
+			"check suite has no head branch",
+		), nil
+	}
+	match, reason := c.HeadBranchSelector.matches(branch)
+	decision := drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    branch,
+		Reason:   reason,
+	}
+	if !match {
+		return decision, nil
+	}
+	if c.PullRequests != nil {
+		hasPullRequests := len(checkSuite.PullRequests) > 0
+		if *c.PullRequests != hasPullRequests {
+			return drake.NotMatched(
+				"checkSuite.pullRequests",
+				fmt.Sprintf("%d", len(checkSuite.PullRequests)),
+				"check suite belongs to a pull request: %t; %t is required",
+				hasPullRequests,
+				*c.PullRequests,
+			), nil
+		}
+	}
+	if !head.Trusted {
+		return drake.NotMatched(
+			"checkSuite",
+			checkSuite.GetHeadSHA(),
+			"head commit is not known to belong to %s; the check suite may be "+
+				"for a pull request from a fork",
+			repo.GetFullName(),
+		), nil
+	}
+	return decision, nil
+}
@@ -0,0 +1,239 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCheckSuiteEventSelectorMatches(t *testing.T) {
+	truth := true
+	falsity := false
+	// nolint: lll
+	const (
+		checkSuitePayload = `{
+	"action": "requested",
+	"check_suite": {
+		"head_branch": "feature/foo",
+		"head_sha": "abc123",
+		"pull_requests": [{"number": 42, "head": {"ref": "feature/foo", "sha": "abc123", "repo": {"id": 1}}, "base": {"ref": "master", "repo": {"id": 1}}}]
+	},
+	"repository": {"id": 1, "full_name": "lovethedrake/canard"}
+}`
+		checkRunPayload = `{
+	"action": "rerequested",
+	"check_run": {
+		"name": "ci",
+		"check_suite": {"head_branch": "master", "head_sha": "def456", "pull_requests": []}
+	},
+	"repository": {"id": 1, "full_name": "lovethedrake/canard"}
+}`
+		tagCheckSuitePayload = `{
+	"action": "requested",
+	"check_suite": {"head_branch": null, "head_sha": "abc123", "pull_requests": []},
+	"repository": {"id": 1, "full_name": "lovethedrake/canard"}
+}`
+		// A pull request from a fork is not listed by its check suite and its
+		// head branch is named by the fork's author
+		forkCheckSuitePayload = `{
+	"action": "requested",
+	"check_suite": {"head_branch": "master", "head_sha": "bad666", "pull_requests": []},
+	"repository": {"id": 1, "full_name": "lovethedrake/canard"}
+}`
+	)
+	testCases := []struct {
+		name     string
+		selector checkSuiteEventSelector
+		// untrusted indicates whether the head isn't trusted (see
+		// githubapi.HeadOf())
+		untrusted bool
+		event     brigade.Event
+		decision  drake.Decision
+	}{
+		{
+			name: "requested check suite with selected head branch",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"feature/**"},
+				},
+				PullRequests: &truth,
+			},
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: checkSuitePayload,
+			},
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "checkSuite.headBranches",
+				Value:    "feature/foo",
+				Reason:   `"feature/foo" matches only "feature/**"`,
+			},
+		},
+		{
+			name: "re-requested check suite is selected the same way",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"feature/**"},
+				},
+			},
+			event: brigade.Event{
+				Type:    "check_suite:rerequested",
+				Payload: checkSuitePayload,
+			},
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "checkSuite.headBranches",
+				Value:    "feature/foo",
+				Reason:   `"feature/foo" matches only "feature/**"`,
+			},
+		},
+		{
+			name: "check suite with unselected head branch",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"master"},
+				},
+			},
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: checkSuitePayload,
+			},
+			decision: drake.Decision{
+				Selector: "checkSuite.headBranches",
+				Value:    "feature/foo",
+				Reason:   `"feature/foo" matches none of only [master]`,
+			},
+		},
+		{
+			name: "check suite belonging to a pull request",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{},
+				PullRequests:       &falsity,
+			},
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: checkSuitePayload,
+			},
+			decision: drake.Decision{
+				Selector: "checkSuite.pullRequests",
+				Value:    "1",
+				Reason:   "check suite belongs to a pull request: true; false is required",
+			},
+		},
+		{
+			name: "re-requested check run",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"master"},
+				},
+				PullRequests: &falsity,
+			},
+			event: brigade.Event{
+				Type:    "check_run:rerequested",
+				Payload: checkRunPayload,
+			},
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "checkSuite.headBranches",
+				Value:    "master",
+				Reason:   `"master" matches only "master"`,
+			},
+		},
+		{
+			name: "re-requested check run not belonging to a pull request",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{},
+				PullRequests:       &truth,
+			},
+			event: brigade.Event{
+				Type:    "check_run:rerequested",
+				Payload: checkRunPayload,
+			},
+			decision: drake.Decision{
+				Selector: "checkSuite.pullRequests",
+				Value:    "0",
+				Reason:   "check suite belongs to a pull request: false; true is required",
+			},
+		},
+		{
+			name: "check suite from fork with head branch master",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"master"},
+				},
+			},
+			untrusted: true,
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: forkCheckSuitePayload,
+			},
+			decision: drake.Decision{
+				Selector: "checkSuite",
+				Value:    "bad666",
+				Reason: "head commit is not known to belong to lovethedrake/canard; " +
+					"the check suite may be for a pull request from a fork",
+			},
+		},
+		{
+			name: "re-requested check run with untrusted head",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"master"},
+				},
+			},
+			untrusted: true,
+			event: brigade.Event{
+				Type:    "check_run:rerequested",
+				Payload: checkRunPayload,
+			},
+			decision: drake.Decision{
+				Selector: "checkSuite",
+				Value:    "def456",
+				Reason: "head commit is not known to belong to lovethedrake/canard; " +
+					"the check suite may be for a pull request from a fork",
+			},
+		},
+		{
+			name: "check suite without head branch",
+			selector: checkSuiteEventSelector{
+				HeadBranchSelector: &refSelector{},
+			},
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: tagCheckSuitePayload,
+			},
+			decision: drake.Decision{
+				Selector: "checkSuite.headBranches",
+				Value:    "abc123",
+				Reason:   "check suite has no head branch",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("checkSuite"))
+			testCase.event.Source = "brigade.sh/github"
+			decision, err := (&trigger{
+				CheckSuiteEventSelector: &testCase.selector,
+				head:                    githubapi.Head{Trusted: !testCase.untrusted},
+			}).Matches(testCase.event)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestCheckSuiteEventSelectorValidate(t *testing.T) {
+	selector := checkSuiteEventSelector{}
+	require.Equal(
+		t,
+		[]string{
+			"checkSuite.headBranches must be specified; without it, no check " +
+				"suite can match",
+		},
+		selector.validate("checkSuite"),
+	)
+}
@@ -0,0 +1,98 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+const (
+	// deploymentIDEnvVar is the name of the environment variable through which
+	// the ID of a deployment is exposed to jobs.
+	deploymentIDEnvVar = "DRAKE_DEPLOYMENT_ID"
+	// deploymentEnvironmentEnvVar is the name of the environment variable
+	// through which the environment of a deployment is exposed to jobs.
+	deploymentEnvironmentEnvVar = "DRAKE_DEPLOYMENT_ENVIRONMENT"
+)
+
+// deploymentEventSelector selects deployments by the environments they target
+// and, optionally, by the refs they deploy. Since environments may be selected
+// by pattern, the environment and ID of a selected deployment are exposed to
+// jobs.
+type deploymentEventSelector struct {
+	EnvironmentSelector *refSelector `json:"environments,omitempty"`
+	// RefSelector, if specified, selects deployments by the ref they deploy,
+	// which may be a branch, a tag, or a commit SHA.
+	RefSelector *refSelector `json:"refs,omitempty"`
+}
+
+func (d *deploymentEventSelector) validate(path string) []string {
+	if d.EnvironmentSelector == nil {
+		return []string{
+			fmt.Sprintf(
+				"%s.environments must be specified; without it, no deployment can "+
+					"match",
+				path,
+			),
+		}
+	}
+	return nil
+}
+
+func (d *deploymentEventSelector) compile(path string) []string {
+	problems := []string{}
+	if d.EnvironmentSelector != nil {
+		problems = append(
+			problems,
+			d.EnvironmentSelector.compile(path+".environments")...,
+		)
+	}
+	if d.RefSelector != nil {
+		problems = append(problems, d.RefSelector.compile(path+".refs")...)
+	}
+	return problems
+}
+
+func (d *deploymentEventSelector) matches(
+	event brigade.Event,
+) (drake.Decision, error) {
+	de := github.DeploymentEvent{}
+	if err := json.Unmarshal([]byte(event.Payload), &de); err != nil {
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	const selector = "deployment.environments"
+	environment := de.GetDeployment().GetEnvironment()
+	if d.EnvironmentSelector == nil {
+		return drake.NotMatched(
+			selector,
+			environment,
+			"no environment selector is configured",
+		), nil
+	}
+	match, reason := d.EnvironmentSelector.matches(environment)
+	decision := drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    environment,
+		Reason:   reason,
+	}
+	if !match {
+		return decision, nil
+	}
+	if d.RefSelector != nil {
+		ref := de.GetDeployment().GetRef()
+		if match, reason := d.RefSelector.matches(ref); !match {
+			return drake.NotMatched("deployment.refs", ref, "%s", reason), nil
+		}
+	}
+	decision.Env = map[string]string{
+		deploymentIDEnvVar:          fmt.Sprintf("%d", de.GetDeployment().GetID()),
+		deploymentEnvironmentEnvVar: environment,
+	}
+	return decision, nil
+}
@@ -0,0 +1,94 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestDeploymentEventSelectorMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector deploymentEventSelector
+		decision drake.Decision
+	}{
+		{
+			name: "deployment to selected environment",
+			selector: deploymentEventSelector{
+				EnvironmentSelector: &refSelector{
+					WhitelistedRefs: []string{"production-*"},
+				},
+				RefSelector: &refSelector{
+					WhitelistedRefs: []string{"/^v[0-9]+/"},
+				},
+			},
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "deployment.environments",
+				Value:    "production-us",
+				Reason:   `"production-us" matches only "production-*"`,
+				Env: map[string]string{
+					"DRAKE_DEPLOYMENT_ID":          "316468027",
+					"DRAKE_DEPLOYMENT_ENVIRONMENT": "production-us",
+				},
+			},
+		},
+		{
+			name: "deployment to unselected environment",
+			selector: deploymentEventSelector{
+				EnvironmentSelector: &refSelector{
+					WhitelistedRefs: []string{"staging"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "deployment.environments",
+				Value:    "production-us",
+				Reason:   `"production-us" matches none of only [staging]`,
+			},
+		},
+		{
+			name: "deployment of unselected ref",
+			selector: deploymentEventSelector{
+				EnvironmentSelector: &refSelector{},
+				RefSelector: &refSelector{
+					WhitelistedRefs: []string{"master"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "deployment.refs",
+				Value:    "v0.6.0",
+				Reason:   `"v0.6.0" matches none of only [master]`,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("deployment"))
+			decision, err := (&trigger{
+				DeploymentEventSelector: &testCase.selector,
+			}).Matches(
+				brigade.Event{
+					Source:  "brigade.sh/github",
+					Type:    "deployment:created",
+					Payload: testPayload(t, "deployment-created"),
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestDeploymentEventSelectorValidate(t *testing.T) {
+	selector := deploymentEventSelector{}
+	require.Equal(
+		t,
+		[]string{
+			"deployment.environments must be specified; without it, no " +
+				"deployment can match",
+		},
+		selector.validate("deployment"),
+	)
+}
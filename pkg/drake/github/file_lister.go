@@ -0,0 +1,90 @@
+package github
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/pkg/errors"
+)
+
+// maxPullRequestFiles is the maximum number of files that the GitHub API will
+// list for a single pull request.
+const maxPullRequestFiles = 3000
+
+// FileLister lists the files changed by pull requests.
+type FileLister interface {
+	// ListFiles returns the paths of all files changed by the specified pull
+	// request of the specified repository. The repository is identified by its
+	// full name (e.g. owner/name). The boolean return value indicates whether
+	// the list is known to be complete.
+	ListFiles(
+		ctx context.Context,
+		event brigade.Event,
+		repo string,
+		number int,
+	) ([]string, bool, error)
+}
+
+// gitHubFileLister is a FileLister that uses the GitHub API.
+type gitHubFileLister struct {
+	// baseURL, if non-empty, overrides the GitHub API's default base URL
+	baseURL string
+}
+
+// NewGitHubFileLister returns a FileLister that lists files using the GitHub
+// API. See githubapi.NewClient() for how it authenticates.
+func NewGitHubFileLister() FileLister {
+	return &gitHubFileLister{}
+}
+
+func (g *gitHubFileLister) ListFiles(
+	ctx context.Context,
+	event brigade.Event,
+	repo string,
+	number int,
+) ([]string, bool, error) {
+	repoParts := strings.SplitN(repo, "/", 2)
+	if len(repoParts) != 2 {
+		return nil, false, errors.Errorf("%q is not a valid repository name", repo)
+	}
+	client, err := githubapi.NewClient(event, g.baseURL)
+	if err != nil {
+		return nil, false, err
+	}
+	files := []string{}
+	var listed int
+	opts := &github.ListOptions{PerPage: 100}
+	for {
+		commitFiles, resp, err := client.PullRequests.ListFiles(
+			ctx,
+			repoParts[0],
+			repoParts[1],
+			number,
+			opts,
+		)
+		if err != nil {
+			return nil, false, errors.Wrapf(
+				err,
+				"error listing files changed by pull request %d of %s",
+				number,
+				repo,
+			)
+		}
+		listed += len(commitFiles)
+		for _, commitFile := range commitFiles {
+			files = append(files, commitFile.GetFilename())
+			// A renamed file is changed at both its old and new paths
+			if previous := commitFile.GetPreviousFilename(); previous != "" {
+				files = append(files, previous)
+			}
+		}
+		if resp.NextPage == 0 {
+			break
+		}
+		opts.Page = resp.NextPage
+	}
+	return files, listed < maxPullRequestFiles, nil
+}
@@ -0,0 +1,82 @@
+package github
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/stretchr/testify/require"
+)
+
+func TestGitHubFileLister(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/lovethedrake/canard/pulls/42/files":
+				if r.Header.Get("Authorization") == "" {
+					w.WriteHeader(http.StatusUnauthorized)
+					return
+				}
+				if r.URL.Query().Get("page") == "2" {
+					fmt.Fprint(w, `[{"filename":"README.md"}]`)
+					return
+				}
+				w.Header().Set(
+					"Link",
+					fmt.Sprintf(
+						`<%s%s?page=2>; rel="next"`,
+						"http://"+r.Host,
+						r.URL.Path,
+					),
+				)
+				fmt.Fprint(
+					w,
+					`[{"filename":"main.go"},`+
+						`{"filename":"new.go","previous_filename":"old.go"}]`,
+				)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer server.Close()
+	lister := &gitHubFileLister{baseURL: server.URL + "/"}
+	event := brigade.Event{
+		Project: brigade.Project{
+			Secrets: map[string]string{githubapi.TokenSecret: "foo"},
+		},
+	}
+
+	files, complete, err := lister.ListFiles(
+		context.Background(),
+		event,
+		"lovethedrake/canard",
+		42,
+	)
+	require.NoError(t, err)
+	require.True(t, complete)
+	require.Equal(t, []string{"main.go", "new.go", "old.go", "README.md"}, files)
+
+	_, _, err = lister.ListFiles(
+		context.Background(),
+		brigade.Event{},
+		"lovethedrake/canard",
+		42,
+	)
+	require.Error(t, err)
+
+	_, _, err = lister.ListFiles(
+		context.Background(),
+		event,
+		"lovethedrake/canard",
+		43,
+	)
+	require.Error(t, err)
+
+	_, _, err = lister.ListFiles(context.Background(), event, "canard", 42)
+	require.Error(t, err)
+}
@@ -0,0 +1,333 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/pkg/errors"
+)
+
+const (
+	// defaultCommandPrefix is the prefix of commands in comments if none is
+	// specified.
+	defaultCommandPrefix = "/drake"
+	// commandEnvVar is the name of the environment variable through which a
+	// command is exposed to jobs.
+	commandEnvVar = "DRAKE_COMMAND"
+	// commandAuthorEnvVar is the name of the environment variable through which
+	// the login of a command's author is exposed to jobs.
+	commandAuthorEnvVar = "DRAKE_COMMAND_AUTHOR"
+	// commandArgEnvVarPrefix prefixes the names of the environment variables
+	// through which a command's arguments are exposed to jobs.
+	commandArgEnvVarPrefix = "DRAKE_ARG_"
+	// pullRequestEnvVar is the name of the environment variable through which
+	// the number of the pull request a command was issued on is exposed to jobs.
+	pullRequestEnvVar = "DRAKE_PULL_REQUEST"
+	// pullRequestCommitEnvVar is the name of the environment variable through
+	// which the head commit of the pull request a command was issued on is
+	// exposed to jobs.
+	pullRequestCommitEnvVar = "DRAKE_PULL_REQUEST_COMMIT"
+)
+
+var (
+	// defaultCommandAuthorAssociations are the author associations permitted to
+	// issue commands if neither author associations nor users are specified.
+	defaultCommandAuthorAssociations = []string{
+		"OWNER",
+		"MEMBER",
+		"COLLABORATOR",
+	}
+	// commandPlaceholderRegex matches placeholders in command patterns. A
+	// placeholder ending in ... captures all remaining words.
+	commandPlaceholderRegex = regexp.MustCompile(`^<([a-z][a-z0-9_]*)(\.\.\.)?>$`)
+	// commandArgRegex matches the values that placeholders may capture. Since
+	// they're exposed to jobs, they're restricted to characters that are inert
+	// in shells.
+	commandArgRegex = regexp.MustCompile(`^[A-Za-z0-9._/:=@+,-]+$`)
+)
+
+// issueCommentEventSelector selects comments on pull requests that contain a
+// command. A command is a line of a comment that begins with a prefix and is
+// followed by words matching one of a number of patterns, each of which is a
+// sequence of literal words and placeholders. For example, the pattern
+// "run <suite>" matches "/drake run integration".
+type issueCommentEventSelector struct {
+	// Prefix is the word that begins every command. It is "/drake" by default.
+	Prefix string `json:"prefix,omitempty"`
+	// Commands enumerates the patterns of commands that are selected.
+	Commands []string `json:"commands,omitempty"`
+	// AuthorAssociations and Users enumerate the associations with the
+	// repository and the logins, respectively, of the users who may issue
+	// commands. If neither is specified, owners, members, and collaborators may
+	// issue commands.
+	AuthorAssociations []string `json:"authorAssociations,omitempty"`
+	Users              []string `json:"users,omitempty"`
+
+	commands []commandPattern
+}
+
+// commandPattern is a compiled command pattern.
+type commandPattern struct {
+	raw   string
+	words []commandWord
+}
+
+// commandWord is either a literal word or, if placeholder is non-empty, a
+// placeholder.
+type commandWord struct {
+	literal     string
+	placeholder string
+	variadic    bool
+}
+
+func (i *issueCommentEventSelector) validate(path string) []string {
+	problems := []string{}
+	if len(i.Commands) == 0 {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"%s.commands must be specified; without it, no comment can match",
+				path,
+			),
+		)
+	}
+	if strings.ContainsAny(i.Prefix, " \t\r\n") {
+		problems = append(
+			problems,
+			fmt.Sprintf("%s.prefix must not contain whitespace", path),
+		)
+	}
+	for j, association := range i.AuthorAssociations {
+		if _, ok := authorAssociations[association]; !ok {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.authorAssociations[%d]: %q is not a recognized author "+
+						"association",
+					path,
+					j,
+					association,
+				),
+			)
+		}
+	}
+	return problems
+}
+
+// compile compiles all of the selector's command patterns. It returns a
+// description, prefixed with the provided path, of every pattern that couldn't
+// be compiled.
+func (i *issueCommentEventSelector) compile(path string) []string {
+	problems := []string{}
+	i.commands = make([]commandPattern, 0, len(i.Commands))
+	for j, raw := range i.Commands {
+		command, err := compileCommandPattern(raw)
+		if err != nil {
+			problems = append(
+				problems,
+				fmt.Sprintf("%s.commands[%d]: %s", path, j, err),
+			)
+			continue
+		}
+		i.commands = append(i.commands, command)
+	}
+	return problems
+}
+
+func compileCommandPattern(raw string) (commandPattern, error) {
+	fields := strings.Fields(raw)
+	if len(fields) == 0 {
+		return commandPattern{}, errors.New("must not be empty")
+	}
+	command := commandPattern{
+		raw:   strings.Join(fields, " "),
+		words: make([]commandWord, len(fields)),
+	}
+	for i, field := range fields {
+		if !strings.HasPrefix(field, "<") {
+			command.words[i] = commandWord{literal: field}
+			continue
+		}
+		submatches := commandPlaceholderRegex.FindStringSubmatch(field)
+		if submatches == nil {
+			return commandPattern{}, errors.Errorf(
+				"%q is not a valid placeholder; placeholders must be lower case, "+
+					"e.g. <suite> or <args...>",
+				field,
+			)
+		}
+		command.words[i] = commandWord{
+			placeholder: submatches[1],
+			variadic:    submatches[2] != "",
+		}
+		if command.words[i].variadic && i != len(fields)-1 {
+			return commandPattern{}, errors.Errorf(
+				"%q must be the last word of the command",
+				field,
+			)
+		}
+	}
+	return command, nil
+}
+
+// parse attempts to parse the provided words using the pattern. If they match,
+// the values captured by each placeholder are returned, indexed by placeholder.
+// Otherwise, a reason is returned.
+func (c commandPattern) parse(words []string) (map[string]string, string) {
+	args := map[string]string{}
+	for i, word := range c.words {
+		if word.variadic {
+			if i >= len(words) {
+				return nil, fmt.Sprintf(
+					"<%s...> requires at least one word",
+					word.placeholder,
+				)
+			}
+			for _, arg := range words[i:] {
+				if !commandArgRegex.MatchString(arg) {
+					return nil, disallowedArgReason(arg)
+				}
+			}
+			args[word.placeholder] = strings.Join(words[i:], " ")
+			return args, ""
+		}
+		if i >= len(words) {
+			return nil, "too few words"
+		}
+		if word.placeholder == "" {
+			if words[i] != word.literal {
+				return nil, fmt.Sprintf("%q is not %q", words[i], word.literal)
+			}
+			continue
+		}
+		if !commandArgRegex.MatchString(words[i]) {
+			return nil, disallowedArgReason(words[i])
+		}
+		args[word.placeholder] = words[i]
+	}
+	if len(words) > len(c.words) {
+		return nil, "too many words"
+	}
+	return args, ""
+}
+
+func disallowedArgReason(arg string) string {
+	return fmt.Sprintf("argument %q contains disallowed characters", arg)
+}
+
+// matches returns whether the comment the provided event pertains to contains
+// a selected command. Since comments don't describe the heads of the pull
+// requests they're on, the provided head (see githubapi.HeadOf()) is used.
+func (i *issueCommentEventSelector) matches(
+	event brigade.Event,
+	head githubapi.Head,
+) (drake.Decision, error) {
+	ice := github.IssueCommentEvent{}
+	if err := json.Unmarshal([]byte(event.Payload), &ice); err != nil {
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	// Unlike GetPullRequestLinks(), IsPullRequest() panics if there's no issue
+	number := ice.GetIssue().GetNumber()
+	if ice.GetIssue().GetPullRequestLinks() == nil {
+		return drake.NotMatched(
+			"issueComment",
+			fmt.Sprintf("#%d", number),
+			"comment is not on a pull request",
+		), nil
+	}
+	if head.SHA == "" {
+		return drake.NotMatched(
+			"issueComment",
+			fmt.Sprintf("#%d", number),
+			"head commit of pull request #%d could not be determined",
+			number,
+		), nil
+	}
+	author := ice.GetComment().GetUser().GetLogin()
+	association := ice.GetComment().GetAuthorAssociation()
+	if !i.authorized(author, association) {
+		return drake.NotMatched(
+			"issueComment.authorAssociations",
+			author,
+			"commenter %q (%s) is not authorized to issue commands",
+			author,
+			association,
+		), nil
+	}
+	prefix := i.Prefix
+	if prefix == "" {
+		prefix = defaultCommandPrefix
+	}
+	reasons := []string{}
+	for _, line := range strings.Split(ice.GetComment().GetBody(), "\n") {
+		words := strings.Fields(line)
+		if len(words) == 0 || words[0] != prefix {
+			continue
+		}
+		words = words[1:]
+		for _, command := range i.commands {
+			args, reason := command.parse(words)
+			if args == nil {
+				reasons = append(
+					reasons,
+					fmt.Sprintf("%q: %s", command.raw, reason),
+				)
+				continue
+			}
+			decision := drake.Matched(
+				"issueComment.commands",
+				strings.Join(words, " "),
+				"comment by %q matches command %q",
+				author,
+				command.raw,
+			)
+			decision.Env = map[string]string{
+				commandEnvVar:           strings.Join(words, " "),
+				commandAuthorEnvVar:     author,
+				pullRequestEnvVar:       strconv.Itoa(number),
+				pullRequestCommitEnvVar: head.SHA,
+			}
+			for placeholder, arg := range args {
+				envVar := commandArgEnvVarPrefix + strings.ToUpper(placeholder)
+				decision.Env[envVar] = arg
+			}
+			return decision, nil
+		}
+	}
+	if len(reasons) == 0 {
+		return drake.NotMatched(
+			"issueComment.commands",
+			"",
+			"comment contains no %s commands",
+			prefix,
+		), nil
+	}
+	return drake.NotMatched(
+		"issueComment.commands",
+		"",
+		"comment contains no matching commands: %s",
+		strings.Join(reasons, "; "),
+	), nil
+}
+
+// authorized returns whether the provided user, having the provided
+// association with the repository, may issue commands.
+func (i *issueCommentEventSelector) authorized(
+	login string,
+	association string,
+) bool {
+	associations := i.AuthorAssociations
+	if len(associations) == 0 && len(i.Users) == 0 {
+		associations = defaultCommandAuthorAssociations
+	}
+	return containsString(associations, association) ||
+		containsString(i.Users, login)
+}
@@ -0,0 +1,369 @@
+package github
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/githubapi"
+	"github.com/stretchr/testify/require"
+)
+
+func testIssueCommentPayload(
+	pullRequest bool,
+	login string,
+	association string,
+	body string,
+) string {
+	pullRequestJSON := "null"
+	if pullRequest {
+		pullRequestJSON = `{"url": "https://api.github.com/repos/lovethedrake/canard/pulls/42"}` // nolint: lll
+	}
+	return fmt.Sprintf(
+		`{
+	"action": "created",
+	"issue": {"number": 42, "pull_request": %s},
+	"comment": {"user": {"login": %q}, "author_association": %q, "body": %q},
+	"repository": {"full_name": "lovethedrake/canard"}
+}`,
+		pullRequestJSON,
+		login,
+		association,
+		body,
+	)
+}
+
+func TestIssueCommentEventSelectorMatches(t *testing.T) {
+	// Commands on pull requests from forks are selected, but the head isn't
+	// trusted when resolving Drakefiles
+	forkHead := githubapi.Head{
+		SHA:          "bad666",
+		Fork:         "mallory/canard",
+		BaseRepo:     "lovethedrake/canard",
+		BaseRevision: "abc123",
+	}
+	testCases := []struct {
+		name     string
+		selector issueCommentEventSelector
+		payload  string
+		// head, if non-nil, overrides forkHead
+		head     *githubapi.Head
+		decision drake.Decision
+	}{
+		{
+			name: "comment on an issue",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(
+				false,
+				"krancour",
+				"OWNER",
+				"/drake retest",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment",
+				Value:    "#42",
+				Reason:   "comment is not on a pull request",
+			},
+		},
+		{
+			name: "comment without an issue",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: `{"comment":{"body":"/drake retest"}}`,
+			decision: drake.Decision{
+				Selector: "issueComment",
+				Value:    "#0",
+				Reason:   "comment is not on a pull request",
+			},
+		},
+		{
+			name: "comment on a pull request whose head is unknown",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"OWNER",
+				"/drake retest",
+			),
+			head: &githubapi.Head{BaseRepo: "lovethedrake/canard"},
+			decision: drake.Decision{
+				Selector: "issueComment",
+				Value:    "#42",
+				Reason:   "head commit of pull request #42 could not be determined",
+			},
+		},
+		{
+			name: "comment by a contributor with default authorization",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"octocat",
+				"CONTRIBUTOR",
+				"/drake retest",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment.authorAssociations",
+				Value:    "octocat",
+				Reason: `commenter "octocat" (CONTRIBUTOR) is not authorized to issue ` +
+					`commands`,
+			},
+		},
+		{
+			name: "comment by a member with default authorization",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"MEMBER",
+				"/drake retest",
+			),
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "issueComment.commands",
+				Value:    "retest",
+				Reason:   `comment by "krancour" matches command "retest"`,
+				Env: map[string]string{
+					"DRAKE_COMMAND":             "retest",
+					"DRAKE_COMMAND_AUTHOR":      "krancour",
+					"DRAKE_PULL_REQUEST":        "42",
+					"DRAKE_PULL_REQUEST_COMMIT": "bad666",
+				},
+			},
+		},
+		{
+			name: "comment by a member who is not allowed",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+				Users:    []string{"octocat"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"MEMBER",
+				"/drake retest",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment.authorAssociations",
+				Value:    "krancour",
+				Reason: `commenter "krancour" (MEMBER) is not authorized to issue ` +
+					`commands`,
+			},
+		},
+		{
+			name: "comment by an allowed user",
+			selector: issueCommentEventSelector{
+				Commands:           []string{"run <suite>"},
+				AuthorAssociations: []string{"OWNER"},
+				Users:              []string{"octocat"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"octocat",
+				"CONTRIBUTOR",
+				"Looks good!\n\n/drake   run integration\n",
+			),
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "issueComment.commands",
+				Value:    "run integration",
+				Reason:   `comment by "octocat" matches command "run <suite>"`,
+				Env: map[string]string{
+					"DRAKE_COMMAND":             "run integration",
+					"DRAKE_COMMAND_AUTHOR":      "octocat",
+					"DRAKE_PULL_REQUEST":        "42",
+					"DRAKE_PULL_REQUEST_COMMIT": "bad666",
+					"DRAKE_ARG_SUITE":           "integration",
+				},
+			},
+		},
+		{
+			name: "comment with variadic arguments",
+			selector: issueCommentEventSelector{
+				Commands: []string{"deploy <env> <flags...>"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"OWNER",
+				"/drake deploy staging --dry-run --region=us-east-1",
+			),
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "issueComment.commands",
+				Value:    "deploy staging --dry-run --region=us-east-1",
+				Reason:   `comment by "krancour" matches command "deploy <env> <flags...>"`, // nolint: lll
+				Env: map[string]string{
+					"DRAKE_COMMAND":             "deploy staging --dry-run --region=us-east-1",
+					"DRAKE_COMMAND_AUTHOR":      "krancour",
+					"DRAKE_PULL_REQUEST":        "42",
+					"DRAKE_PULL_REQUEST_COMMIT": "bad666",
+					"DRAKE_ARG_ENV":             "staging",
+					"DRAKE_ARG_FLAGS":           "--dry-run --region=us-east-1",
+				},
+			},
+		},
+		{
+			name: "comment with disallowed characters in an argument",
+			selector: issueCommentEventSelector{
+				Commands: []string{"run <suite>"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"OWNER",
+				"/drake run $(whoami)",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment.commands",
+				Reason: `comment contains no matching commands: "run <suite>": ` +
+					`argument "$(whoami)" contains disallowed characters`,
+			},
+		},
+		{
+			name: "comment with no matching commands",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest", "run <suite>", "lint <args...>"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"OWNER",
+				"/drake run\n/drake lint",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment.commands",
+				Reason: `comment contains no matching commands: ` +
+					`"retest": "run" is not "retest"; "run <suite>": too few words; ` +
+					`"lint <args...>": "run" is not "lint"; ` +
+					`"retest": "lint" is not "retest"; "run <suite>": "lint" is not ` +
+					`"run"; "lint <args...>": <args...> requires at least one word`,
+			},
+		},
+		{
+			name: "comment with too many words",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"OWNER",
+				"/drake retest please",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment.commands",
+				Reason: `comment contains no matching commands: "retest": too many ` +
+					`words`,
+			},
+		},
+		{
+			name: "comment with no commands",
+			selector: issueCommentEventSelector{
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(
+				true,
+				"krancour",
+				"OWNER",
+				"Please run /drake retest",
+			),
+			decision: drake.Decision{
+				Selector: "issueComment.commands",
+				Reason:   "comment contains no /drake commands",
+			},
+		},
+		{
+			name: "comment with custom prefix",
+			selector: issueCommentEventSelector{
+				Prefix:   "/ci",
+				Commands: []string{"retest"},
+			},
+			payload: testIssueCommentPayload(true, "krancour", "OWNER", "/ci retest"),
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "issueComment.commands",
+				Value:    "retest",
+				Reason:   `comment by "krancour" matches command "retest"`,
+				Env: map[string]string{
+					"DRAKE_COMMAND":             "retest",
+					"DRAKE_COMMAND_AUTHOR":      "krancour",
+					"DRAKE_PULL_REQUEST":        "42",
+					"DRAKE_PULL_REQUEST_COMMIT": "bad666",
+				},
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("issueComment"))
+			head := forkHead
+			if testCase.head != nil {
+				head = *testCase.head
+			}
+			decision, err := (&trigger{
+				IssueCommentEventSelector: &testCase.selector,
+				head:                      head,
+			}).Matches(
+				brigade.Event{
+					Source:  "brigade.sh/github",
+					Type:    "issue_comment:created",
+					Payload: testCase.payload,
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestIssueCommentEventSelectorCompile(t *testing.T) {
+	selector := issueCommentEventSelector{
+		Commands: []string{
+			"retest",
+			" ",
+			"run <Suite>",
+			"run <args...> now",
+			"run <suite> <args...>",
+		},
+	}
+	require.Equal(
+		t,
+		[]string{
+			"issueComment.commands[1]: must not be empty",
+			`issueComment.commands[2]: "<Suite>" is not a valid placeholder; ` +
+				"placeholders must be lower case, e.g. <suite> or <args...>",
+			`issueComment.commands[3]: "<args...>" must be the last word of the ` +
+				"command",
+		},
+		selector.compile("issueComment"),
+	)
+	require.Len(t, selector.commands, 2)
+}
+
+func TestIssueCommentEventSelectorValidate(t *testing.T) {
+	selector := issueCommentEventSelector{
+		Prefix:             "/drake run",
+		AuthorAssociations: []string{"OWNER", "ADMIN"},
+	}
+	require.Equal(
+		t,
+		[]string{
+			"issueComment.commands must be specified; without it, no comment " +
+				"can match",
+			"issueComment.prefix must not contain whitespace",
+			`issueComment.authorAssociations[1]: "ADMIN" is not a recognized ` +
+				"author association",
+		},
+		selector.validate("issueComment"),
+	)
+}
@@ -0,0 +1,86 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// labelSelector selects sets of labels. A set of labels is selected if it
+// contains at least one of the Any labels (if there are any), all of the All
+// labels, and none of the None labels. Labels are compared exactly.
+type labelSelector struct {
+	Any  []string `json:"any,omitempty"`
+	All  []string `json:"all,omitempty"`
+	None []string `json:"none,omitempty"`
+}
+
+func (l *labelSelector) validate(path string) []string {
+	problems := []string{}
+	if len(l.Any) == 0 && len(l.All) == 0 && len(l.None) == 0 {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"at least one of %s.any, %s.all, or %s.none must be specified",
+				path,
+				path,
+				path,
+			),
+		)
+	}
+	for _, list := range []struct {
+		field  string
+		labels []string
+	}{
+		{field: "any", labels: l.Any},
+		{field: "all", labels: l.All},
+		{field: "none", labels: l.None},
+	} {
+		for i, label := range list.labels {
+			if label == "" {
+				problems = append(
+					problems,
+					fmt.Sprintf("%s.%s[%d] must not be empty", path, list.field, i),
+				)
+			}
+		}
+	}
+	return problems
+}
+
+// matches returns whether the provided set of labels is selected along with a
+// reason.
+func (l *labelSelector) matches(labels []string) (bool, string) {
+	has := map[string]bool{}
+	for _, label := range labels {
+		has[label] = true
+	}
+	if len(l.Any) > 0 {
+		found := false
+		for _, label := range l.Any {
+			if has[label] {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false, fmt.Sprintf(
+				"none of labels [%s] are present",
+				strings.Join(l.Any, ", "),
+			)
+		}
+	}
+	for _, label := range l.All {
+		if !has[label] {
+			return false, fmt.Sprintf("required label %q is not present", label)
+		}
+	}
+	for _, label := range l.None {
+		if has[label] {
+			return false, fmt.Sprintf("excluded label %q is present", label)
+		}
+	}
+	return true, fmt.Sprintf(
+		"labels [%s] are acceptable",
+		strings.Join(labels, ", "),
+	)
+}
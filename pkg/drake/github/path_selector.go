@@ -0,0 +1,71 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+)
+
+// pathSelector selects sets of changed files using two lists of patterns
+// having the same syntax as a refSelector's. A file is relevant if it is
+// included by the only list and is not excluded by the ignore list. A set of
+// changed files is selected if any one of them is relevant.
+type pathSelector struct {
+	OnlyPaths   []string `json:"only,omitempty"`
+	IgnorePaths []string `json:"ignore,omitempty"`
+
+	only   []refPattern
+	ignore []refPattern
+}
+
+func (p *pathSelector) validate(path string) []string {
+	if len(p.OnlyPaths) == 0 && len(p.IgnorePaths) == 0 {
+		return []string{
+			fmt.Sprintf(
+				"at least one of %s.only or %s.ignore must be specified",
+				path,
+				path,
+			),
+		}
+	}
+	return nil
+}
+
+// compile compiles all of the selector's patterns. It returns a description,
+// prefixed with the provided path, of every pattern that couldn't be compiled.
+func (p *pathSelector) compile(path string) []string {
+	problems := []string{}
+	var listProblems []string
+	p.only, listProblems = compileRefPatterns(path+".only", p.OnlyPaths)
+	problems = append(problems, listProblems...)
+	p.ignore, listProblems = compileRefPatterns(path+".ignore", p.IgnorePaths)
+	return append(problems, listProblems...)
+}
+
+// matches returns whether the provided set of changed files is selected along
+// with a reason. If complete is false, the set of changed files is known to be
+// incomplete, so it cannot be ruled out that a relevant file was changed and
+// the set is selected. The selector must have been compiled.
+func (p *pathSelector) matches(files []string, complete bool) (bool, string) {
+	if !complete {
+		return true, "the complete list of changed files is unavailable"
+	}
+	for _, file := range files {
+		if included, _ := lastMatch(
+			file,
+			p.only,
+			!hasNonNegated(p.only),
+		); !included {
+			continue
+		}
+		if ignored, _ := lastMatch(file, p.ignore, false); ignored {
+			continue
+		}
+		return true, fmt.Sprintf("changed file %q is relevant", file)
+	}
+	return false, fmt.Sprintf(
+		"none of %d changed file(s) are relevant to only [%s] and ignore [%s]",
+		len(files),
+		strings.Join(p.OnlyPaths, ", "),
+		strings.Join(p.IgnorePaths, ", "),
+	)
+}
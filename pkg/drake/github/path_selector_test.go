@@ -0,0 +1,93 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestPathSelectorMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector pathSelector
+		files    []string
+		complete bool
+		matched  bool
+	}{
+		{
+			name: "relevant file",
+			selector: pathSelector{
+				OnlyPaths: []string{"services/foo/**"},
+			},
+			files:    []string{"README.md", "services/foo/main.go"},
+			complete: true,
+			matched:  true,
+		},
+		{
+			name: "no relevant files",
+			selector: pathSelector{
+				OnlyPaths: []string{"services/foo/**"},
+			},
+			files:    []string{"README.md", "services/foobar/main.go"},
+			complete: true,
+		},
+		{
+			name: "no files",
+			selector: pathSelector{
+				OnlyPaths: []string{"**"},
+			},
+			files:    []string{},
+			complete: true,
+		},
+		{
+			name: "all files ignored",
+			selector: pathSelector{
+				IgnorePaths: []string{"**/*.md", "docs/**"},
+			},
+			files:    []string{"README.md", "docs/index.html", "services/x/y.md"},
+			complete: true,
+		},
+		{
+			name: "some files not ignored",
+			selector: pathSelector{
+				IgnorePaths: []string{"**/*.md", "docs/**"},
+			},
+			files:    []string{"README.md", "main.go"},
+			complete: true,
+			matched:  true,
+		},
+		{
+			name: "only and ignore",
+			selector: pathSelector{
+				OnlyPaths:   []string{"services/foo/**"},
+				IgnorePaths: []string{"**/*_test.go"},
+			},
+			files:    []string{"services/foo/main_test.go", "services/bar/main.go"},
+			complete: true,
+		},
+		{
+			name: "negation and regex",
+			selector: pathSelector{
+				OnlyPaths: []string{"/\\.go$/", "!vendor/**"},
+			},
+			files:    []string{"vendor/github.com/foo/foo.go", "Makefile"},
+			complete: true,
+		},
+		{
+			name: "incomplete list of files",
+			selector: pathSelector{
+				OnlyPaths: []string{"services/foo/**"},
+			},
+			files:   []string{"README.md"},
+			matched: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("paths"))
+			matched, reason :=
+				testCase.selector.matches(testCase.files, testCase.complete)
+			require.Equal(t, testCase.matched, matched, reason)
+		})
+	}
+}
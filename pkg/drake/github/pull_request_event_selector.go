@@ -1,38 +1,356 @@
 package github
 
 import (
+	"context"
 	"encoding/json"
-	"log"
+	"fmt"
+	"strings"
 
 	"github.com/google/go-github/v33/github"
 	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/githubapi"
 	"github.com/pkg/errors"
 )
 
+// mergedAction is a pseudo-action that selects pull requests that were closed
+// by being merged.
+const mergedAction = "merged"
+
+// defaultPullRequestActions are the pull request actions that are selected if
+// none are specified.
+var defaultPullRequestActions = []string{"opened", "synchronize", "reopened"}
+
+// pullRequestActions enumerates the pull request actions that may be
+// selected.
+var pullRequestActions = map[string]struct{}{
+	"assigned":               {},
+	"auto_merge_disabled":    {},
+	"auto_merge_enabled":     {},
+	"closed":                 {},
+	"converted_to_draft":     {},
+	"edited":                 {},
+	"labeled":                {},
+	"locked":                 {},
+	mergedAction:             {},
+	"opened":                 {},
+	"ready_for_review":       {},
+	"reopened":               {},
+	"review_request_removed": {},
+	"review_requested":       {},
+	"synchronize":            {},
+	"unassigned":             {},
+	"unlabeled":              {},
+	"unlocked":               {},
+}
+
+// authorAssociations enumerates the possible associations of a pull request's
+// author with the repository.
+var authorAssociations = map[string]struct{}{
+	"COLLABORATOR":           {},
+	"CONTRIBUTOR":            {},
+	"FIRST_TIMER":            {},
+	"FIRST_TIME_CONTRIBUTOR": {},
+	"MANNEQUIN":              {},
+	"MEMBER":                 {},
+	"NONE":                   {},
+	"OWNER":                  {},
+}
+
+// nolint: lll
 type pullRequestEventSelector struct {
+	// Actions enumerates the pull request actions that are selected. The
+	// pseudo-action "merged" selects pull requests that were closed by being
+	// merged. If empty, defaultPullRequestActions are selected.
+	Actions              []string     `json:"actions,omitempty"`
 	TargetBranchSelector *refSelector `json:"targetBranches,omitempty"`
+	HeadBranchSelector   *refSelector `json:"headBranches,omitempty"`
+	// Draft, if specified, selects only draft pull requests if true and only
+	// pull requests that are ready for review if false.
+	Draft *bool `json:"draft,omitempty"`
+	// Fork, if specified, selects only pull requests originating from forks if
+	// true and only pull requests originating from the base repository if
+	// false. See githubapi.IsFork().
+	Fork *bool `json:"fork,omitempty"`
+	// AuthorAssociations, if non-empty, enumerates the associations with the
+	// repository (e.g. MEMBER or OWNER) that a pull request's author must have
+	// one of.
+	AuthorAssociations []string       `json:"authorAssociations,omitempty"`
+	LabelSelector      *labelSelector `json:"labels,omitempty"`
+	// TitleSelector, if specified, selects pull requests by their titles.
+	TitleSelector *regexSelector `json:"titles,omitempty"`
+	// AuthorSelector, if specified, selects pull requests by their authors'
+	// logins.
+	AuthorSelector *regexSelector `json:"authors,omitempty"`
+	PathSelector   *pathSelector  `json:"paths,omitempty"`
+}
+
+func (p *pullRequestEventSelector) validate(path string) []string {
+	problems := []string{}
+	for i, action := range p.Actions {
+		if _, ok := pullRequestActions[action]; !ok {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.actions[%d]: %q is not a recognized pull request action",
+					path,
+					i,
+					action,
+				),
+			)
+		}
+	}
+	if p.TargetBranchSelector == nil {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"%s.targetBranches must be specified; without it, no pull request "+
+					"can match",
+				path,
+			),
+		)
+	}
+	for i, association := range p.AuthorAssociations {
+		if _, ok := authorAssociations[association]; !ok {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.authorAssociations[%d]: %q is not a recognized author "+
+						"association",
+					path,
+					i,
+					association,
+				),
+			)
+		}
+	}
+	if p.LabelSelector != nil {
+		problems = append(problems, p.LabelSelector.validate(path+".labels")...)
+	}
+	if p.TitleSelector != nil {
+		problems = append(problems, p.TitleSelector.validate(path+".titles")...)
+	}
+	if p.AuthorSelector != nil {
+		problems = append(problems, p.AuthorSelector.validate(path+".authors")...)
+	}
+	if p.PathSelector != nil {
+		problems = append(problems, p.PathSelector.validate(path+".paths")...)
+	}
+	return problems
+}
+
+func (p *pullRequestEventSelector) compile(path string) []string {
+	problems := []string{}
+	if p.TargetBranchSelector != nil {
+		problems = append(
+			problems,
+			p.TargetBranchSelector.compile(path+".targetBranches")...,
+		)
+	}
+	if p.HeadBranchSelector != nil {
+		problems = append(
+			problems,
+			p.HeadBranchSelector.compile(path+".headBranches")...,
+		)
+	}
+	if p.TitleSelector != nil {
+		problems = append(problems, p.TitleSelector.compile(path+".titles")...)
+	}
+	if p.AuthorSelector != nil {
+		problems = append(problems, p.AuthorSelector.compile(path+".authors")...)
+	}
+	if p.PathSelector != nil {
+		problems = append(problems, p.PathSelector.compile(path+".paths")...)
+	}
+	return problems
 }
 
 func (p *pullRequestEventSelector) matches(
 	event brigade.Event,
-) (bool, error) {
+	fileLister FileLister,
+) (drake.Decision, error) {
+	const selector = "pullRequest.targetBranches"
 	if p.TargetBranchSelector == nil {
-		log.Printf(
-			"check suite request event does not match nil target branch selector",
-		)
-		return false, nil
+		return drake.NotMatched(
+			selector,
+			"",
+			"no target branch selector is configured",
+		), nil
 	}
 	pre := github.PullRequestEvent{}
 	if err := json.Unmarshal([]byte(event.Payload), &pre); err != nil {
-		return false, errors.Wrap(err, "error unmarshaling event payload")
-	}
-	branch := *pre.PullRequest.Base.Ref
-	match, err := p.TargetBranchSelector.matches(branch)
-	if err != nil {
-		return false, errors.Wrap(
-			err,
-			"error matching branch %q to target branch selector",
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	action := strings.TrimPrefix(event.Type, "pull_request:")
+	if match, reason := p.matchesAction(action, pre); !match {
+		return drake.NotMatched("pullRequest.actions", action, "%s", reason), nil
+	}
+	pr := pre.GetPullRequest()
+	branch := pr.GetBase().GetRef()
+	match, reason := p.TargetBranchSelector.matches(branch)
+	decision := drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    branch,
+		Reason:   reason,
+	}
+	if !match {
+		return decision, nil
+	}
+	if skipCIRegex.MatchString(pr.GetTitle()) {
+		return drake.NotMatched(
+			"pullRequest.titles",
+			pr.GetTitle(),
+			"title contains %s",
+			skipCIRegex.FindString(pr.GetTitle()),
+		), nil
+	}
+	if p.HeadBranchSelector != nil {
+		headBranch := pr.GetHead().GetRef()
+		if match, reason := p.HeadBranchSelector.matches(headBranch); !match {
+			return drake.NotMatched(
+				"pullRequest.headBranches",
+				headBranch,
+				"%s",
+				reason,
+			), nil
+		}
+	}
+	if p.Draft != nil && *p.Draft != pr.GetDraft() {
+		return drake.NotMatched(
+			"pullRequest.draft",
+			fmt.Sprintf("%t", pr.GetDraft()),
+			"draft status is %t; %t is required",
+			pr.GetDraft(),
+			*p.Draft,
+		), nil
+	}
+	if p.Fork != nil {
+		fork := githubapi.IsFork(pr)
+		if *p.Fork != fork {
+			return drake.NotMatched(
+				"pullRequest.fork",
+				pr.GetHead().GetRepo().GetFullName(),
+				"pull request is from a fork: %t; %t is required",
+				fork,
+				*p.Fork,
+			), nil
+		}
+	}
+	if len(p.AuthorAssociations) > 0 {
+		association := pr.GetAuthorAssociation()
+		if !containsString(p.AuthorAssociations, association) {
+			return drake.NotMatched(
+				"pullRequest.authorAssociations",
+				association,
+				"author association %q is not among [%s]",
+				association,
+				strings.Join(p.AuthorAssociations, ", "),
+			), nil
+		}
+	}
+	if p.LabelSelector != nil {
+		labels := make([]string, len(pr.Labels))
+		for i, label := range pr.Labels {
+			labels[i] = label.GetName()
+		}
+		if match, reason := p.LabelSelector.matches(labels); !match {
+			return drake.NotMatched(
+				"pullRequest.labels",
+				strings.Join(labels, ","),
+				"%s",
+				reason,
+			), nil
+		}
+	}
+	if p.TitleSelector != nil {
+		if match, reason := p.TitleSelector.matches(pr.GetTitle()); !match {
+			return drake.NotMatched(
+				"pullRequest.titles",
+				pr.GetTitle(),
+				"%s",
+				reason,
+			), nil
+		}
+	}
+	if p.AuthorSelector != nil {
+		author := pr.GetUser().GetLogin()
+		if match, reason := p.AuthorSelector.matches(author); !match {
+			return drake.NotMatched(
+				"pullRequest.authors",
+				author,
+				"%s",
+				reason,
+			), nil
+		}
+	}
+	// Changed files are considered last because listing them is costly
+	if p.PathSelector != nil {
+		repo := pre.GetRepo().GetFullName()
+		files, complete, err := fileLister.ListFiles(
+			context.Background(),
+			event,
+			repo,
+			pre.GetNumber(),
+		)
+		if err != nil {
+			return drake.Decision{}, err
+		}
+		match, reason := p.PathSelector.matches(files, complete)
+		if !match {
+			return drake.NotMatched(
+				"pullRequest.paths",
+				fmt.Sprintf("%s#%d", repo, pre.GetNumber()),
+				"%s",
+				reason,
+			), nil
+		}
+	}
+	return decision, nil
+}
+
+// matchesAction returns whether the provided action is selected along with a
+// reason if it isn't.
+func (p *pullRequestEventSelector) matchesAction(
+	action string,
+	pre github.PullRequestEvent,
+) (bool, string) {
+	actions := p.Actions
+	if len(actions) == 0 {
+		actions = defaultPullRequestActions
+	}
+	for _, selected := range actions {
+		if selected == action {
+			return true, ""
+		}
+		if selected == mergedAction &&
+			action == "closed" &&
+			pre.GetPullRequest().GetMerged() {
+			return true, ""
+		}
+	}
+	reason := fmt.Sprintf(
+		"action %q is not among [%s]",
+		action,
+		strings.Join(actions, ", "),
+	)
+	if action == "closed" {
+		reason = fmt.Sprintf(
+			"action %q (merged: %t) is not among [%s]",
+			action,
+			pre.GetPullRequest().GetMerged(),
+			strings.Join(actions, ", "),
 		)
 	}
-	return match, nil
+	return false, reason
+}
+
+func containsString(strs []string, str string) bool {
+	for _, s := range strs {
+		if s == str {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,368 @@
+package github
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+// nolint: lll
+const testPullRequestPayload = `{
+	"action": "opened",
+	"number": 42,
+	"repository": {"full_name": "lovethedrake/canard"},
+	"pull_request": {
+		"title": "Add foo",
+		"user": {"login": "dependabot[bot]"},
+		"draft": false,
+		"author_association": "CONTRIBUTOR",
+		"labels": [{"name": "ci:full"}, {"name": "docs"}],
+		"base": {"ref": "master", "repo": {"full_name": "lovethedrake/canard"}},
+		"head": {"ref": "feature/foo", "repo": {"full_name": "someone/canard"}}
+	}
+}`
+
+func TestPullRequestEventSelectorMatches(t *testing.T) {
+	truth := true
+	falsity := false
+	testCases := []struct {
+		name       string
+		selector   pullRequestEventSelector
+		fileLister FileLister
+		decision   drake.Decision
+		err        error
+	}{
+		{
+			name: "all criteria satisfied",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				HeadBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"feature/*"},
+				},
+				Draft:              &falsity,
+				Fork:               &truth,
+				AuthorAssociations: []string{"CONTRIBUTOR", "MEMBER"},
+				LabelSelector: &labelSelector{
+					Any:  []string{"ci:full", "ci:all"},
+					All:  []string{"docs"},
+					None: []string{"wip"},
+				},
+			},
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "pullRequest.targetBranches",
+				Value:    "master",
+				Reason:   "no refs are required",
+			},
+		},
+		{
+			name: "head branch not selected",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				HeadBranchSelector: &refSelector{
+					BlacklistedRefs: []string{"feature/**"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.headBranches",
+				Value:    "feature/foo",
+				Reason:   `"feature/foo" matches ignore "feature/**"`,
+			},
+		},
+		{
+			name: "drafts only",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				Draft:                &truth,
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.draft",
+				Value:    "false",
+				Reason:   "draft status is false; true is required",
+			},
+		},
+		{
+			name: "forks excluded",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				Fork:                 &falsity,
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.fork",
+				Value:    "someone/canard",
+				Reason:   "pull request is from a fork: true; false is required",
+			},
+		},
+		{
+			name: "author association not selected",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				AuthorAssociations:   []string{"MEMBER", "OWNER"},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.authorAssociations",
+				Value:    "CONTRIBUTOR",
+				Reason:   `author association "CONTRIBUTOR" is not among [MEMBER, OWNER]`,
+			},
+		},
+		{
+			name: "none of any labels present",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				LabelSelector: &labelSelector{
+					Any: []string{"ci:quick", "ci:lint"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.labels",
+				Value:    "ci:full,docs",
+				Reason:   "none of labels [ci:quick, ci:lint] are present",
+			},
+		},
+		{
+			name: "not all labels present",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				LabelSelector: &labelSelector{
+					All: []string{"ci:full", "approved"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.labels",
+				Value:    "ci:full,docs",
+				Reason:   `required label "approved" is not present`,
+			},
+		},
+		{
+			name: "excluded label present",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				LabelSelector: &labelSelector{
+					None: []string{"docs"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.labels",
+				Value:    "ci:full,docs",
+				Reason:   `excluded label "docs" is present`,
+			},
+		},
+		{
+			name: "target branch is checked first",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"develop"},
+				},
+				Draft: &truth,
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.targetBranches",
+				Value:    "master",
+				Reason:   `"master" matches none of only [develop]`,
+			},
+		},
+		{
+			name: "title not selected",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				TitleSelector: &regexSelector{
+					OnlyRegexes: []string{"^(feat|fix)"},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.titles",
+				Value:    "Add foo",
+				Reason:   `"Add foo" matches none of only [^(feat|fix)]`,
+			},
+		},
+		{
+			name: "author ignored",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				AuthorSelector: &regexSelector{
+					IgnoreRegexes: []string{`\[bot\]$`},
+				},
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.authors",
+				Value:    "dependabot[bot]",
+				Reason:   `"dependabot[bot]" matches ignore "\\[bot\\]$"`,
+			},
+		},
+		{
+			name: "relevant file changed",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				PathSelector: &pathSelector{
+					OnlyPaths: []string{"services/foo/**"},
+				},
+			},
+			fileLister: &fakeFileLister{
+				files:    []string{"README.md", "services/foo/main.go"},
+				complete: true,
+			},
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "pullRequest.targetBranches",
+				Value:    "master",
+				Reason:   "no refs are required",
+			},
+		},
+		{
+			name: "no relevant file changed",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				PathSelector: &pathSelector{
+					OnlyPaths: []string{"services/foo/**"},
+				},
+			},
+			fileLister: &fakeFileLister{
+				files:    []string{"README.md", "services/bar/main.go"},
+				complete: true,
+			},
+			decision: drake.Decision{
+				Selector: "pullRequest.paths",
+				Value:    "lovethedrake/canard#42",
+				Reason: "none of 2 changed file(s) are relevant to only " +
+					"[services/foo/**] and ignore []",
+			},
+		},
+		{
+			name: "error listing files",
+			selector: pullRequestEventSelector{
+				TargetBranchSelector: &refSelector{},
+				PathSelector: &pathSelector{
+					OnlyPaths: []string{"services/foo/**"},
+				},
+			},
+			fileLister: &fakeFileLister{
+				err: errors.New("rate limit exceeded"),
+			},
+			err: errors.New("rate limit exceeded"),
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("pullRequest"))
+			decision, err := testCase.selector.matches(
+				brigade.Event{
+					Source:  "brigade.sh/github",
+					Type:    "pull_request:opened",
+					Payload: testPullRequestPayload,
+				},
+				testCase.fileLister,
+			)
+			require.Equal(t, testCase.err, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestPullRequestEventSelectorSkipCI(t *testing.T) {
+	selector := pullRequestEventSelector{
+		TargetBranchSelector: &refSelector{},
+	}
+	require.Empty(t, selector.compile("pullRequest"))
+	decision, err := selector.matches(
+		brigade.Event{
+			Source: "brigade.sh/github",
+			Type:   "pull_request:opened",
+			Payload: `{"pull_request":{"title":"Update docs [skip ci]",` +
+				`"base":{"ref":"master"}}}`,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		drake.Decision{
+			Selector: "pullRequest.titles",
+			Value:    "Update docs [skip ci]",
+			Reason:   "title contains [skip ci]",
+		},
+		decision,
+	)
+}
+
+func TestPullRequestEventSelectorDeletedFork(t *testing.T) {
+	falsity := false
+	selector := pullRequestEventSelector{
+		TargetBranchSelector: &refSelector{},
+		Fork:                 &falsity,
+	}
+	require.Empty(t, selector.compile("pullRequest"))
+	decision, err := selector.matches(
+		brigade.Event{
+			Source: "brigade.sh/github",
+			Type:   "pull_request:opened",
+			Payload: `{"pull_request":{"head":{"ref":"patch-1","repo":null},` +
+				`"base":{"ref":"master",` +
+				`"repo":{"full_name":"lovethedrake/canard"}}}}`,
+		},
+		nil,
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		drake.Decision{
+			Selector: "pullRequest.fork",
+			Reason:   "pull request is from a fork: true; false is required",
+		},
+		decision,
+	)
+}
+
+func TestPullRequestEventSelectorValidate(t *testing.T) {
+	selector := pullRequestEventSelector{
+		TargetBranchSelector: &refSelector{},
+		AuthorAssociations:   []string{"MEMBER", "member"},
+		LabelSelector: &labelSelector{
+			All: []string{"ci:full", ""},
+		},
+	}
+	require.Equal(
+		t,
+		[]string{
+			`pullRequest.authorAssociations[1]: "member" is not a recognized ` +
+				"author association",
+			"pullRequest.labels.all[1] must not be empty",
+		},
+		selector.validate("pullRequest"),
+	)
+	selector = pullRequestEventSelector{
+		TargetBranchSelector: &refSelector{},
+		LabelSelector:        &labelSelector{},
+	}
+	require.Equal(
+		t,
+		[]string{
+			"at least one of pullRequest.labels.any, pullRequest.labels.all, or " +
+				"pullRequest.labels.none must be specified",
+		},
+		selector.validate("pullRequest"),
+	)
+}
+
+// fakeFileLister is a FileLister that lists a fixed set of files for pull
+// request 42 of lovethedrake/canard.
+type fakeFileLister struct {
+	files    []string
+	complete bool
+	err      error
+}
+
+func (f *fakeFileLister) ListFiles(
+	_ context.Context,
+	_ brigade.Event,
+	repo string,
+	number int,
+) ([]string, bool, error) {
+	if repo != "lovethedrake/canard" || number != 42 {
+		return nil, false, errors.New("no such pull request")
+	}
+	return f.files, f.complete, f.err
+}
@@ -0,0 +1,187 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+const (
+	// reviewCommitEnvVar is the name of the environment variable through which
+	// the SHA of the commit that was reviewed is exposed to jobs.
+	reviewCommitEnvVar = "DRAKE_REVIEW_COMMIT"
+	// reviewerEnvVar is the name of the environment variable through which the
+	// login of a reviewer is exposed to jobs.
+	reviewerEnvVar = "DRAKE_REVIEWER"
+)
+
+// defaultReviewStates are the review states that are selected if none are
+// specified.
+var defaultReviewStates = []string{"approved"}
+
+// reviewStates enumerates the review states that may be selected.
+var reviewStates = map[string]struct{}{
+	"approved":          {},
+	"changes_requested": {},
+	"commented":         {},
+}
+
+// pullRequestReviewEventSelector selects submitted pull request reviews. Since
+// the build checks out the pull request's head, a review is selected only if
+// it is of that commit, whose SHA is also exposed to jobs.
+type pullRequestReviewEventSelector struct {
+	// States enumerates the review states that are selected. If empty,
+	// defaultReviewStates are selected.
+	States               []string     `json:"states,omitempty"`
+	TargetBranchSelector *refSelector `json:"targetBranches,omitempty"`
+	// ReviewerAssociations enumerates the associations with the repository
+	// (e.g. MEMBER or OWNER) that a reviewer must have. If empty,
+	// defaultCommandAuthorAssociations are selected.
+	ReviewerAssociations []string `json:"reviewerAssociations,omitempty"`
+}
+
+func (p *pullRequestReviewEventSelector) validate(path string) []string {
+	problems := []string{}
+	for i, state := range p.States {
+		if _, ok := reviewStates[state]; !ok {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.states[%d]: %q is not a recognized review state",
+					path,
+					i,
+					state,
+				),
+			)
+		}
+	}
+	if p.TargetBranchSelector == nil {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"%s.targetBranches must be specified; without it, no review can "+
+					"match",
+				path,
+			),
+		)
+	}
+	for i, association := range p.ReviewerAssociations {
+		if _, ok := authorAssociations[association]; !ok {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.reviewerAssociations[%d]: %q is not a recognized author "+
+						"association",
+					path,
+					i,
+					association,
+				),
+			)
+		}
+	}
+	return problems
+}
+
+func (p *pullRequestReviewEventSelector) compile(path string) []string {
+	if p.TargetBranchSelector == nil {
+		return nil
+	}
+	return p.TargetBranchSelector.compile(path + ".targetBranches")
+}
+
+func (p *pullRequestReviewEventSelector) matches(
+	event brigade.Event,
+) (drake.Decision, error) {
+	const selector = "pullRequestReview.targetBranches"
+	if p.TargetBranchSelector == nil {
+		return drake.NotMatched(
+			selector,
+			"",
+			"no target branch selector is configured",
+		), nil
+	}
+	prre := github.PullRequestReviewEvent{}
+	if err := json.Unmarshal([]byte(event.Payload), &prre); err != nil {
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	action := strings.TrimPrefix(event.Type, "pull_request_review:")
+	if action != "submitted" {
+		return drake.NotMatched(
+			"pullRequestReview",
+			action,
+			"action %q is not submitted",
+			action,
+		), nil
+	}
+	review := prre.GetReview()
+	// Webhook payloads have lower case states, but the REST API's are upper case
+	state := strings.ToLower(review.GetState())
+	states := p.States
+	if len(states) == 0 {
+		states = defaultReviewStates
+	}
+	if !containsString(states, state) {
+		return drake.NotMatched(
+			"pullRequestReview.states",
+			state,
+			"review state %q is not among [%s]",
+			state,
+			strings.Join(states, ", "),
+		), nil
+	}
+	pr := prre.GetPullRequest()
+	branch := pr.GetBase().GetRef()
+	match, reason := p.TargetBranchSelector.matches(branch)
+	decision := drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    branch,
+		Reason:   reason,
+	}
+	if !match {
+		return decision, nil
+	}
+	if skipCIRegex.MatchString(pr.GetTitle()) {
+		return drake.NotMatched(
+			"pullRequestReview",
+			pr.GetTitle(),
+			"pull request title contains %s",
+			skipCIRegex.FindString(pr.GetTitle()),
+		), nil
+	}
+	associations := p.ReviewerAssociations
+	if len(associations) == 0 {
+		associations = defaultCommandAuthorAssociations
+	}
+	association := review.GetAuthorAssociation()
+	if !containsString(associations, association) {
+		return drake.NotMatched(
+			"pullRequestReview.reviewerAssociations",
+			association,
+			"reviewer association %q is not among [%s]",
+			association,
+			strings.Join(associations, ", "),
+		), nil
+	}
+	// The checkout is of the pull request's head, so a review of any other
+	// commit mustn't trigger a build of commits nobody has reviewed.
+	if review.GetCommitID() != pr.GetHead().GetSHA() {
+		return drake.NotMatched(
+			"pullRequestReview",
+			review.GetCommitID(),
+			"reviewed commit is not the pull request's head commit %s",
+			pr.GetHead().GetSHA(),
+		), nil
+	}
+	decision.Env = map[string]string{
+		reviewCommitEnvVar: review.GetCommitID(),
+		reviewerEnvVar:     review.GetUser().GetLogin(),
+	}
+	return decision, nil
+}
@@ -0,0 +1,218 @@
+package github
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestPullRequestReviewEventSelectorMatches(t *testing.T) {
+	payload := testPayload(t, "pull-request-review-submitted")
+	testCases := []struct {
+		name      string
+		selector  pullRequestReviewEventSelector
+		eventType string
+		payload   string
+		decision  drake.Decision
+	}{
+		{
+			name: "approval for selected target branch",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"master"},
+				},
+				ReviewerAssociations: []string{"OWNER", "MEMBER"},
+			},
+			payload: payload,
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "pullRequestReview.targetBranches",
+				Value:    "master",
+				Reason:   `"master" matches only "master"`,
+				Env: map[string]string{
+					"DRAKE_REVIEW_COMMIT": "9c1f7e2d8a4b6c3e5f0a1b2c3d4e5f6a7b8c9d0e",
+					"DRAKE_REVIEWER":      "krancour",
+				},
+			},
+		},
+		{
+			name: "approval for unselected target branch",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{
+					WhitelistedRefs: []string{"release/*"},
+				},
+			},
+			payload: payload,
+			decision: drake.Decision{
+				Selector: "pullRequestReview.targetBranches",
+				Value:    "master",
+				Reason:   `"master" matches none of only [release/*]`,
+			},
+		},
+		{
+			name: "changes requested are not selected by default",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{},
+			},
+			payload: strings.Replace(
+				payload,
+				`"state": "approved"`,
+				`"state": "changes_requested"`,
+				1,
+			),
+			decision: drake.Decision{
+				Selector: "pullRequestReview.states",
+				Value:    "changes_requested",
+				Reason:   `review state "changes_requested" is not among [approved]`,
+			},
+		},
+		{
+			name: "selected changes requested",
+			selector: pullRequestReviewEventSelector{
+				States:               []string{"changes_requested"},
+				TargetBranchSelector: &refSelector{},
+			},
+			payload: strings.Replace(
+				payload,
+				`"state": "approved"`,
+				`"state": "CHANGES_REQUESTED"`,
+				1,
+			),
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "pullRequestReview.targetBranches",
+				Value:    "master",
+				Reason:   "no refs are required",
+				Env: map[string]string{
+					"DRAKE_REVIEW_COMMIT": "9c1f7e2d8a4b6c3e5f0a1b2c3d4e5f6a7b8c9d0e",
+					"DRAKE_REVIEWER":      "krancour",
+				},
+			},
+		},
+		{
+			name: "approval by reviewer with unselected association",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{},
+				ReviewerAssociations: []string{"OWNER"},
+			},
+			payload: payload,
+			decision: drake.Decision{
+				Selector: "pullRequestReview.reviewerAssociations",
+				Value:    "MEMBER",
+				Reason:   `reviewer association "MEMBER" is not among [OWNER]`,
+			},
+		},
+		{
+			name: "approval by contributor is not selected by default",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{},
+			},
+			payload: strings.Replace(
+				payload,
+				`"author_association": "MEMBER"`,
+				`"author_association": "CONTRIBUTOR"`,
+				1,
+			),
+			decision: drake.Decision{
+				Selector: "pullRequestReview.reviewerAssociations",
+				Value:    "CONTRIBUTOR",
+				Reason: `reviewer association "CONTRIBUTOR" is not among ` +
+					"[OWNER, MEMBER, COLLABORATOR]",
+			},
+		},
+		{
+			name: "approval of commit other than head",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{},
+			},
+			payload: strings.Replace(
+				payload,
+				`"commit_id": "9c1f7e2d8a4b6c3e5f0a1b2c3d4e5f6a7b8c9d0e"`,
+				`"commit_id": "0d9c8b7a6f5e4d3c2b1a0f5e3c6b4a8d2e7f1c9b"`,
+				1,
+			),
+			decision: drake.Decision{
+				Selector: "pullRequestReview",
+				Value:    "0d9c8b7a6f5e4d3c2b1a0f5e3c6b4a8d2e7f1c9b",
+				Reason: "reviewed commit is not the pull request's head commit " +
+					"9c1f7e2d8a4b6c3e5f0a1b2c3d4e5f6a7b8c9d0e",
+			},
+		},
+		{
+			name: "approval of pull request with skip ci directive",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{},
+			},
+			payload: strings.Replace(
+				payload,
+				`"title": "Add foo"`,
+				`"title": "Add foo [skip ci]"`,
+				1,
+			),
+			decision: drake.Decision{
+				Selector: "pullRequestReview",
+				Value:    "Add foo [skip ci]",
+				Reason:   "pull request title contains [skip ci]",
+			},
+		},
+		{
+			name: "edited review",
+			selector: pullRequestReviewEventSelector{
+				TargetBranchSelector: &refSelector{},
+			},
+			eventType: "pull_request_review:edited",
+			payload: strings.Replace(
+				payload,
+				`"action": "submitted"`,
+				`"action": "edited"`,
+				1,
+			),
+			decision: drake.Decision{
+				Selector: "pullRequestReview",
+				Value:    "edited",
+				Reason:   `action "edited" is not submitted`,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("pullRequestReview"))
+			if testCase.eventType == "" {
+				testCase.eventType = "pull_request_review:submitted"
+			}
+			decision, err := (&trigger{
+				PullRequestReviewEventSelector: &testCase.selector,
+			}).Matches(
+				brigade.Event{
+					Source:  "brigade.sh/github",
+					Type:    testCase.eventType,
+					Payload: testCase.payload,
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestPullRequestReviewEventSelectorValidate(t *testing.T) {
+	selector := pullRequestReviewEventSelector{
+		States:               []string{"approved", "dismissed"},
+		ReviewerAssociations: []string{"MAINTAINER"},
+	}
+	require.Equal(
+		t,
+		[]string{
+			`pullRequestReview.states[1]: "dismissed" is not a recognized review ` +
+				"state",
+			"pullRequestReview.targetBranches must be specified; without it, no " +
+				"review can match",
+			`pullRequestReview.reviewerAssociations[0]: "MAINTAINER" is not a ` +
+				"recognized author association",
+		},
+		selector.validate("pullRequestReview"),
+	)
+}
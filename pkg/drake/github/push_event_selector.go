@@ -2,11 +2,12 @@ package github
 
 import (
 	"encoding/json"
-	"log"
+	"fmt"
 	"regexp"
 
 	"github.com/google/go-github/v33/github"
 	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
 	"github.com/pkg/errors"
 )
 
@@ -17,43 +18,233 @@ var (
 
 type pushEventSelector struct {
 	BranchSelector *refSelector `json:"branches,omitempty"`
-	TagSelector    *refSelector `json:"tags,omitempty"`
+	TagSelector    *tagSelector `json:"tags,omitempty"`
+	// HeadCommitSelector, if specified, selects pushes by their head commit.
+	HeadCommitSelector *commitSelector `json:"headCommit,omitempty"`
+	// PathSelector, if specified, selects pushes by the files changed by their
+	// commits.
+	PathSelector *pathSelector `json:"paths,omitempty"`
 }
 
-func (p *pushEventSelector) matches(event brigade.Event) (bool, error) {
+// commitSelector selects commits by their messages and authors. Authors are
+// represented as "Name <email>".
+type commitSelector struct {
+	MessageSelector *regexSelector `json:"messages,omitempty"`
+	AuthorSelector  *regexSelector `json:"authors,omitempty"`
+}
+
+func (c *commitSelector) validate(path string) []string {
+	problems := []string{}
+	if c.MessageSelector == nil && c.AuthorSelector == nil {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"at least one of %s.messages or %s.authors must be specified",
+				path,
+				path,
+			),
+		)
+	}
+	if c.MessageSelector != nil {
+		problems = append(
+			problems,
+			c.MessageSelector.validate(path+".messages")...,
+		)
+	}
+	if c.AuthorSelector != nil {
+		problems = append(problems, c.AuthorSelector.validate(path+".authors")...)
+	}
+	return problems
+}
+
+func (c *commitSelector) compile(path string) []string {
+	problems := []string{}
+	if c.MessageSelector != nil {
+		problems = append(
+			problems,
+			c.MessageSelector.compile(path+".messages")...,
+		)
+	}
+	if c.AuthorSelector != nil {
+		problems = append(problems, c.AuthorSelector.compile(path+".authors")...)
+	}
+	return problems
+}
+
+// matches returns whether the provided commit is selected. If it isn't, the
+// field of the selector that rejected it and a reason are also returned. The
+// selector must have been compiled.
+func (c *commitSelector) matches(
+	commit *github.HeadCommit,
+) (bool, string, string) {
+	if c.MessageSelector != nil {
+		if match, reason :=
+			c.MessageSelector.matches(commit.GetMessage()); !match {
+			return false, "messages", reason
+		}
+	}
+	if c.AuthorSelector != nil {
+		author := fmt.Sprintf(
+			"%s <%s>",
+			commit.GetAuthor().GetName(),
+			commit.GetAuthor().GetEmail(),
+		)
+		if match, reason := c.AuthorSelector.matches(author); !match {
+			return false, "authors", reason
+		}
+	}
+	return true, "", ""
+}
+
+// maxPushPayloadCommits is the maximum number of commits that GitHub includes
+// in the payload of a push event.
+const maxPushPayloadCommits = 2048
+
+func (p *pushEventSelector) validate(path string) []string {
+	problems := []string{}
+	if p.BranchSelector == nil && p.TagSelector == nil {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"at least one of %s.branches or %s.tags must be specified; without "+
+					"either, no push can match",
+				path,
+				path,
+			),
+		)
+	}
+	if p.TagSelector != nil {
+		problems = append(problems, p.TagSelector.validate(path+".tags")...)
+	}
+	if p.HeadCommitSelector != nil {
+		problems = append(
+			problems,
+			p.HeadCommitSelector.validate(path+".headCommit")...,
+		)
+	}
+	if p.PathSelector != nil {
+		problems = append(problems, p.PathSelector.validate(path+".paths")...)
+	}
+	return problems
+}
+
+func (p *pushEventSelector) compile(path string) []string {
+	problems := []string{}
+	if p.BranchSelector != nil {
+		problems = append(problems, p.BranchSelector.compile(path+".branches")...)
+	}
+	if p.TagSelector != nil {
+		problems = append(problems, p.TagSelector.compile(path+".tags")...)
+	}
+	if p.HeadCommitSelector != nil {
+		problems = append(
+			problems,
+			p.HeadCommitSelector.compile(path+".headCommit")...,
+		)
+	}
+	if p.PathSelector != nil {
+		problems = append(problems, p.PathSelector.compile(path+".paths")...)
+	}
+	return problems
+}
+
+func (p *pushEventSelector) matches(
+	event brigade.Event,
+) (drake.Decision, error) {
 	pe := github.PushEvent{}
 	if err := json.Unmarshal([]byte(event.Payload), &pe); err != nil {
-		return false, errors.Wrap(err, "error unmarshaling event payload")
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
 	}
-	var fullRef string
-	if pe.Ref != nil {
-		fullRef = *pe.Ref
+	fullRef := pe.GetRef()
+	// Each of the selectors is either a *refSelector or a *tagSelector
+	var refSelector interface {
+		matches(ref string) (bool, string)
 	}
-	var refSelector *refSelector
+	var selector string
 	var ref string
 	if refSubmatches :=
 		branchRefRegex.FindStringSubmatch(fullRef); len(refSubmatches) == 2 {
-		refSelector = p.BranchSelector
+		selector = "push.branches"
 		ref = refSubmatches[1]
+		if p.BranchSelector != nil {
+			refSelector = p.BranchSelector
+		}
 	}
 	if refSelector == nil {
 		if refSubmatches :=
 			tagRefRegex.FindStringSubmatch(fullRef); len(refSubmatches) == 2 {
-			refSelector = p.TagSelector
+			selector = "push.tags"
 			ref = refSubmatches[1]
+			if p.TagSelector != nil {
+				refSelector = p.TagSelector
+			}
 		}
 	}
 	if refSelector == nil {
-		log.Printf("no applicable selector found for ref %q", fullRef)
-		return false, nil
-	}
-	match, err := refSelector.matches(ref)
-	if err != nil {
-		return false, errors.Wrapf(
-			err,
-			"error matching ref %q to selector",
+		return drake.NotMatched(
+			selector,
 			fullRef,
-		)
+			"no applicable selector is configured",
+		), nil
+	}
+	match, reason := refSelector.matches(ref)
+	decision := drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    fullRef,
+		Reason:   reason,
+	}
+	if !match {
+		return decision, nil
+	}
+	headCommit := pe.GetHeadCommit()
+	// A tag's head commit is typically one that was already built when it was
+	// pushed to a branch, so its message doesn't reflect the intent of the
+	// person pushing the tag. Only pushes to branches are skipped.
+	if selector == "push.branches" &&
+		skipCIRegex.MatchString(headCommit.GetMessage()) {
+		return drake.NotMatched(
+			"push.headCommit",
+			headCommit.GetID(),
+			"head commit message contains %s",
+			skipCIRegex.FindString(headCommit.GetMessage()),
+		), nil
+	}
+	if p.HeadCommitSelector != nil {
+		if match, field, reason :=
+			p.HeadCommitSelector.matches(headCommit); !match {
+			return drake.NotMatched(
+				"push.headCommit."+field,
+				headCommit.GetID(),
+				"%s",
+				reason,
+			), nil
+		}
+	}
+	if p.PathSelector != nil {
+		files, complete := changedFiles(pe)
+		if match, reason := p.PathSelector.matches(files, complete); !match {
+			return drake.NotMatched("push.paths", fullRef, "%s", reason), nil
+		}
+	}
+	return decision, nil
+}
+
+// changedFiles returns the paths of all files added, modified, or removed by
+// the commits in the provided push event. The boolean return value indicates
+// whether the list is known to be complete. It is not if the payload lists no
+// commits, as is the case when a branch is created from an existing commit or
+// a tag is pushed, or if it lists only some of the commits pushed.
+func changedFiles(pe github.PushEvent) ([]string, bool) {
+	files := []string{}
+	for _, commit := range pe.Commits {
+		files = append(files, commit.Added...)
+		files = append(files, commit.Modified...)
+		files = append(files, commit.Removed...)
 	}
-	return match, nil
+	complete := len(pe.Commits) > 0 &&
+		len(pe.Commits) < maxPushPayloadCommits &&
+		pe.GetSize() <= len(pe.Commits)
+	return files, complete
 }
@@ -0,0 +1,102 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+// refEventSelector selects the creation or deletion of branches and tags.
+type refEventSelector struct {
+	BranchSelector *refSelector `json:"branches,omitempty"`
+	TagSelector    *tagSelector `json:"tags,omitempty"`
+}
+
+func (r *refEventSelector) validate(path string) []string {
+	problems := []string{}
+	if r.BranchSelector == nil && r.TagSelector == nil {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"at least one of %s.branches or %s.tags must be specified; without "+
+					"either, no branch or tag can match",
+				path,
+				path,
+			),
+		)
+	}
+	if r.TagSelector != nil {
+		problems = append(problems, r.TagSelector.validate(path+".tags")...)
+	}
+	return problems
+}
+
+func (r *refEventSelector) compile(path string) []string {
+	problems := []string{}
+	if r.BranchSelector != nil {
+		problems = append(problems, r.BranchSelector.compile(path+".branches")...)
+	}
+	if r.TagSelector != nil {
+		problems = append(problems, r.TagSelector.compile(path+".tags")...)
+	}
+	return problems
+}
+
+// matches returns a decision about the provided create or delete event. The
+// path qualifies the selectors named by the decision.
+func (r *refEventSelector) matches(
+	event brigade.Event,
+	path string,
+) (drake.Decision, error) {
+	// The payloads of create and delete events have these fields in common.
+	// Unlike in push events, refs are not fully qualified.
+	payload := struct {
+		Ref     string `json:"ref"`
+		RefType string `json:"ref_type"`
+	}{}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	// Each of the selectors is either a *refSelector or a *tagSelector
+	var refSelector interface {
+		matches(ref string) (bool, string)
+	}
+	var selector string
+	switch payload.RefType {
+	case "branch":
+		selector = path + ".branches"
+		if r.BranchSelector != nil {
+			refSelector = r.BranchSelector
+		}
+	case "tag":
+		selector = path + ".tags"
+		if r.TagSelector != nil {
+			refSelector = r.TagSelector
+		}
+	default:
+		return drake.NotMatched(
+			path,
+			payload.Ref,
+			"ref type %q is not supported",
+			payload.RefType,
+		), nil
+	}
+	if refSelector == nil {
+		return drake.NotMatched(
+			selector,
+			payload.Ref,
+			"no applicable selector is configured",
+		), nil
+	}
+	match, reason := refSelector.matches(payload.Ref)
+	return drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    payload.Ref,
+		Reason:   reason,
+	}, nil
+}
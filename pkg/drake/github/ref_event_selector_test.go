@@ -0,0 +1,127 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefEventSelectorMatches(t *testing.T) {
+	testCases := []struct {
+		name      string
+		trigger   trigger
+		eventType string
+		payload   string
+		decision  drake.Decision
+	}{
+		{
+			name: "created branch with selected name",
+			trigger: trigger{
+				CreateEventSelector: &refEventSelector{
+					BranchSelector: &refSelector{
+						WhitelistedRefs: []string{"feature/**"},
+					},
+				},
+			},
+			eventType: "create",
+			payload:   "create-branch",
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "create.branches",
+				Value:    "feature/foo",
+				Reason:   `"feature/foo" matches only "feature/**"`,
+			},
+		},
+		{
+			name: "created tag without tag selector",
+			trigger: trigger{
+				CreateEventSelector: &refEventSelector{
+					BranchSelector: &refSelector{},
+				},
+			},
+			eventType: "create",
+			payload:   "create-tag",
+			decision: drake.Decision{
+				Selector: "create.tags",
+				Value:    "v0.6.0",
+				Reason:   "no applicable selector is configured",
+			},
+		},
+		{
+			name: "created tag with selected version",
+			trigger: trigger{
+				CreateEventSelector: &refEventSelector{
+					TagSelector: &tagSelector{SemVer: ">=0.6.0"},
+				},
+			},
+			eventType: "create",
+			payload:   "create-tag",
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "create.tags",
+				Value:    "v0.6.0",
+				Reason:   `"v0.6.0" is within semver range ">=0.6.0"`,
+			},
+		},
+		{
+			name: "deleted branch without delete event selector",
+			trigger: trigger{
+				CreateEventSelector: &refEventSelector{
+					BranchSelector: &refSelector{},
+				},
+			},
+			eventType: "delete",
+			payload:   "delete-branch",
+			decision: drake.Decision{
+				Selector: "delete",
+				Value:    "delete",
+				Reason:   "no delete event selector is configured",
+			},
+		},
+		{
+			name: "deleted branch with ignored name",
+			trigger: trigger{
+				DeleteEventSelector: &refEventSelector{
+					BranchSelector: &refSelector{
+						BlacklistedRefs: []string{"feature/*"},
+					},
+				},
+			},
+			eventType: "delete",
+			payload:   "delete-branch",
+			decision: drake.Decision{
+				Selector: "delete.branches",
+				Value:    "feature/foo",
+				Reason:   `"feature/foo" matches ignore "feature/*"`,
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.trigger.compile())
+			decision, err := testCase.trigger.Matches(
+				brigade.Event{
+					Source:  "brigade.sh/github",
+					Type:    testCase.eventType,
+					Payload: testPayload(t, testCase.payload),
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestRefEventSelectorValidate(t *testing.T) {
+	selector := refEventSelector{}
+	require.Equal(
+		t,
+		[]string{
+			"at least one of delete.branches or delete.tags must be specified; " +
+				"without either, no branch or tag can match",
+		},
+		selector.validate("delete"),
+	)
+}
@@ -1,60 +1,204 @@
 package github
 
 import (
+	"fmt"
 	"regexp"
 	"strings"
 
 	"github.com/pkg/errors"
 )
 
+// refSelector selects refs using two lists of patterns. A ref is selected if
+// it is included by the only list and is not excluded by the ignore list.
+//
+// Each pattern is one of:
+//
+//   - An exact ref name, e.g. master.
+//   - A regular expression delimited by slashes, e.g. /^v[0-9]+/. This is
+//     unanchored unless it is explicitly anchored.
+//   - A shell-style glob, i.e. any other pattern containing *, ? or [. Within
+//     a glob, * matches any sequence of characters except /, ** matches any
+//     sequence of characters including /, ? matches any single character
+//     except /, and [...] matches any single character in the class.
+//
+// Any pattern may be negated by prefixing it with !. Within each list, the
+// last pattern that matches a ref decides whether that ref is matched by the
+// list, so a negated pattern can carve exceptions out of those that precede
+// it, and vice versa. A ref that is matched by no pattern in the only list is
+// included only if that list contains no patterns that aren't negated.
 type refSelector struct {
 	WhitelistedRefs []string `json:"only,omitempty"`
 	BlacklistedRefs []string `json:"ignore,omitempty"`
+
+	only   []refPattern
+	ignore []refPattern
+}
+
+// refPattern is a compiled pattern from either of a refSelector's lists.
+type refPattern struct {
+	raw     string
+	negated bool
+	regex   *regexp.Regexp
+}
+
+// compile compiles all of the selector's patterns. It returns a description,
+// prefixed with the provided path, of every pattern that couldn't be compiled.
+func (r *refSelector) compile(path string) []string {
+	problems := []string{}
+	var listProblems []string
+	r.only, listProblems = compileRefPatterns(path+".only", r.WhitelistedRefs)
+	problems = append(problems, listProblems...)
+	r.ignore, listProblems =
+		compileRefPatterns(path+".ignore", r.BlacklistedRefs)
+	return append(problems, listProblems...)
+}
+
+func compileRefPatterns(path string, raws []string) ([]refPattern, []string) {
+	patterns := make([]refPattern, 0, len(raws))
+	problems := []string{}
+	for i, raw := range raws {
+		pattern, err := compileRefPattern(raw)
+		if err != nil {
+			problems = append(problems, fmt.Sprintf("%s[%d]: %s", path, i, err))
+			continue
+		}
+		patterns = append(patterns, pattern)
+	}
+	return patterns, problems
 }
 
-func (r *refSelector) matches(ref string) (bool, error) {
-	var matchesWhitelist bool
-	if len(r.WhitelistedRefs) == 0 {
-		matchesWhitelist = true
-	} else {
-		for _, whitelistedRef := range r.WhitelistedRefs {
-			var err error
-			matchesWhitelist, err = refMatch(ref, whitelistedRef)
-			if err != nil {
-				return false, err
+func compileRefPattern(raw string) (refPattern, error) {
+	pattern := refPattern{raw: raw}
+	valueOrPattern := raw
+	if strings.HasPrefix(valueOrPattern, "!") {
+		pattern.negated = true
+		valueOrPattern = valueOrPattern[1:]
+	}
+	if valueOrPattern == "" {
+		return refPattern{}, errors.New("must not be empty")
+	}
+	var expr string
+	switch {
+	case len(valueOrPattern) > 1 &&
+		strings.HasPrefix(valueOrPattern, "/") &&
+		strings.HasSuffix(valueOrPattern, "/"):
+		expr = valueOrPattern[1 : len(valueOrPattern)-1]
+	case strings.ContainsAny(valueOrPattern, "*?["):
+		var err error
+		if expr, err = globToRegex(valueOrPattern); err != nil {
+			return refPattern{}, errors.Wrapf(
+				err,
+				"error compiling glob %s",
+				valueOrPattern,
+			)
+		}
+	default:
+		expr = "^" + regexp.QuoteMeta(valueOrPattern) + "$"
+	}
+	var err error
+	if pattern.regex, err = regexp.Compile(expr); err != nil {
+		return refPattern{}, errors.Wrapf(
+			err,
+			"error compiling regular expression %s",
+			valueOrPattern,
+		)
+	}
+	return pattern, nil
+}
+
+// globToRegex translates a shell-style glob into an equivalent, anchored,
+// regular expression.
+func globToRegex(glob string) (string, error) {
+	var sb strings.Builder
+	sb.WriteString("^")
+	for i := 0; i < len(glob); i++ {
+		switch c := glob[i]; c {
+		case '*':
+			if i+1 < len(glob) && glob[i+1] == '*' {
+				i++
+				if i+1 < len(glob) && glob[i+1] == '/' {
+					// **/ matches zero or more whole path segments
+					i++
+					sb.WriteString("(?:.*/)?")
+				} else {
+					sb.WriteString(".*")
+				}
+			} else {
+				sb.WriteString("[^/]*")
 			}
-			if matchesWhitelist {
-				break
+		case '?':
+			sb.WriteString("[^/]")
+		case '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end < 0 {
+				return "", errors.New("unterminated character class")
 			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + class + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
 		}
 	}
-	var matchesBlacklist bool
-	for _, blacklistedRef := range r.BlacklistedRefs {
-		var err error
-		matchesBlacklist, err = refMatch(ref, blacklistedRef)
-		if err != nil {
-			return false, err
+	sb.WriteString("$")
+	return sb.String(), nil
+}
+
+// matches returns whether the provided ref is selected along with a reason.
+// The selector must have been compiled.
+func (r *refSelector) matches(ref string) (bool, string) {
+	included, pattern := lastMatch(ref, r.only, !hasNonNegated(r.only))
+	if !included {
+		if pattern != nil {
+			return false, fmt.Sprintf("%q is excluded by only %q", ref, pattern.raw)
 		}
-		if matchesBlacklist {
-			break
+		return false, fmt.Sprintf(
+			"%q matches none of only [%s]",
+			ref,
+			strings.Join(r.WhitelistedRefs, ", "),
+		)
+	}
+	reason := "no refs are required"
+	if pattern != nil {
+		reason = fmt.Sprintf("%q matches only %q", ref, pattern.raw)
+	} else if len(r.only) > 0 {
+		reason = fmt.Sprintf(
+			"%q is excluded by none of only [%s]",
+			ref,
+			strings.Join(r.WhitelistedRefs, ", "),
+		)
+	}
+	if ignored, pattern := lastMatch(ref, r.ignore, false); ignored {
+		return false, fmt.Sprintf("%q matches ignore %q", ref, pattern.raw)
+	}
+	return true, reason
+}
+
+// lastMatch returns whether the provided ref is matched by the provided list
+// of patterns, according to the last pattern in the list that matches it, and
+// returns that pattern. If no pattern matches the ref, the provided default is
+// returned along with a nil pattern.
+func lastMatch(
+	ref string,
+	patterns []refPattern,
+	def bool,
+) (bool, *refPattern) {
+	for i := len(patterns) - 1; i >= 0; i-- {
+		if patterns[i].regex.MatchString(ref) {
+			return !patterns[i].negated, &patterns[i]
 		}
 	}
-	return matchesWhitelist && !matchesBlacklist, nil
+	return def, nil
 }
 
-func refMatch(ref, valueOrPattern string) (bool, error) {
-	if strings.HasPrefix(valueOrPattern, "/") &&
-		strings.HasSuffix(valueOrPattern, "/") {
-		pattern := valueOrPattern[1 : len(valueOrPattern)-1]
-		regex, err := regexp.Compile(pattern)
-		if err != nil {
-			return false, errors.Wrapf(
-				err,
-				"error compiling regular expression %s",
-				valueOrPattern,
-			)
+func hasNonNegated(patterns []refPattern) bool {
+	for _, pattern := range patterns {
+		if !pattern.negated {
+			return true
 		}
-		return regex.MatchString(ref), nil
 	}
-	return ref == valueOrPattern, nil
+	return false
 }
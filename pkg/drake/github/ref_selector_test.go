@@ -0,0 +1,215 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRefSelectorMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector refSelector
+		matched  []string
+		rejected []string
+	}{
+		{
+			name:     "no patterns",
+			selector: refSelector{},
+			matched:  []string{"master", "feature/foo"},
+		},
+		{
+			name: "exact",
+			selector: refSelector{
+				WhitelistedRefs: []string{"master", "release/*x"},
+			},
+			matched:  []string{"master", "release/ax"},
+			rejected: []string{"master2", "amaster", "release/a/x"},
+		},
+		{
+			name: "regex is unanchored",
+			selector: refSelector{
+				WhitelistedRefs: []string{"/v[0-9]+/"},
+			},
+			matched:  []string{"v1", "release-v2", "v3-rc1"},
+			rejected: []string{"v", "master"},
+		},
+		{
+			name: "anchored regex",
+			selector: refSelector{
+				WhitelistedRefs: []string{"/^v[0-9]+$/"},
+			},
+			matched:  []string{"v1", "v23"},
+			rejected: []string{"release-v2", "v3-rc1"},
+		},
+		{
+			name: "single star does not cross slashes",
+			selector: refSelector{
+				WhitelistedRefs: []string{"release/*"},
+			},
+			matched:  []string{"release/1.0", "release/"},
+			rejected: []string{"release/1.0/hotfix", "release", "xrelease/1.0"},
+		},
+		{
+			name: "double star crosses slashes",
+			selector: refSelector{
+				WhitelistedRefs: []string{"feature/**"},
+			},
+			matched:  []string{"feature/foo", "feature/foo/bar"},
+			rejected: []string{"feature", "features/foo"},
+		},
+		{
+			name: "double star slash matches zero or more segments",
+			selector: refSelector{
+				WhitelistedRefs: []string{"**/hotfix"},
+			},
+			matched:  []string{"hotfix", "release/hotfix", "a/b/hotfix"},
+			rejected: []string{"release/hotfix2", "xhotfix"},
+		},
+		{
+			name: "question marks and classes",
+			selector: refSelector{
+				WhitelistedRefs: []string{"v?.[0-9]", "rc-[!0-9]"},
+			},
+			matched:  []string{"v1.2", "vx.0", "rc-a"},
+			rejected: []string{"v1.x", "v/.1", "v12.3", "rc-1"},
+		},
+		{
+			name: "glob metacharacters are otherwise literal",
+			selector: refSelector{
+				WhitelistedRefs: []string{"v1.*"},
+			},
+			matched:  []string{"v1.0", "v1."},
+			rejected: []string{"v100"},
+		},
+		{
+			name: "exact, regex, and glob combined",
+			selector: refSelector{
+				WhitelistedRefs: []string{"master", "/^v[0-9]+\\./", "release/*"},
+			},
+			matched:  []string{"master", "v1.0", "release/1.0"},
+			rejected: []string{"develop", "v1", "release/1.0/hotfix"},
+		},
+		{
+			name: "negation carves exceptions out of preceding patterns",
+			selector: refSelector{
+				WhitelistedRefs: []string{"release/**", "!release/legacy/*"},
+			},
+			matched:  []string{"release/1.0", "release/legacy"},
+			rejected: []string{"release/legacy/1.0", "master"},
+		},
+		{
+			name: "later patterns override negations",
+			selector: refSelector{
+				WhitelistedRefs: []string{
+					"release/**",
+					"!release/legacy/*",
+					"release/legacy/important",
+				},
+			},
+			matched:  []string{"release/1.0", "release/legacy/important"},
+			rejected: []string{"release/legacy/1.0"},
+		},
+		{
+			name: "only negations",
+			selector: refSelector{
+				WhitelistedRefs: []string{"!/^wip-/", "!gh-pages"},
+			},
+			matched:  []string{"master", "feature/foo"},
+			rejected: []string{"wip-foo", "gh-pages"},
+		},
+		{
+			name: "ignore",
+			selector: refSelector{
+				BlacklistedRefs: []string{"/^dependabot\\//", "gh-pages"},
+			},
+			matched:  []string{"master", "feature/dependabot/foo"},
+			rejected: []string{"dependabot/npm/foo", "gh-pages"},
+		},
+		{
+			name: "negation within ignore",
+			selector: refSelector{
+				BlacklistedRefs: []string{"tmp/**", "!tmp/keep"},
+			},
+			matched:  []string{"master", "tmp/keep"},
+			rejected: []string{"tmp/foo", "tmp/keep/foo"},
+		},
+		{
+			name: "ignore takes precedence over only",
+			selector: refSelector{
+				WhitelistedRefs: []string{"release/*"},
+				BlacklistedRefs: []string{"release/*-rc"},
+			},
+			matched:  []string{"release/1.0"},
+			rejected: []string{"release/1.0-rc", "master"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("test"))
+			for _, ref := range testCase.matched {
+				matched, reason := testCase.selector.matches(ref)
+				require.True(t, matched, "%s: %s", ref, reason)
+			}
+			for _, ref := range testCase.rejected {
+				matched, reason := testCase.selector.matches(ref)
+				require.False(t, matched, "%s: %s", ref, reason)
+			}
+		})
+	}
+}
+
+func TestRefSelectorMatchesReasons(t *testing.T) {
+	selector := refSelector{
+		WhitelistedRefs: []string{"release/*", "!release/old"},
+		BlacklistedRefs: []string{"/-rc$/"},
+	}
+	require.Empty(t, selector.compile("test"))
+	testCases := []struct {
+		ref     string
+		matched bool
+		reason  string
+	}{
+		{
+			ref:     "release/1.0",
+			matched: true,
+			reason:  `"release/1.0" matches only "release/*"`,
+		},
+		{
+			ref:    "release/old",
+			reason: `"release/old" is excluded by only "!release/old"`,
+		},
+		{
+			ref:    "master",
+			reason: `"master" matches none of only [release/*, !release/old]`,
+		},
+		{
+			ref:    "release/1.0-rc",
+			reason: `"release/1.0-rc" matches ignore "/-rc$/"`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.ref, func(t *testing.T) {
+			matched, reason := selector.matches(testCase.ref)
+			require.Equal(t, testCase.matched, matched)
+			require.Equal(t, testCase.reason, reason)
+		})
+	}
+}
+
+func TestRefSelectorCompile(t *testing.T) {
+	selector := refSelector{
+		WhitelistedRefs: []string{"master", "[abc", "/[/"},
+		BlacklistedRefs: []string{"!", "feature/**"},
+	}
+	require.Equal(
+		t,
+		[]string{
+			"test.only[1]: error compiling glob [abc: unterminated character class",
+			"test.only[2]: error compiling regular expression /[/: error " +
+				"parsing regexp: missing closing ]: `[`",
+			"test.ignore[0]: must not be empty",
+		},
+		selector.compile("test"),
+	)
+}
@@ -0,0 +1,105 @@
+package github
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// skipCIRegex matches directives, in commit messages or pull request titles,
+// to skip CI.
+var skipCIRegex = regexp.MustCompile(`(?i)\[(skip ci|ci skip)\]`)
+
+// regexSelector selects strings, such as commit messages, using two lists of
+// unanchored regular expressions. A string is selected if it matches any
+// expression in the only list (or that list is empty) and no expression in the
+// ignore list.
+type regexSelector struct {
+	OnlyRegexes   []string `json:"only,omitempty"`
+	IgnoreRegexes []string `json:"ignore,omitempty"`
+
+	only   []*regexp.Regexp
+	ignore []*regexp.Regexp
+}
+
+func (r *regexSelector) validate(path string) []string {
+	if len(r.OnlyRegexes) == 0 && len(r.IgnoreRegexes) == 0 {
+		return []string{
+			fmt.Sprintf(
+				"at least one of %s.only or %s.ignore must be specified",
+				path,
+				path,
+			),
+		}
+	}
+	return nil
+}
+
+// compile compiles all of the selector's regular expressions. It returns a
+// description, prefixed with the provided path, of every expression that
+// couldn't be compiled.
+func (r *regexSelector) compile(path string) []string {
+	problems := []string{}
+	var listProblems []string
+	r.only, listProblems = compileRegexes(path+".only", r.OnlyRegexes)
+	problems = append(problems, listProblems...)
+	r.ignore, listProblems = compileRegexes(path+".ignore", r.IgnoreRegexes)
+	return append(problems, listProblems...)
+}
+
+func compileRegexes(path string, exprs []string) ([]*regexp.Regexp, []string) {
+	regexes := make([]*regexp.Regexp, 0, len(exprs))
+	problems := []string{}
+	for i, expr := range exprs {
+		if expr == "" {
+			problems = append(
+				problems,
+				fmt.Sprintf("%s[%d]: must not be empty", path, i),
+			)
+			continue
+		}
+		regex, err := regexp.Compile(expr)
+		if err != nil {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s[%d]: error compiling regular expression %s: %s",
+					path,
+					i,
+					expr,
+					err,
+				),
+			)
+			continue
+		}
+		regexes = append(regexes, regex)
+	}
+	return regexes, problems
+}
+
+// matches returns whether the provided string is selected along with a
+// reason. The selector must have been compiled.
+func (r *regexSelector) matches(str string) (bool, string) {
+	if len(r.only) > 0 {
+		var included bool
+		for _, regex := range r.only {
+			if regex.MatchString(str) {
+				included = true
+				break
+			}
+		}
+		if !included {
+			return false, fmt.Sprintf(
+				"%q matches none of only [%s]",
+				str,
+				strings.Join(r.OnlyRegexes, ", "),
+			)
+		}
+	}
+	for _, regex := range r.ignore {
+		if regex.MatchString(str) {
+			return false, fmt.Sprintf("%q matches ignore %q", str, regex)
+		}
+	}
+	return true, fmt.Sprintf("%q is selected", str)
+}
@@ -0,0 +1,74 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRegexSelectorMatches(t *testing.T) {
+	selector := regexSelector{
+		OnlyRegexes:   []string{"^feat", "^fix"},
+		IgnoreRegexes: []string{"(?i)wip"},
+	}
+	require.Empty(t, selector.compile("messages"))
+	testCases := []struct {
+		str     string
+		matched bool
+		reason  string
+	}{
+		{
+			str:     "feat: add foo",
+			matched: true,
+			reason:  `"feat: add foo" is selected`,
+		},
+		{
+			str:    "chore: bump bar",
+			reason: `"chore: bump bar" matches none of only [^feat, ^fix]`,
+		},
+		{
+			str:    "fix: WIP",
+			reason: `"fix: WIP" matches ignore "(?i)wip"`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.str, func(t *testing.T) {
+			matched, reason := selector.matches(testCase.str)
+			require.Equal(t, testCase.matched, matched)
+			require.Equal(t, testCase.reason, reason)
+		})
+	}
+}
+
+func TestRegexSelectorCompile(t *testing.T) {
+	selector := regexSelector{
+		OnlyRegexes:   []string{"("},
+		IgnoreRegexes: []string{"foo", ""},
+	}
+	require.Equal(
+		t,
+		[]string{
+			"messages.only[0]: error compiling regular expression (: error " +
+				"parsing regexp: missing closing ): `(`",
+			"messages.ignore[1]: must not be empty",
+		},
+		selector.compile("messages"),
+	)
+}
+
+func TestSkipCIRegex(t *testing.T) {
+	for _, str := range []string{
+		"Update docs [skip ci]",
+		"[ci skip] Update docs",
+		"Update docs\n\n[SKIP CI]",
+	} {
+		require.True(t, skipCIRegex.MatchString(str), str)
+	}
+	for _, str := range []string{
+		"Update docs",
+		"skip ci",
+		"[skip-ci]",
+	} {
+		require.False(t, skipCIRegex.MatchString(str), str)
+	}
+}
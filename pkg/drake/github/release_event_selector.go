@@ -0,0 +1,127 @@
+package github
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+// defaultReleaseActions are the release actions that are selected if none are
+// specified.
+var defaultReleaseActions = []string{"published"}
+
+// releaseActions enumerates the release actions that may be selected.
+var releaseActions = map[string]struct{}{
+	"created":     {},
+	"deleted":     {},
+	"edited":      {},
+	"prereleased": {},
+	"published":   {},
+	"released":    {},
+	"unpublished": {},
+}
+
+// releaseEventSelector selects releases by action and by the tags they are
+// created from.
+type releaseEventSelector struct {
+	// Actions enumerates the release actions that are selected. If empty,
+	// defaultReleaseActions are selected.
+	Actions     []string     `json:"actions,omitempty"`
+	TagSelector *tagSelector `json:"tags,omitempty"`
+	// Prerelease, if specified, selects only prereleases if true and only full
+	// releases if false.
+	Prerelease *bool `json:"prerelease,omitempty"`
+}
+
+func (r *releaseEventSelector) validate(path string) []string {
+	problems := []string{}
+	for i, action := range r.Actions {
+		if _, ok := releaseActions[action]; !ok {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.actions[%d]: %q is not a recognized release action",
+					path,
+					i,
+					action,
+				),
+			)
+		}
+	}
+	if r.TagSelector == nil {
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"%s.tags must be specified; without it, no release can match",
+				path,
+			),
+		)
+	} else {
+		problems = append(problems, r.TagSelector.validate(path+".tags")...)
+	}
+	return problems
+}
+
+func (r *releaseEventSelector) compile(path string) []string {
+	if r.TagSelector == nil {
+		return nil
+	}
+	return r.TagSelector.compile(path + ".tags")
+}
+
+func (r *releaseEventSelector) matches(
+	event brigade.Event,
+) (drake.Decision, error) {
+	re := github.ReleaseEvent{}
+	if err := json.Unmarshal([]byte(event.Payload), &re); err != nil {
+		return drake.Decision{},
+			errors.Wrap(err, "error unmarshaling event payload")
+	}
+	actions := r.Actions
+	if len(actions) == 0 {
+		actions = defaultReleaseActions
+	}
+	if !containsString(actions, re.GetAction()) {
+		return drake.NotMatched(
+			"release.actions",
+			re.GetAction(),
+			"action %q is not among [%s]",
+			re.GetAction(),
+			strings.Join(actions, ", "),
+		), nil
+	}
+	const selector = "release.tags"
+	tag := re.GetRelease().GetTagName()
+	if r.TagSelector == nil {
+		return drake.NotMatched(
+			selector,
+			tag,
+			"no tag selector is configured",
+		), nil
+	}
+	match, reason := r.TagSelector.matches(tag)
+	decision := drake.Decision{
+		Matched:  match,
+		Selector: selector,
+		Value:    tag,
+		Reason:   reason,
+	}
+	if !match {
+		return decision, nil
+	}
+	if r.Prerelease != nil && *r.Prerelease != re.GetRelease().GetPrerelease() {
+		return drake.NotMatched(
+			"release.prerelease",
+			tag,
+			"release is a prerelease: %t; %t is required",
+			re.GetRelease().GetPrerelease(),
+			*r.Prerelease,
+		), nil
+	}
+	return decision, nil
+}
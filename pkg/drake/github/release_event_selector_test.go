@@ -0,0 +1,128 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestReleaseEventSelectorMatches(t *testing.T) {
+	truth := true
+	falsity := false
+	testCases := []struct {
+		name      string
+		selector  releaseEventSelector
+		eventType string
+		payload   string
+		decision  drake.Decision
+	}{
+		{
+			name: "published release with selected tag",
+			selector: releaseEventSelector{
+				TagSelector: &tagSelector{SemVer: "*"},
+				Prerelease:  &falsity,
+			},
+			eventType: "release:published",
+			payload:   "release-published",
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "release.tags",
+				Value:    "v0.6.0",
+				Reason:   `"v0.6.0" is within semver range "*"`,
+			},
+		},
+		{
+			name: "published release with unselected tag",
+			selector: releaseEventSelector{
+				TagSelector: &tagSelector{
+					refSelector: refSelector{
+						WhitelistedRefs: []string{"v1.*"},
+					},
+				},
+			},
+			eventType: "release:published",
+			payload:   "release-published",
+			decision: drake.Decision{
+				Selector: "release.tags",
+				Value:    "v0.6.0",
+				Reason:   `"v0.6.0" matches none of only [v1.*]`,
+			},
+		},
+		{
+			name: "prereleased release is not selected by default",
+			selector: releaseEventSelector{
+				TagSelector: &tagSelector{},
+			},
+			eventType: "release:prereleased",
+			payload:   "release-prereleased",
+			decision: drake.Decision{
+				Selector: "release.actions",
+				Value:    "prereleased",
+				Reason:   `action "prereleased" is not among [published]`,
+			},
+		},
+		{
+			name: "prerelease when full releases are required",
+			selector: releaseEventSelector{
+				Actions:     []string{"published", "prereleased"},
+				TagSelector: &tagSelector{},
+				Prerelease:  &falsity,
+			},
+			eventType: "release:prereleased",
+			payload:   "release-prereleased",
+			decision: drake.Decision{
+				Selector: "release.prerelease",
+				Value:    "v0.7.0-rc.1",
+				Reason:   "release is a prerelease: true; false is required",
+			},
+		},
+		{
+			name: "prerelease when prereleases are required",
+			selector: releaseEventSelector{
+				Actions:     []string{"prereleased"},
+				TagSelector: &tagSelector{},
+				Prerelease:  &truth,
+			},
+			eventType: "release:prereleased",
+			payload:   "release-prereleased",
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "release.tags",
+				Value:    "v0.7.0-rc.1",
+				Reason:   "no refs are required",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("release"))
+			decision, err := (&trigger{
+				ReleaseEventSelector: &testCase.selector,
+			}).Matches(
+				brigade.Event{
+					Source:  "brigade.sh/github",
+					Type:    testCase.eventType,
+					Payload: testPayload(t, testCase.payload),
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
+
+func TestReleaseEventSelectorValidate(t *testing.T) {
+	selector := releaseEventSelector{
+		Actions: []string{"published", "drafted"},
+	}
+	require.Equal(
+		t,
+		[]string{
+			`release.actions[1]: "drafted" is not a recognized release action`,
+			"release.tags must be specified; without it, no release can match",
+		},
+		selector.validate("release"),
+	)
+}
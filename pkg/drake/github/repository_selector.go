@@ -0,0 +1,73 @@
+package github
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+// repoQualifier is the qualifier that Brigade's GitHub gateway uses to route
+// events to the projects subscribed to a repository.
+const repoQualifier = "repo"
+
+// repositoryOf returns the full name (i.e. owner/name) of the repository the
+// provided event pertains to. The event's repo qualifier takes precedence over
+// the repository object in its payload. An empty string is returned if neither
+// is present.
+func repositoryOf(event brigade.Event) (string, error) {
+	if repo := event.Qualifiers[repoQualifier]; repo != "" {
+		return repo, nil
+	}
+	payload := struct {
+		Repository struct {
+			FullName string `json:"full_name"`
+		} `json:"repository"`
+	}{}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return "", errors.Wrap(err, "error unmarshaling event payload")
+	}
+	return payload.Repository.FullName, nil
+}
+
+// matchesRepository returns a decision about whether the repository the
+// provided event pertains to is selected by the trigger's repository and owner
+// selectors, at least one of which must be non-nil.
+func (t *trigger) matchesRepository(
+	event brigade.Event,
+) (drake.Decision, error) {
+	selector := "repositories"
+	if t.RepositorySelector == nil {
+		selector = "owners"
+	}
+	repo, err := repositoryOf(event)
+	if err != nil {
+		return drake.Decision{}, err
+	}
+	if repo == "" {
+		return drake.NotMatched(
+			selector,
+			"",
+			"event does not pertain to a repository",
+		), nil
+	}
+	if t.RepositorySelector != nil {
+		if match, reason := t.RepositorySelector.matches(repo); !match {
+			return drake.NotMatched("repositories", repo, "%s", reason), nil
+		}
+	}
+	if t.OwnerSelector != nil {
+		owner := strings.SplitN(repo, "/", 2)[0]
+		if match, reason := t.OwnerSelector.matches(owner); !match {
+			return drake.NotMatched("owners", owner, "%s", reason), nil
+		}
+	}
+	return drake.Matched(
+		selector,
+		repo,
+		"repository %q is selected",
+		repo,
+	), nil
+}
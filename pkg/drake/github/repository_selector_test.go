@@ -0,0 +1,137 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRepositorySelectors(t *testing.T) {
+	const payload = `{
+	"ref": "refs/heads/master",
+	"repository": {"full_name": "lovethedrake/canard", "owner": {"login": "lovethedrake"}}
+}` // nolint: lll
+	pushEventSelector := &pushEventSelector{
+		BranchSelector: &refSelector{},
+	}
+	testCases := []struct {
+		name       string
+		trigger    trigger
+		qualifiers map[string]string
+		payload    string
+		decision   drake.Decision
+	}{
+		{
+			name: "repository from payload is selected",
+			trigger: trigger{
+				PushEventSelector: pushEventSelector,
+				RepositorySelector: &refSelector{
+					WhitelistedRefs: []string{"lovethedrake/canard"},
+				},
+			},
+			payload: payload,
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "push.branches",
+				Value:    "refs/heads/master",
+				Reason:   "no refs are required",
+			},
+		},
+		{
+			name: "repository from payload is not selected",
+			trigger: trigger{
+				PushEventSelector: pushEventSelector,
+				RepositorySelector: &refSelector{
+					BlacklistedRefs: []string{"*/canard"},
+				},
+			},
+			payload: payload,
+			decision: drake.Decision{
+				Selector: "repositories",
+				Value:    "lovethedrake/canard",
+				Reason:   `"lovethedrake/canard" matches ignore "*/canard"`,
+			},
+		},
+		{
+			name: "repo qualifier takes precedence over payload",
+			trigger: trigger{
+				PushEventSelector: pushEventSelector,
+				RepositorySelector: &refSelector{
+					WhitelistedRefs: []string{"/^lovethedrake/(canard|drake)$/"},
+				},
+			},
+			qualifiers: map[string]string{"repo": "lovethedrake/prototype"},
+			payload:    payload,
+			decision: drake.Decision{
+				Selector: "repositories",
+				Value:    "lovethedrake/prototype",
+				Reason: `"lovethedrake/prototype" matches none of only ` +
+					"[/^lovethedrake/(canard|drake)$/]",
+			},
+		},
+		{
+			name: "owner is selected",
+			trigger: trigger{
+				PushEventSelector: pushEventSelector,
+				OwnerSelector: &refSelector{
+					WhitelistedRefs: []string{"lovethedrake"},
+				},
+			},
+			qualifiers: map[string]string{"repo": "lovethedrake/canard"},
+			payload:    payload,
+			decision: drake.Decision{
+				Matched:  true,
+				Selector: "push.branches",
+				Value:    "refs/heads/master",
+				Reason:   "no refs are required",
+			},
+		},
+		{
+			name: "owner is not selected",
+			trigger: trigger{
+				PushEventSelector: pushEventSelector,
+				RepositorySelector: &refSelector{
+					WhitelistedRefs: []string{"*/canard"},
+				},
+				OwnerSelector: &refSelector{
+					WhitelistedRefs: []string{"brigadecore"},
+				},
+			},
+			payload: payload,
+			decision: drake.Decision{
+				Selector: "owners",
+				Value:    "lovethedrake",
+				Reason:   `"lovethedrake" matches none of only [brigadecore]`,
+			},
+		},
+		{
+			name: "event does not pertain to a repository",
+			trigger: trigger{
+				PushEventSelector: pushEventSelector,
+				OwnerSelector:     &refSelector{},
+			},
+			payload: `{"ref": "refs/heads/master"}`,
+			decision: drake.Decision{
+				Selector: "owners",
+				Reason:   "event does not pertain to a repository",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.trigger.compile())
+			decision, err := testCase.trigger.Matches(
+				brigade.Event{
+					Source:     "brigade.sh/github",
+					Type:       "push",
+					Qualifiers: testCase.qualifiers,
+					Payload:    testCase.payload,
+				},
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.decision, decision)
+		})
+	}
+}
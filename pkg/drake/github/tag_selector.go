@@ -0,0 +1,127 @@
+package github
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/lovethedrake/canard/pkg/semver"
+)
+
+const (
+	// vPrefixOptional permits, but does not require, a leading "v" in tags
+	// that are evaluated as semantic versions.
+	vPrefixOptional = "optional"
+	// vPrefixRequired requires a leading "v" in tags that are evaluated as
+	// semantic versions.
+	vPrefixRequired = "required"
+	// vPrefixForbidden forbids a leading "v" in tags that are evaluated as
+	// semantic versions.
+	vPrefixForbidden = "forbidden"
+)
+
+// tagSelector selects tags using the patterns of its embedded refSelector
+// and, optionally, a range of semantic versions. A tag is selected only if
+// both select it.
+type tagSelector struct {
+	refSelector
+	// SemVer, if non-empty, is a range of semantic versions. Only tags that
+	// are semantic versions within this range are selected.
+	SemVer string `json:"semver,omitempty"`
+	// IncludePrereleases indicates whether tags that are prerelease versions
+	// may be selected by SemVer. Prereleases are excluded by default.
+	IncludePrereleases bool `json:"includePrereleases,omitempty"`
+	// VPrefix determines whether tags selected by SemVer may, must, or must
+	// not have a leading "v". It is "optional" by default.
+	VPrefix string `json:"vPrefix,omitempty"`
+
+	semverRange *semver.Range
+}
+
+func (t *tagSelector) validate(path string) []string {
+	problems := []string{}
+	if t.SemVer == "" {
+		if t.IncludePrereleases {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.includePrereleases has no effect unless %s.semver is specified",
+					path,
+					path,
+				),
+			)
+		}
+		if t.VPrefix != "" {
+			problems = append(
+				problems,
+				fmt.Sprintf(
+					"%s.vPrefix has no effect unless %s.semver is specified",
+					path,
+					path,
+				),
+			)
+		}
+	}
+	return problems
+}
+
+func (t *tagSelector) compile(path string) []string {
+	problems := t.refSelector.compile(path)
+	switch t.VPrefix {
+	case "", vPrefixOptional, vPrefixRequired, vPrefixForbidden:
+	default:
+		problems = append(
+			problems,
+			fmt.Sprintf(
+				"%s.vPrefix: %q is not one of %s, %s, or %s",
+				path,
+				t.VPrefix,
+				vPrefixOptional,
+				vPrefixRequired,
+				vPrefixForbidden,
+			),
+		)
+	}
+	if t.SemVer != "" {
+		semverRange, err := semver.ParseRange(t.SemVer)
+		if err != nil {
+			return append(problems, fmt.Sprintf("%s.semver: %s", path, err))
+		}
+		t.semverRange = &semverRange
+	}
+	return problems
+}
+
+// matches returns whether the provided tag is selected along with a reason.
+// The selector must have been compiled.
+func (t *tagSelector) matches(tag string) (bool, string) {
+	match, reason := t.refSelector.matches(tag)
+	if !match || t.semverRange == nil {
+		return match, reason
+	}
+	hasVPrefix := strings.HasPrefix(tag, "v")
+	if hasVPrefix && t.VPrefix == vPrefixForbidden {
+		return false, fmt.Sprintf("%q has a forbidden v prefix", tag)
+	}
+	if !hasVPrefix && t.VPrefix == vPrefixRequired {
+		return false, fmt.Sprintf("%q lacks a required v prefix", tag)
+	}
+	version, err := semver.ParseVersion(tag)
+	if err != nil {
+		return false, fmt.Sprintf("%q is not a semantic version", tag)
+	}
+	if version.IsPrerelease() && !t.IncludePrereleases {
+		return false, fmt.Sprintf("%q is a prerelease", tag)
+	}
+	if !t.semverRange.Contains(version) {
+		return false, fmt.Sprintf(
+			"%q is not within semver range %q",
+			tag,
+			t.SemVer,
+		)
+	}
+	semverReason := fmt.Sprintf("%q is within semver range %q", tag, t.SemVer)
+	if len(t.only) == 0 {
+		return true, semverReason
+	}
+	return true, reason + " and " + semverReason
+}
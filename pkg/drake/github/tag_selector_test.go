@@ -0,0 +1,154 @@
+package github
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestTagSelectorMatches(t *testing.T) {
+	testCases := []struct {
+		name     string
+		selector tagSelector
+		matched  []string
+		rejected []string
+	}{
+		{
+			name:     "no semver range",
+			selector: tagSelector{},
+			matched:  []string{"v1.0.0", "v2.0.0-rc.1", "latest"},
+		},
+		{
+			name:     "semver range",
+			selector: tagSelector{SemVer: ">=1.0.0 <2.0.0"},
+			matched:  []string{"v1.0.0", "1.2.3", "v1.9.9+build.5"},
+			rejected: []string{"v0.9.0", "v2.0.0", "v1.2", "latest", "release-1.2.3"},
+		},
+		{
+			name:     "prereleases excluded by default",
+			selector: tagSelector{SemVer: "^1.0.0"},
+			matched:  []string{"v1.1.0"},
+			rejected: []string{"v1.1.0-rc.1", "v2.0.0-alpha"},
+		},
+		{
+			name: "prereleases included",
+			selector: tagSelector{
+				SemVer:             "^1.0.0",
+				IncludePrereleases: true,
+			},
+			matched:  []string{"v1.1.0", "v1.1.0-rc.1"},
+			rejected: []string{"v1.0.0-rc.1", "v2.0.0"},
+		},
+		{
+			name: "v prefix optional",
+			selector: tagSelector{
+				SemVer:  "*",
+				VPrefix: vPrefixOptional,
+			},
+			matched: []string{"v1.0.0", "1.0.0"},
+		},
+		{
+			name: "v prefix required",
+			selector: tagSelector{
+				SemVer:  "*",
+				VPrefix: vPrefixRequired,
+			},
+			matched:  []string{"v1.0.0"},
+			rejected: []string{"1.0.0"},
+		},
+		{
+			name: "v prefix forbidden",
+			selector: tagSelector{
+				SemVer:  "*",
+				VPrefix: vPrefixForbidden,
+			},
+			matched:  []string{"1.0.0"},
+			rejected: []string{"v1.0.0"},
+		},
+		{
+			name: "semver range and patterns",
+			selector: tagSelector{
+				refSelector: refSelector{
+					BlacklistedRefs: []string{"v1.3.*"},
+				},
+				SemVer: ">=1.0.0 <2.0.0",
+			},
+			matched:  []string{"v1.2.0", "v1.4.0"},
+			rejected: []string{"v1.3.0", "v2.0.0"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.selector.compile("test"))
+			for _, tag := range testCase.matched {
+				matched, reason := testCase.selector.matches(tag)
+				require.True(t, matched, "%s: %s", tag, reason)
+			}
+			for _, tag := range testCase.rejected {
+				matched, reason := testCase.selector.matches(tag)
+				require.False(t, matched, "%s: %s", tag, reason)
+			}
+		})
+	}
+}
+
+func TestTagSelectorMatchesReasons(t *testing.T) {
+	selector := tagSelector{
+		refSelector: refSelector{
+			WhitelistedRefs: []string{"v1.*"},
+		},
+		SemVer:  ">=1.0.0 <2.0.0",
+		VPrefix: vPrefixRequired,
+	}
+	require.Empty(t, selector.compile("test"))
+	testCases := []struct {
+		tag     string
+		matched bool
+		reason  string
+	}{
+		{
+			tag:     "v1.2.3",
+			matched: true,
+			reason: `"v1.2.3" matches only "v1.*" and "v1.2.3" is within ` +
+				`semver range ">=1.0.0 <2.0.0"`,
+		},
+		{
+			tag:    "v2.0.0",
+			reason: `"v2.0.0" matches none of only [v1.*]`,
+		},
+		{
+			tag:    "v1.foo",
+			reason: `"v1.foo" is not a semantic version`,
+		},
+		{
+			tag:    "v1.2.3-rc.1",
+			reason: `"v1.2.3-rc.1" is a prerelease`,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.tag, func(t *testing.T) {
+			matched, reason := selector.matches(testCase.tag)
+			require.Equal(t, testCase.matched, matched)
+			require.Equal(t, testCase.reason, reason)
+		})
+	}
+}
+
+func TestTagSelectorCompile(t *testing.T) {
+	selector := tagSelector{
+		refSelector: refSelector{
+			WhitelistedRefs: []string{""},
+		},
+		SemVer:  ">=1.0.0 <bogus",
+		VPrefix: "sometimes",
+	}
+	problems := selector.compile("test")
+	require.Len(t, problems, 3)
+	require.Equal(t, "test.only[0]: must not be empty", problems[0])
+	require.Equal(
+		t,
+		`test.vPrefix: "sometimes" is not one of optional, required, or forbidden`,
+		problems[1],
+	)
+	require.Contains(t, problems[2], "test.semver: ")
+}
@@ -1,88 +1,348 @@
 package github
 
 import (
-	"encoding/json"
-	"log"
+	"strings"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
 	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/lovethedrake/canard/pkg/githubapi"
 	"github.com/pkg/errors"
 )
 
+// GitHubEventSource is the source of events emitted by Brigade's GitHub
+// gateway.
+const GitHubEventSource = "brigade.sh/github"
+
+const (
+	// SpecURI identifies the trigger spec implemented by this package.
+	SpecURI = "github.com/lovethedrake/drakespec-github"
+	// SpecVersions is the range of versions of the trigger spec implemented by
+	// this package.
+	SpecVersions = "^1.0.0"
+)
+
 // nolint: lll
 type trigger struct {
-	PullRequestEventSelector *pullRequestEventSelector `json:"pullRequest,omitempty"`
-	PushEventSelector        *pushEventSelector        `json:"push,omitempty"`
+	PullRequestEventSelector       *pullRequestEventSelector       `json:"pullRequest,omitempty"`
+	PullRequestReviewEventSelector *pullRequestReviewEventSelector `json:"pullRequestReview,omitempty"`
+	PushEventSelector              *pushEventSelector              `json:"push,omitempty"`
+	CheckSuiteEventSelector        *checkSuiteEventSelector        `json:"checkSuite,omitempty"`
+	IssueCommentEventSelector      *issueCommentEventSelector      `json:"issueComment,omitempty"`
+	ReleaseEventSelector           *releaseEventSelector           `json:"release,omitempty"`
+	CreateEventSelector            *refEventSelector               `json:"create,omitempty"`
+	DeleteEventSelector            *refEventSelector               `json:"delete,omitempty"`
+	DeploymentEventSelector        *deploymentEventSelector        `json:"deployment,omitempty"`
+	// RepositorySelector and OwnerSelector, if specified, select events of all
+	// types by the full name (i.e. owner/name) of the repository they pertain
+	// to and by the login of its owner, respectively.
+	RepositorySelector *refSelector `json:"repositories,omitempty"`
+	OwnerSelector      *refSelector `json:"owners,omitempty"`
+
+	fileLister FileLister
+	head       githubapi.Head
 }
 
 // NewTriggerFromJSON takes a slice of bytes containing JSON as an argument and
 // returns a Trigger that implements the
-// github.com/lovethedrake/drakespec-github spec.
+// github.com/lovethedrake/drakespec-github spec. Files changed by pull requests
+// are listed using the GitHub API. Since the head of the event isn't known,
+// neither check suites nor comments on pull requests are selected; see
+// NewTriggerBuilder().
 func NewTriggerFromJSON(jsonBytes []byte) (drake.Trigger, error) {
-	t := &trigger{}
-	err := json.Unmarshal(jsonBytes, t)
-	return t, err
+	return NewTriggerBuilder(NewGitHubFileLister(), githubapi.Head{})(jsonBytes)
 }
 
-func (t *trigger) Matches(event brigade.Event) (bool, error) {
-	if event.Source != "github" {
-		log.Printf(
-			"event from provider %q does not match github trigger",
-			event.Source,
+// NewTriggerBuilder returns a drake.TriggerBuilder for Triggers that implement
+// the github.com/lovethedrake/drakespec-github spec, that use the provided
+// FileLister to list the files changed by pull requests, and that evaluate
+// only events having the provided head (see githubapi.HeadOf()).
+func NewTriggerBuilder(
+	fileLister FileLister,
+	head githubapi.Head,
+) drake.TriggerBuilder {
+	return func(jsonBytes []byte) (drake.Trigger, error) {
+		t := &trigger{
+			fileLister: fileLister,
+			head:       head,
+		}
+		if err := drake.UnmarshalStrict(jsonBytes, t); err != nil {
+			return nil, err
+		}
+		if err := drake.NewValidationError(t.compile()); err != nil {
+			return nil, err
+		}
+		return t, nil
+	}
+}
+
+// compile compiles all of the trigger's patterns. It returns a description of
+// every pattern that couldn't be compiled.
+func (t *trigger) compile() []string {
+	problems := []string{}
+	if t.RepositorySelector != nil {
+		problems = append(problems, t.RepositorySelector.compile("repositories")...)
+	}
+	if t.OwnerSelector != nil {
+		problems = append(problems, t.OwnerSelector.compile("owners")...)
+	}
+	if t.PullRequestEventSelector != nil {
+		problems = append(
+			problems,
+			t.PullRequestEventSelector.compile("pullRequest")...,
+		)
+	}
+	if t.PullRequestReviewEventSelector != nil {
+		problems = append(
+			problems,
+			t.PullRequestReviewEventSelector.compile("pullRequestReview")...,
 		)
-		return false, nil
 	}
+	if t.PushEventSelector != nil {
+		problems = append(problems, t.PushEventSelector.compile("push")...)
+	}
+	if t.CheckSuiteEventSelector != nil {
+		problems = append(
+			problems,
+			t.CheckSuiteEventSelector.compile("checkSuite")...,
+		)
+	}
+	if t.IssueCommentEventSelector != nil {
+		problems = append(
+			problems,
+			t.IssueCommentEventSelector.compile("issueComment")...,
+		)
+	}
+	if t.ReleaseEventSelector != nil {
+		problems = append(problems, t.ReleaseEventSelector.compile("release")...)
+	}
+	if t.CreateEventSelector != nil {
+		problems = append(problems, t.CreateEventSelector.compile("create")...)
+	}
+	if t.DeleteEventSelector != nil {
+		problems = append(problems, t.DeleteEventSelector.compile("delete")...)
+	}
+	if t.DeploymentEventSelector != nil {
+		problems = append(
+			problems,
+			t.DeploymentEventSelector.compile("deployment")...,
+		)
+	}
+	return problems
+}
 
-	switch event.Type {
-	case "pull_request:opened",
-		"pull_request:synchronize",
-		"pull_request:reopened":
-		if t.PullRequestEventSelector == nil {
-			log.Println(
-				"pull request event does not match trigger with unconfigured pull " +
-					"request event selector",
-			)
-			return false, nil
-		}
-		matches, err := t.PullRequestEventSelector.matches(event)
+// Validate implements drake.Validator.
+func (t *trigger) Validate() error {
+	problems := []string{}
+	if t.PullRequestEventSelector == nil &&
+		t.PullRequestReviewEventSelector == nil &&
+		t.PushEventSelector == nil &&
+		t.CheckSuiteEventSelector == nil &&
+		t.IssueCommentEventSelector == nil &&
+		t.ReleaseEventSelector == nil &&
+		t.CreateEventSelector == nil &&
+		t.DeleteEventSelector == nil &&
+		t.DeploymentEventSelector == nil {
+		problems = append(
+			problems,
+			"at least one of pullRequest, pullRequestReview, push, checkSuite, "+
+				"issueComment, release, create, delete, or deployment must be "+
+				"specified",
+		)
+	}
+	if t.PullRequestEventSelector != nil {
+		problems = append(
+			problems,
+			t.PullRequestEventSelector.validate("pullRequest")...,
+		)
+	}
+	if t.PullRequestReviewEventSelector != nil {
+		problems = append(
+			problems,
+			t.PullRequestReviewEventSelector.validate("pullRequestReview")...,
+		)
+	}
+	if t.PushEventSelector != nil {
+		problems = append(problems, t.PushEventSelector.validate("push")...)
+	}
+	if t.CheckSuiteEventSelector != nil {
+		problems = append(
+			problems,
+			t.CheckSuiteEventSelector.validate("checkSuite")...,
+		)
+	}
+	if t.IssueCommentEventSelector != nil {
+		problems = append(
+			problems,
+			t.IssueCommentEventSelector.validate("issueComment")...,
+		)
+	}
+	if t.ReleaseEventSelector != nil {
+		problems = append(problems, t.ReleaseEventSelector.validate("release")...)
+	}
+	if t.CreateEventSelector != nil {
+		problems = append(problems, t.CreateEventSelector.validate("create")...)
+	}
+	if t.DeleteEventSelector != nil {
+		problems = append(problems, t.DeleteEventSelector.validate("delete")...)
+	}
+	if t.DeploymentEventSelector != nil {
+		problems = append(
+			problems,
+			t.DeploymentEventSelector.validate("deployment")...,
+		)
+	}
+	return drake.NewValidationError(problems)
+}
+
+func (t *trigger) Matches(event brigade.Event) (drake.Decision, error) {
+	if event.Source != GitHubEventSource {
+		return drake.NotMatched(
+			"",
+			event.Source,
+			"event is not from Brigade's GitHub gateway",
+		), nil
+	}
+
+	if t.RepositorySelector != nil || t.OwnerSelector != nil {
+		decision, err := t.matchesRepository(event)
 		if err != nil {
-			return false, errors.Wrap(
+			return decision, errors.Wrap(
 				err,
-				"error matching pull request event to pull request event selector",
+				"error matching event to repository and owner selectors",
 			)
 		}
-		if matches {
-			log.Println("pull request event matches trigger")
-		} else {
-			log.Println("pull request event does not match trigger")
+		if !decision.Matched {
+			return decision, nil
+		}
+	}
+
+	switch {
+	case strings.HasPrefix(event.Type, "pull_request:"):
+		if t.PullRequestEventSelector == nil {
+			return drake.NotMatched(
+				"pullRequest",
+				event.Type,
+				"no pull request event selector is configured",
+			), nil
+		}
+		decision, err := t.PullRequestEventSelector.matches(event, t.fileLister)
+		return decision, errors.Wrap(
+			err,
+			"error matching pull request event to pull request event selector",
+		)
+	case strings.HasPrefix(event.Type, "pull_request_review:"):
+		if t.PullRequestReviewEventSelector == nil {
+			return drake.NotMatched(
+				"pullRequestReview",
+				event.Type,
+				"no pull request review event selector is configured",
+			), nil
 		}
-		return matches, nil
-	case "push":
+		decision, err := t.PullRequestReviewEventSelector.matches(event)
+		return decision, errors.Wrap(
+			err,
+			"error matching pull request review event to pull request review "+
+				"event selector",
+		)
+	case event.Type == "push":
 		if t.PushEventSelector == nil {
-			log.Println(
-				"push event does not match trigger with unconfigured push event " +
-					"selector",
-			)
-			return false, nil
+			return drake.NotMatched(
+				"push",
+				event.Type,
+				"no push event selector is configured",
+			), nil
 		}
-		matches, err := t.PushEventSelector.matches(event)
-		if err != nil {
-			return false, errors.Wrap(
-				err,
-				"error matching push event to push event selector ",
-			)
+		decision, err := t.PushEventSelector.matches(event)
+		return decision, errors.Wrap(
+			err,
+			"error matching push event to push event selector",
+		)
+	case event.Type == "check_suite:requested",
+		event.Type == "check_suite:rerequested",
+		event.Type == "check_run:rerequested":
+		if t.CheckSuiteEventSelector == nil {
+			return drake.NotMatched(
+				"checkSuite",
+				event.Type,
+				"no check suite event selector is configured",
+			), nil
+		}
+		decision, err := t.CheckSuiteEventSelector.matches(event, t.head)
+		return decision, errors.Wrap(
+			err,
+			"error matching check suite event to check suite event selector",
+		)
+	case event.Type == "issue_comment:created":
+		if t.IssueCommentEventSelector == nil {
+			return drake.NotMatched(
+				"issueComment",
+				event.Type,
+				"no issue comment event selector is configured",
+			), nil
 		}
-		if matches {
-			log.Println("push event matches trigger")
-		} else {
-			log.Println("push event does not match trigger")
+		decision, err := t.IssueCommentEventSelector.matches(event, t.head)
+		return decision, errors.Wrap(
+			err,
+			"error matching issue comment event to issue comment event selector",
+		)
+	case strings.HasPrefix(event.Type, "release:"):
+		if t.ReleaseEventSelector == nil {
+			return drake.NotMatched(
+				"release",
+				event.Type,
+				"no release event selector is configured",
+			), nil
+		}
+		decision, err := t.ReleaseEventSelector.matches(event)
+		return decision, errors.Wrap(
+			err,
+			"error matching release event to release event selector",
+		)
+	case event.Type == "create":
+		if t.CreateEventSelector == nil {
+			return drake.NotMatched(
+				"create",
+				event.Type,
+				"no create event selector is configured",
+			), nil
+		}
+		decision, err := t.CreateEventSelector.matches(event, "create")
+		return decision, errors.Wrap(
+			err,
+			"error matching create event to create event selector",
+		)
+	case event.Type == "delete":
+		if t.DeleteEventSelector == nil {
+			return drake.NotMatched(
+				"delete",
+				event.Type,
+				"no delete event selector is configured",
+			), nil
 		}
-		return matches, nil
+		decision, err := t.DeleteEventSelector.matches(event, "delete")
+		return decision, errors.Wrap(
+			err,
+			"error matching delete event to delete event selector",
+		)
+	// The only action of deployment events is "created"
+	case event.Type == "deployment", event.Type == "deployment:created":
+		if t.DeploymentEventSelector == nil {
+			return drake.NotMatched(
+				"deployment",
+				event.Type,
+				"no deployment event selector is configured",
+			), nil
+		}
+		decision, err := t.DeploymentEventSelector.matches(event)
+		return decision, errors.Wrap(
+			err,
+			"error matching deployment event to deployment event selector",
+		)
 	default:
-		log.Printf(
-			"unsupported event type %q does not match github trigger",
+		return drake.NotMatched(
+			"",
 			event.Type,
-		)
-		return false, nil
+			"event type is not supported",
+		), nil
 	}
 }
@@ -1,18 +1,29 @@
 package github
 
 import (
+	"io/ioutil"
+	"path/filepath"
 	"testing"
 
 	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
 	"github.com/stretchr/testify/require"
 )
 
+// testPayload returns the contents of the named payload recorded in the
+// testdata directory.
+func testPayload(t *testing.T, name string) string {
+	payload, err := ioutil.ReadFile(filepath.Join("testdata", name+".json"))
+	require.NoError(t, err)
+	return string(payload)
+}
+
 func TestMatches(t *testing.T) {
 	testCases := []struct {
 		name       string
 		trigger    *trigger
 		event      brigade.Event
-		assertions func(*testing.T, bool, error)
+		assertions func(*testing.T, drake.Decision, error)
 	}{
 		{
 			name:    "non-github event",
@@ -20,21 +31,45 @@ func TestMatches(t *testing.T) {
 			event: brigade.Event{
 				Source: "bitbucket",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.False(t, decision.Matched)
+			},
+		},
+		{
+			name: "event from source other than the GitHub gateway",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "github",
+				Type:    "pull_request:opened",
+				Payload: `{"action":"opened","pull_request":{"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.Equal(
+					t,
+					drake.Decision{
+						Value:  "github",
+						Reason: "event is not from Brigade's GitHub gateway",
+					},
+					decision,
+				)
 			},
 		},
 		{
 			name:    "unsupported event type",
 			trigger: &trigger{},
 			event: brigade.Event{
-				Source: "github",
+				Source: "brigade.sh/github",
 				Type:   "pull_request",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -42,12 +77,12 @@ func TestMatches(t *testing.T) {
 				"selector",
 			trigger: &trigger{},
 			event: brigade.Event{
-				Source: "github",
+				Source: "brigade.sh/github",
 				Type:   "check_suite:requested",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -56,12 +91,12 @@ func TestMatches(t *testing.T) {
 				PullRequestEventSelector: &pullRequestEventSelector{},
 			},
 			event: brigade.Event{
-				Source: "github",
+				Source: "brigade.sh/github",
 				Type:   "pull_request:opened",
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -74,13 +109,13 @@ func TestMatches(t *testing.T) {
 				},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "pull_request:opened",
 				Payload: `{"action":"opened","pull_request":{"base":{"ref":"foo"}}}`, // nolint: lll
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -93,26 +128,159 @@ func TestMatches(t *testing.T) {
 				},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
+				Type:    "pull_request:opened",
+				Payload: `{"action":"opened","pull_request":{"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					drake.Decision{
+						Matched:  true,
+						Selector: "pullRequest.targetBranches",
+						Value:    "master",
+						Reason:   `"master" matches only "master"`,
+					},
+					decision,
+				)
+			},
+		},
+		{
+			name: "pull request event for action that is not selected by default",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
+				Type:    "pull_request:labeled",
+				Payload: `{"action":"labeled","pull_request":{"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					drake.Decision{
+						Selector: "pullRequest.actions",
+						Value:    "labeled",
+						Reason: `action "labeled" is not among [opened, synchronize, ` +
+							`reopened]`,
+					},
+					decision,
+				)
+			},
+		},
+		{
+			name: "pull request event for selected action",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					Actions:              []string{"labeled", "ready_for_review"},
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
+				Type:    "pull_request:ready_for_review",
+				Payload: `{"action":"ready_for_review","pull_request":{"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name: "pull request event for action that is not selected",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					Actions:              []string{"labeled"},
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
 				Type:    "pull_request:opened",
 				Payload: `{"action":"opened","pull_request":{"base":{"ref":"master"}}}`, // nolint: lll
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.False(t, decision.Matched)
+			},
+		},
+		{
+			name: "pull request closed by being merged",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					Actions:              []string{"merged"},
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
+				Type:    "pull_request:closed",
+				Payload: `{"action":"closed","pull_request":{"merged":true,"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.True(t, matches)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name: "pull request closed without being merged",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					Actions:              []string{"merged"},
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
+				Type:    "pull_request:closed",
+				Payload: `{"action":"closed","pull_request":{"merged":false,"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					drake.Decision{
+						Selector: "pullRequest.actions",
+						Value:    "closed",
+						Reason:   `action "closed" (merged: false) is not among [merged]`,
+					},
+					decision,
+				)
+			},
+		},
+		{
+			name: "pull request closed with any outcome",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					Actions:              []string{"closed"},
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
+				Type:    "pull_request:closed",
+				Payload: `{"action":"closed","pull_request":{"merged":false,"base":{"ref":"master"}}}`, // nolint: lll
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
 			},
 		},
 		{
 			name:    "push event for branch with no push event selector",
 			trigger: &trigger{},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/heads/master"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -122,13 +290,13 @@ func TestMatches(t *testing.T) {
 				PushEventSelector: &pushEventSelector{},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/heads/master"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -142,13 +310,13 @@ func TestMatches(t *testing.T) {
 				},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/heads/foo"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -162,26 +330,26 @@ func TestMatches(t *testing.T) {
 				},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/heads/master"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.True(t, matches)
+				require.True(t, decision.Matched)
 			},
 		},
 		{
 			name:    "push event for tag with no push event selector",
 			trigger: &trigger{},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/tags/foo"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -191,13 +359,13 @@ func TestMatches(t *testing.T) {
 				PushEventSelector: &pushEventSelector{},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/tags/foo"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.False(t, decision.Matched)
 			},
 		},
 		{
@@ -205,19 +373,29 @@ func TestMatches(t *testing.T) {
 				"tag selector",
 			trigger: &trigger{
 				PushEventSelector: &pushEventSelector{
-					TagSelector: &refSelector{
-						WhitelistedRefs: []string{"foo"},
+					TagSelector: &tagSelector{
+						refSelector: refSelector{
+							WhitelistedRefs: []string{"foo"},
+						},
 					},
 				},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/tags/bar"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.False(t, matches)
+				require.Equal(
+					t,
+					drake.Decision{
+						Selector: "push.tags",
+						Value:    "refs/tags/bar",
+						Reason:   `"bar" matches none of only [foo]`,
+					},
+					decision,
+				)
 			},
 		},
 		{
@@ -225,27 +403,474 @@ func TestMatches(t *testing.T) {
 				"selector",
 			trigger: &trigger{
 				PushEventSelector: &pushEventSelector{
-					TagSelector: &refSelector{
-						WhitelistedRefs: []string{"foo"},
+					TagSelector: &tagSelector{
+						refSelector: refSelector{
+							WhitelistedRefs: []string{"foo"},
+						},
 					},
 				},
 			},
 			event: brigade.Event{
-				Source:  "github",
+				Source:  "brigade.sh/github",
 				Type:    "push",
 				Payload: `{"ref":"refs/tags/foo"}`,
 			},
-			assertions: func(t *testing.T, matches bool, err error) {
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name: "push event with skip ci directive",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","head_commit":` +
+					`{"id":"abc123","message":"Fix typo\n\n[ci skip]"}}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
 				require.NoError(t, err)
-				require.True(t, matches)
+				require.Equal(
+					t,
+					drake.Decision{
+						Selector: "push.headCommit",
+						Value:    "abc123",
+						Reason:   "head commit message contains [ci skip]",
+					},
+					decision,
+				)
 			},
 		},
+		{
+			name: "tag push event with skip ci directive",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/tags/v1.0.0","head_commit":` +
+					`{"id":"abc123","message":"Fix typo\n\n[ci skip]"}}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name: "push event with head commit by ignored author",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					HeadCommitSelector: &commitSelector{
+						AuthorSelector: &regexSelector{
+							IgnoreRegexes: []string{`^dependabot\[bot\] `},
+						},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","head_commit":{"id":"abc123",` +
+					`"message":"Bump foo","author":{"name":"dependabot[bot]",` +
+					`"email":"support@github.com"}}}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					drake.Decision{
+						Selector: "push.headCommit.authors",
+						Value:    "abc123",
+						Reason: `"dependabot[bot] <support@github.com>" matches ignore ` +
+							`"^dependabot\\[bot\\] "`,
+					},
+					decision,
+				)
+			},
+		},
+		{
+			name: "push event with head commit having selected message",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					HeadCommitSelector: &commitSelector{
+						MessageSelector: &regexSelector{
+							OnlyRegexes: []string{"^release: "},
+						},
+						AuthorSelector: &regexSelector{
+							IgnoreRegexes: []string{`\[bot\]`},
+						},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","head_commit":{"id":"abc123",` +
+					`"message":"release: v1.0.0","author":{"name":"Jane Doe",` +
+					`"email":"jane@example.com"}}}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name: "push event with head commit having unselected message",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					HeadCommitSelector: &commitSelector{
+						MessageSelector: &regexSelector{
+							OnlyRegexes: []string{"^release: "},
+						},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","head_commit":{"id":"abc123",` +
+					`"message":"chore: tidy"}}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.False(t, decision.Matched)
+				require.Equal(t, "push.headCommit.messages", decision.Selector)
+			},
+		},
+		{
+			name: "push event that changes relevant files",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &pathSelector{
+						OnlyPaths: []string{"services/foo/**"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","size":2,"commits":[` +
+					`{"added":["README.md"]},` +
+					`{"modified":["Makefile"],"removed":["services/foo/main.go"]}]}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+				require.Equal(t, "push.branches", decision.Selector)
+			},
+		},
+		{
+			name: "push event that changes no relevant files",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &pathSelector{
+						IgnorePaths: []string{"**/*.md"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","size":1,"commits":[` +
+					`{"added":["README.md"],"modified":["docs/index.md"]}]}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					drake.Decision{
+						Selector: "push.paths",
+						Value:    "refs/heads/master",
+						Reason: "none of 2 changed file(s) are relevant to only [] and " +
+							"ignore [**/*.md]",
+					},
+					decision,
+				)
+			},
+		},
+		{
+			name: "push event with truncated commits",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector: &pathSelector{
+						IgnorePaths: []string{"**/*.md"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source: "brigade.sh/github",
+				Type:   "push",
+				Payload: `{"ref":"refs/heads/master","size":3,"commits":[` +
+					`{"added":["README.md"]}]}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name: "push event without commits",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{},
+					PathSelector: &pathSelector{
+						IgnorePaths: []string{"**/*.md"},
+					},
+				},
+			},
+			event: brigade.Event{
+				Source:  "brigade.sh/github",
+				Type:    "push",
+				Payload: `{"ref":"refs/tags/v1.0.0","commits":[]}`,
+			},
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+	}
+
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Empty(t, testCase.trigger.compile())
+			decision, err := testCase.trigger.Matches(testCase.event)
+			testCase.assertions(t, decision, err)
+		})
+	}
+}
+
+func TestNewTriggerFromJSON(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, error)
+	}{
+		{
+			name: "valid",
+			json: `{"pullRequest":{"targetBranches":{"only":["master"]}},` +
+				`"push":{"tags":{"ignore":["/^v0\\./"],"semver":"^1.0.0",` +
+				`"includePrereleases":true,"vPrefix":"required"}}}`,
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name: "bad patterns",
+			json: `{"pullRequest":{"targetBranches":{"only":["release/[0-9"]}},` +
+				`"push":{"tags":{"only":["/(/"],"ignore":["","!"]}}}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				verr, ok := err.(*drake.ValidationError)
+				require.True(t, ok)
+				require.Equal(
+					t,
+					[]string{
+						"pullRequest.targetBranches.only[0]: error compiling glob " +
+							"release/[0-9: unterminated character class",
+						"push.tags.only[0]: error compiling regular expression /(/: " +
+							"error parsing regexp: missing closing ): `(`",
+						"push.tags.ignore[0]: must not be empty",
+						"push.tags.ignore[1]: must not be empty",
+					},
+					verr.Problems,
+				)
+			},
+		},
+		{
+			name: "misspelled fields",
+			json: `{"pullrequest":{},"push":{"branch":{}}}`,
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "pullrequest: unknown field")
+				require.Contains(t, err.Error(), "push.branch: unknown field")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			_, err := NewTriggerFromJSON([]byte(testCase.json))
+			testCase.assertions(t, err)
+		})
 	}
+}
 
+func TestValidate(t *testing.T) {
+	testCases := []struct {
+		name       string
+		trigger    *trigger
+		assertions func(*testing.T, error)
+	}{
+		{
+			name: "valid",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.NoError(t, err)
+			},
+		},
+		{
+			name:    "no selectors",
+			trigger: &trigger{},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"at least one of pullRequest, pullRequestReview, push, "+
+						"checkSuite, issueComment, release, create, delete, or "+
+						"deployment must be specified",
+				)
+			},
+		},
+		{
+			name: "empty pull request selector",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{},
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				verr, ok := err.(*drake.ValidationError)
+				require.True(t, ok)
+				require.Len(t, verr.Problems, 1)
+				require.Contains(
+					t,
+					verr.Problems[0],
+					"pullRequest.targetBranches must be specified",
+				)
+			},
+		},
+		{
+			name: "unrecognized pull request action",
+			trigger: &trigger{
+				PullRequestEventSelector: &pullRequestEventSelector{
+					Actions:              []string{"opened", "merge"},
+					TargetBranchSelector: &refSelector{},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					`pullRequest.actions[1]: "merge" is not a recognized pull request `+
+						"action",
+				)
+			},
+		},
+		{
+			name: "semver options without semver",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					TagSelector: &tagSelector{
+						IncludePrereleases: true,
+						VPrefix:            vPrefixRequired,
+					},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"push.tags.includePrereleases has no effect unless push.tags.semver "+
+						"is specified",
+				)
+				require.Contains(
+					t,
+					err.Error(),
+					"push.tags.vPrefix has no effect unless push.tags.semver is "+
+						"specified",
+				)
+			},
+		},
+		{
+			name: "empty path selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector: &refSelector{},
+					PathSelector:   &pathSelector{},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"at least one of push.paths.only or push.paths.ignore must be "+
+						"specified",
+				)
+			},
+		},
+		{
+			name: "empty head commit selector",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{
+					BranchSelector:     &refSelector{},
+					HeadCommitSelector: &commitSelector{},
+				},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"at least one of push.headCommit.messages or "+
+						"push.headCommit.authors must be specified",
+				)
+			},
+		},
+		{
+			name: "issue comment selector without commands",
+			trigger: &trigger{
+				IssueCommentEventSelector: &issueCommentEventSelector{},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"issueComment.commands must be specified",
+				)
+			},
+		},
+		{
+			name: "push selector without branches or tags",
+			trigger: &trigger{
+				PushEventSelector: &pushEventSelector{},
+			},
+			assertions: func(t *testing.T, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"at least one of push.branches or push.tags must be specified",
+				)
+			},
+		},
+	}
 	for _, testCase := range testCases {
 		t.Run(testCase.name, func(t *testing.T) {
-			matches, err := testCase.trigger.Matches(testCase.event)
-			testCase.assertions(t, matches, err)
+			testCase.assertions(t, testCase.trigger.Validate())
 		})
 	}
 }
@@ -0,0 +1,165 @@
+package plugin
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log"
+	"os/exec"
+	"strings"
+	"time"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/pkg/errors"
+)
+
+// DefaultTimeout is how long a plugin is given to reach a decision if no other
+// timeout is specified.
+const DefaultTimeout = 10 * time.Second
+
+// maxOutputBytes is the most output a plugin may write to either stdout or
+// stderr. Anything more is discarded.
+const maxOutputBytes = 1024 * 1024
+
+// Request is what a plugin receives, as JSON, on stdin.
+type Request struct {
+	// Config is the trigger's configuration, verbatim from the Drakefile.
+	Config json.RawMessage `json:"config"`
+	// Event is the event being evaluated. The worker's API token and the
+	// project's secrets are redacted.
+	Event brigade.Event `json:"event"`
+}
+
+// Response is what a plugin must write, as JSON, to stdout before exiting
+// with a zero exit code.
+type Response struct {
+	// Matches indicates whether the event satisfies the trigger.
+	Matches bool `json:"matches"`
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+	// Selector optionally identifies the part of the trigger's configuration
+	// that decided the outcome.
+	Selector string `json:"selector,omitempty"`
+	// Value optionally specifies the value from the event that was inspected in
+	// reaching the decision.
+	Value string `json:"value,omitempty"`
+}
+
+type trigger struct {
+	path       string
+	timeout    time.Duration
+	configJSON []byte
+}
+
+// NewTriggerBuilder returns a drake.TriggerBuilder that builds Triggers that
+// delegate their decisions to the executable at the provided path. The
+// executable is run afresh for each event evaluated. It receives a Request on
+// stdin and must write a Response to stdout. If it exits with a non-zero exit
+// code, writes anything other than a Response to stdout, or fails to exit
+// within the provided timeout, the evaluation fails. If timeout is zero,
+// DefaultTimeout is used.
+func NewTriggerBuilder(path string, timeout time.Duration) drake.TriggerBuilder {
+	if timeout == 0 {
+		timeout = DefaultTimeout
+	}
+	return func(configJSON []byte) (drake.Trigger, error) {
+		return &trigger{
+			path:       path,
+			timeout:    timeout,
+			configJSON: configJSON,
+		}, nil
+	}
+}
+
+func (t *trigger) Matches(event brigade.Event) (drake.Decision, error) {
+	// Plugins don't need credentials to make a decision
+	event.Worker.ApiToken = ""
+	event.Project.Secrets = nil
+	configJSON := json.RawMessage(t.configJSON)
+	if len(configJSON) == 0 {
+		configJSON = json.RawMessage("null")
+	}
+	reqBytes, err := json.Marshal(
+		Request{
+			Config: configJSON,
+			Event:  event,
+		},
+	)
+	if err != nil {
+		return drake.Decision{}, errors.Wrap(err, "error marshaling plugin request")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), t.timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.path) // nolint: gosec
+	cmd.Stdin = bytes.NewReader(reqBytes)
+	stdout := &limitedBuffer{limit: maxOutputBytes}
+	stderr := &limitedBuffer{limit: maxOutputBytes}
+	cmd.Stdout = stdout
+	cmd.Stderr = stderr
+	if err = cmd.Start(); err != nil {
+		return drake.Decision{}, errors.Wrapf(err, "error executing plugin %s", t.path)
+	}
+	// The plugin is killed when the context times out, but if it has spawned
+	// processes of its own that have inherited its stdout or stderr, Wait()
+	// won't return until those have exited too. So we don't wait on Wait()
+	// beyond the timeout.
+	doneCh := make(chan error, 1)
+	go func() {
+		doneCh <- cmd.Wait()
+	}()
+	select {
+	case err = <-doneCh:
+	case <-ctx.Done():
+	}
+	if ctx.Err() != nil {
+		return drake.Decision{}, errors.Errorf(
+			"plugin %s did not reach a decision within %s",
+			t.path,
+			t.timeout,
+		)
+	}
+	if stderrStr := strings.TrimSpace(stderr.String()); stderrStr != "" {
+		log.Printf("plugin %s wrote to stderr:\n%s", t.path, stderrStr)
+	}
+	if err != nil {
+		return drake.Decision{}, errors.Wrapf(err, "error executing plugin %s", t.path)
+	}
+
+	res := Response{}
+	decoder := json.NewDecoder(bytes.NewReader(stdout.Bytes()))
+	decoder.DisallowUnknownFields()
+	if err = decoder.Decode(&res); err != nil {
+		return drake.Decision{}, errors.Wrapf(
+			err,
+			"error parsing response from plugin %s",
+			t.path,
+		)
+	}
+	return drake.Decision{
+		Matched:  res.Matches,
+		Selector: res.Selector,
+		Value:    res.Value,
+		Reason:   res.Reason,
+	}, nil
+}
+
+// limitedBuffer is an io.Writer that buffers, at most, limit bytes and
+// silently discards the rest. Discarding, rather than failing, ensures a
+// plugin that writes too much is never blocked on a full pipe.
+type limitedBuffer struct {
+	bytes.Buffer
+	limit int
+}
+
+func (l *limitedBuffer) Write(p []byte) (int, error) {
+	if remaining := l.limit - l.Len(); remaining > 0 {
+		if len(p) > remaining {
+			l.Buffer.Write(p[:remaining]) // nolint: errcheck
+		} else {
+			l.Buffer.Write(p) // nolint: errcheck
+		}
+	}
+	return len(p), nil
+}
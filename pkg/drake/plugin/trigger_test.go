@@ -0,0 +1,202 @@
+package plugin
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/drake"
+	"github.com/stretchr/testify/require"
+)
+
+// pluginBehaviorEnvVar, when set, causes the test binary to behave as a plugin
+// instead of running tests.
+const pluginBehaviorEnvVar = "CANARD_TEST_PLUGIN_BEHAVIOR"
+
+func TestMain(m *testing.M) {
+	switch os.Getenv(pluginBehaviorEnvVar) {
+	case "":
+		os.Exit(m.Run())
+	case "echo":
+		// Matches if the config's "type" equals the event's type and echoes the
+		// request back as the reason
+		reqBytes, _ := ioutil.ReadAll(os.Stdin)
+		req := struct {
+			Config struct {
+				Type string `json:"type"`
+			} `json:"config"`
+			Event brigade.Event `json:"event"`
+		}{}
+		if err := json.Unmarshal(reqBytes, &req); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+		resBytes, _ := json.Marshal(
+			Response{
+				Matches: req.Config.Type == req.Event.Type,
+				Reason:  string(reqBytes),
+			},
+		)
+		fmt.Println(string(resBytes))
+	case "fail":
+		fmt.Fprintln(os.Stderr, "something went wrong")
+		os.Exit(1)
+	case "garbage":
+		fmt.Println("yes")
+	case "hang":
+		time.Sleep(time.Minute)
+	}
+	os.Exit(0)
+}
+
+// pluginPath returns the path to an executable that runs the test binary with
+// the specified plugin behavior.
+func pluginPath(t *testing.T, dir string, behavior string) string {
+	path := filepath.Join(dir, behavior)
+	script := fmt.Sprintf(
+		"#!/bin/sh\n%s=%s exec %q\n",
+		pluginBehaviorEnvVar,
+		behavior,
+		os.Args[0],
+	)
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0755))
+	return path
+}
+
+func TestTrigger(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	event := brigade.Event{
+		Type: "push",
+		Project: brigade.Project{
+			Secrets: map[string]string{"foo": "bar"},
+		},
+		Worker: brigade.Worker{
+			ApiToken: "secret",
+		},
+	}
+	testCases := []struct {
+		name       string
+		behavior   string
+		config     string
+		timeout    time.Duration
+		assertions func(*testing.T, drake.Decision, error)
+	}{
+		{
+			name:     "match",
+			behavior: "echo",
+			config:   `{"type":"push"}`,
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.True(t, decision.Matched)
+			},
+		},
+		{
+			name:     "no match",
+			behavior: "echo",
+			config:   `{"type":"pull_request:opened"}`,
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.False(t, decision.Matched)
+			},
+		},
+		{
+			name:     "no config",
+			behavior: "echo",
+			assertions: func(t *testing.T, decision drake.Decision, err error) {
+				require.NoError(t, err)
+				require.False(t, decision.Matched)
+			},
+		},
+		{
+			name:     "non-zero exit code",
+			behavior: "fail",
+			assertions: func(t *testing.T, _ drake.Decision, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error executing plugin")
+			},
+		},
+		{
+			name:     "invalid response",
+			behavior: "garbage",
+			assertions: func(t *testing.T, _ drake.Decision, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error parsing response")
+			},
+		},
+		{
+			name:     "timeout",
+			behavior: "hang",
+			timeout:  time.Second,
+			assertions: func(t *testing.T, _ drake.Decision, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "did not reach a decision within 1s")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trigger, err := NewTriggerBuilder(
+				pluginPath(t, dir, testCase.behavior),
+				testCase.timeout,
+			)([]byte(testCase.config))
+			require.NoError(t, err)
+			decision, err := trigger.Matches(event)
+			testCase.assertions(t, decision, err)
+		})
+	}
+}
+
+func TestTriggerRedactsCredentials(t *testing.T) {
+	dir, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(dir)
+	// Have the plugin write the request it receives to a file
+	outPath := filepath.Join(dir, "request.json")
+	path := filepath.Join(dir, "plugin")
+	script := fmt.Sprintf(
+		"#!/bin/sh\ncat > %q\n"+
+			`echo '{"matches":true,"reason":"ok","selector":"foo","value":"bar"}'`+
+			"\n",
+		outPath,
+	)
+	require.NoError(t, ioutil.WriteFile(path, []byte(script), 0755))
+	trigger, err := NewTriggerBuilder(path, 0)([]byte(`{"foo":"bar"}`))
+	require.NoError(t, err)
+	decision, err := trigger.Matches(
+		brigade.Event{
+			ID: "123",
+			Project: brigade.Project{
+				Secrets: map[string]string{"foo": "bar"},
+			},
+			Worker: brigade.Worker{
+				ApiToken: "secret",
+			},
+		},
+	)
+	require.NoError(t, err)
+	require.Equal(
+		t,
+		drake.Decision{
+			Matched:  true,
+			Selector: "foo",
+			Value:    "bar",
+			Reason:   "ok",
+		},
+		decision,
+	)
+	reqBytes, err := ioutil.ReadFile(outPath)
+	require.NoError(t, err)
+	req := Request{}
+	require.NoError(t, json.Unmarshal(reqBytes, &req))
+	require.JSONEq(t, `{"foo":"bar"}`, string(req.Config))
+	require.Equal(t, "123", req.Event.ID)
+	require.Empty(t, req.Event.Worker.ApiToken)
+	require.Empty(t, req.Event.Project.Secrets)
+}
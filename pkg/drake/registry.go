@@ -0,0 +1,211 @@
+package drake
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/lovethedrake/canard/pkg/semver"
+	"github.com/pkg/errors"
+)
+
+// TriggerBuilder builds a Trigger from its JSON configuration.
+type TriggerBuilder func(configJSON []byte) (Trigger, error)
+
+// TriggerRegistry maps trigger spec URIs and versions to the TriggerBuilders
+// that implement them.
+type TriggerRegistry struct {
+	mu            sync.RWMutex
+	registrations map[string][]triggerRegistration
+}
+
+type triggerRegistration struct {
+	versions semver.Range
+	builder  TriggerBuilder
+}
+
+// DefaultTriggerRegistry is the TriggerRegistry that the executor consults
+// after registering its built-in triggers, which are bound to the event being
+// handled. Embedders may register additional triggers with it using
+// RegisterTrigger(), including ones that supersede the built-in triggers.
+var DefaultTriggerRegistry = NewTriggerRegistry()
+
+// NewTriggerRegistry returns an empty TriggerRegistry.
+func NewTriggerRegistry() *TriggerRegistry {
+	return &TriggerRegistry{
+		registrations: map[string][]triggerRegistration{},
+	}
+}
+
+// RegisterTrigger registers the provided TriggerBuilder with the
+// DefaultTriggerRegistry. See TriggerRegistry.Register().
+func RegisterTrigger(
+	specURI string,
+	versionRange string,
+	builder TriggerBuilder,
+) error {
+	return DefaultTriggerRegistry.Register(specURI, versionRange, builder)
+}
+
+// Register registers the provided TriggerBuilder as implementing every version
+// of the trigger spec identified by specURI that falls within versionRange.
+// See semver.ParseRange() for range syntax. Where the ranges of more than one
+// registration for the same spec URI contain a given version, the builder
+// whose range is narrowest around that version (see semver.Range.Narrower()) is
+// preferred and, of builders whose ranges are equally narrow, the one
+// registered last is. This permits embedders to supersede built-in triggers
+// using a range no wider than the built-in one's, and to implement particular
+// versions separately from a broader range regardless of the order in which
+// builders are registered.
+func (t *TriggerRegistry) Register(
+	specURI string,
+	versionRange string,
+	builder TriggerBuilder,
+) error {
+	if specURI == "" {
+		return errors.New("trigger spec URI must not be empty")
+	}
+	if builder == nil {
+		return errors.Errorf("builder for trigger %q must not be nil", specURI)
+	}
+	versions, err := semver.ParseRange(versionRange)
+	if err != nil {
+		return errors.Wrapf(err, "error registering trigger %q", specURI)
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.registrations[specURI] = append(
+		t.registrations[specURI],
+		triggerRegistration{
+			versions: versions,
+			builder:  builder,
+		},
+	)
+	return nil
+}
+
+// Clone returns a new TriggerRegistry having all the same registrations.
+// Registering additional triggers with the clone does not affect the original.
+func (t *TriggerRegistry) Clone() *TriggerRegistry {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	clone := NewTriggerRegistry()
+	for specURI, registrations := range t.registrations {
+		clone.registrations[specURI] =
+			append([]triggerRegistration{}, registrations...)
+	}
+	return clone
+}
+
+// RegisterAll registers every registration of the provided TriggerRegistry
+// with this one, after this one's own, preserving the order in which they were
+// registered. See Register() for why that order matters.
+func (t *TriggerRegistry) RegisterAll(other *TriggerRegistry) {
+	other.mu.RLock()
+	registrations := map[string][]triggerRegistration{}
+	for specURI, otherRegistrations := range other.registrations {
+		registrations[specURI] = otherRegistrations
+	}
+	other.mu.RUnlock()
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for specURI, otherRegistrations := range registrations {
+		t.registrations[specURI] = append(
+			append([]triggerRegistration{}, t.registrations[specURI]...),
+			otherRegistrations...,
+		)
+	}
+}
+
+// Resolve returns the best TriggerBuilder for the provided spec URI and
+// version. See Register() for how the best is chosen. If no trigger is
+// registered for the spec URI, the error returned satisfies
+// IsUnregisteredTrigger().
+func (t *TriggerRegistry) Resolve(
+	specURI string,
+	specVersion string,
+) (TriggerBuilder, error) {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	registrations, ok := t.registrations[specURI]
+	if !ok {
+		return nil, &unregisteredTriggerError{specURI: specURI}
+	}
+	version, err := semver.ParseVersion(specVersion)
+	if err != nil {
+		return nil, errors.Wrapf(
+			err,
+			"error parsing version of trigger %q",
+			specURI,
+		)
+	}
+	var best *triggerRegistration
+	for i := range registrations {
+		registration := &registrations[i]
+		if !registration.versions.Contains(version) {
+			continue
+		}
+		// Of equally narrow ranges, the one registered last is preferred
+		if best == nil ||
+			!best.versions.Narrower(registration.versions, version) {
+			best = registration
+		}
+	}
+	if best != nil {
+		return best.builder, nil
+	}
+	ranges := make([]string, len(registrations))
+	for i, registration := range registrations {
+		ranges[i] = fmt.Sprintf("%q", registration.versions)
+	}
+	return nil, errors.Errorf(
+		"trigger %q does not support version %s; supported versions are: %s",
+		specURI,
+		specVersion,
+		strings.Join(ranges, ", "),
+	)
+}
+
+// Build resolves the best TriggerBuilder for the provided spec URI and version
+// (see Resolve()) and uses it to build a Trigger from the provided JSON
+// configuration.
+func (t *TriggerRegistry) Build(
+	specURI string,
+	specVersion string,
+	configJSON []byte,
+) (Trigger, error) {
+	builder, err := t.Resolve(specURI, specVersion)
+	if err != nil {
+		return nil, err
+	}
+	return builder(configJSON)
+}
+
+// SpecURIs returns, in lexical order, the spec URIs of all registered
+// triggers.
+func (t *TriggerRegistry) SpecURIs() []string {
+	t.mu.RLock()
+	defer t.mu.RUnlock()
+	specURIs := make([]string, 0, len(t.registrations))
+	for specURI := range t.registrations {
+		specURIs = append(specURIs, specURI)
+	}
+	sort.Strings(specURIs)
+	return specURIs
+}
+
+type unregisteredTriggerError struct {
+	specURI string
+}
+
+func (u *unregisteredTriggerError) Error() string {
+	return fmt.Sprintf("no trigger is registered for %q", u.specURI)
+}
+
+// IsUnregisteredTrigger returns true if the provided error indicates that no
+// trigger is registered for a given spec URI.
+func IsUnregisteredTrigger(err error) bool {
+	_, ok := err.(*unregisteredTriggerError)
+	return ok
+}
@@ -0,0 +1,184 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+type namedTrigger struct {
+	name string
+}
+
+func (n *namedTrigger) Matches(brigade.Event) (Decision, error) {
+	return Matched("", "", "always"), nil
+}
+
+func builderFor(name string) TriggerBuilder {
+	return func([]byte) (Trigger, error) {
+		return &namedTrigger{name: name}, nil
+	}
+}
+
+func TestTriggerRegistry(t *testing.T) {
+	const specURI = "example.com/drakespec-foo"
+	registry := NewTriggerRegistry()
+	require.NoError(t, registry.Register(specURI, "^1.0.0", builderFor("v1")))
+	require.NoError(t, registry.Register(specURI, "^2.0.0", builderFor("v2")))
+	// Supersedes the v1 builder for a subset of versions
+	require.NoError(
+		t,
+		registry.Register(specURI, ">=1.5.0 <2.0.0", builderFor("v1.5")),
+	)
+	// Is wider than all the above, so supersedes none of them despite being
+	// registered later
+	require.NoError(t, registry.Register(specURI, "<2", builderFor("legacy")))
+	// Is as narrow as the v2 builder, so supersedes it by being registered later
+	require.NoError(
+		t,
+		registry.Register(specURI, ">=2.0.0 <3.0.0", builderFor("v2-override")),
+	)
+	require.Equal(t, []string{specURI}, registry.SpecURIs())
+
+	testCases := []struct {
+		name        string
+		specURI     string
+		specVersion string
+		assertions  func(*testing.T, Trigger, error)
+	}{
+		{
+			name:        "unregistered spec URI",
+			specURI:     "example.com/drakespec-bar",
+			specVersion: "v1.0.0",
+			assertions: func(t *testing.T, _ Trigger, err error) {
+				require.Error(t, err)
+				require.True(t, IsUnregisteredTrigger(err))
+			},
+		},
+		{
+			name:        "invalid version",
+			specURI:     specURI,
+			specVersion: "latest",
+			assertions: func(t *testing.T, _ Trigger, err error) {
+				require.Error(t, err)
+				require.False(t, IsUnregisteredTrigger(err))
+				require.Contains(t, err.Error(), "error parsing version")
+			},
+		},
+		{
+			name:        "unsupported version",
+			specURI:     specURI,
+			specVersion: "v3.0.0",
+			assertions: func(t *testing.T, _ Trigger, err error) {
+				require.Error(t, err)
+				require.False(t, IsUnregisteredTrigger(err))
+				require.Contains(
+					t,
+					err.Error(),
+					`does not support version v3.0.0; supported versions are: `+
+						`"^1.0.0", "^2.0.0", ">=1.5.0 <2.0.0", "<2", `+
+						`">=2.0.0 <3.0.0"`,
+				)
+			},
+		},
+		{
+			name:        "version supported by one builder",
+			specURI:     specURI,
+			specVersion: "v0.9.0",
+			assertions: func(t *testing.T, trigger Trigger, err error) {
+				require.NoError(t, err)
+				require.Equal(t, &namedTrigger{name: "legacy"}, trigger)
+			},
+		},
+		{
+			name:        "version supported by a narrower and a wider builder",
+			specURI:     specURI,
+			specVersion: "v1.2.0",
+			assertions: func(t *testing.T, trigger Trigger, err error) {
+				require.NoError(t, err)
+				require.Equal(t, &namedTrigger{name: "v1"}, trigger)
+			},
+		},
+		{
+			name:        "version supported by multiple builders",
+			specURI:     specURI,
+			specVersion: "v1.6.0",
+			assertions: func(t *testing.T, trigger Trigger, err error) {
+				require.NoError(t, err)
+				require.Equal(t, &namedTrigger{name: "v1.5"}, trigger)
+			},
+		},
+		{
+			name:        "version supported by equally narrow builders",
+			specURI:     specURI,
+			specVersion: "v2.1.0",
+			assertions: func(t *testing.T, trigger Trigger, err error) {
+				require.NoError(t, err)
+				require.Equal(t, &namedTrigger{name: "v2-override"}, trigger)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			trigger, err := registry.Build(
+				testCase.specURI,
+				testCase.specVersion,
+				nil,
+			)
+			testCase.assertions(t, trigger, err)
+		})
+	}
+}
+
+func TestTriggerRegistryRegisterErrors(t *testing.T) {
+	registry := NewTriggerRegistry()
+	require.Error(t, registry.Register("", "^1.0.0", builderFor("foo")))
+	require.Error(t, registry.Register("example.com/foo", "^1.0.0", nil))
+	err := registry.Register("example.com/foo", "one", builderFor("foo"))
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error parsing version range")
+}
+
+func TestTriggerRegistryClone(t *testing.T) {
+	const specURI = "example.com/drakespec-foo"
+	registry := NewTriggerRegistry()
+	require.NoError(t, registry.Register(specURI, "*", builderFor("original")))
+	clone := registry.Clone()
+	require.NoError(t, clone.Register(specURI, "*", builderFor("clone")))
+
+	trigger, err := registry.Build(specURI, "v1.0.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, &namedTrigger{name: "original"}, trigger)
+	trigger, err = clone.Build(specURI, "v1.0.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, &namedTrigger{name: "clone"}, trigger)
+}
+
+func TestTriggerRegistryRegisterAll(t *testing.T) {
+	const specURI = "example.com/drakespec-foo"
+	registry := NewTriggerRegistry()
+	require.NoError(
+		t,
+		registry.Register(specURI, "^1.0.0", builderFor("built-in")),
+	)
+	other := NewTriggerRegistry()
+	require.NoError(t, other.Register(specURI, "^1.0.0", builderFor("embedder")))
+	require.NoError(t, other.Register(specURI, "*", builderFor("fallback")))
+	registry.RegisterAll(other)
+
+	// Registrations from the other registry come last, so they supersede this
+	// one's when equally narrow
+	trigger, err := registry.Build(specURI, "v1.0.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, &namedTrigger{name: "embedder"}, trigger)
+	trigger, err = registry.Build(specURI, "v2.0.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, &namedTrigger{name: "fallback"}, trigger)
+	// The other registry is unaffected
+	require.Equal(t, []string{specURI}, other.SpecURIs())
+	require.NoError(t, other.Register(specURI, "^1.0.0", builderFor("later")))
+	trigger, err = registry.Build(specURI, "v1.0.0", nil)
+	require.NoError(t, err)
+	require.Equal(t, &namedTrigger{name: "embedder"}, trigger)
+}
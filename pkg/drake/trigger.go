@@ -1,8 +1,63 @@
 package drake
 
-import "github.com/lovethedrake/canard/pkg/brigade"
+import (
+	"fmt"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+)
 
 // Trigger is the public interface for all triggers.
 type Trigger interface {
-	Matches(brigade.Event) (bool, error)
+	// Matches decides whether the provided event satisfies the trigger. An error
+	// is returned only if no decision could be reached.
+	Matches(brigade.Event) (Decision, error)
+}
+
+// Decision describes whether, and why, an event satisfies a trigger.
+type Decision struct {
+	// Matched indicates whether the event satisfies the trigger.
+	Matched bool `json:"matched"`
+	// Selector identifies the part of the trigger's configuration that decided
+	// the outcome, e.g. "push.branches". It is empty if the outcome was decided
+	// before any selector was consulted.
+	Selector string `json:"selector,omitempty"`
+	// Value is the ref, or other value from the event, that was inspected in
+	// reaching the decision.
+	Value string `json:"value,omitempty"`
+	// Reason explains the decision.
+	Reason string `json:"reason"`
+	// Env, if the event satisfies the trigger, holds environment variables,
+	// such as arguments parsed from the event, to be set in every container of
+	// every job of the triggered pipeline.
+	Env map[string]string `json:"env,omitempty"`
+}
+
+// Matched returns a Decision indicating that an event satisfies a trigger.
+func Matched(
+	selector string,
+	value string,
+	reasonFormat string,
+	args ...interface{},
+) Decision {
+	return Decision{
+		Matched:  true,
+		Selector: selector,
+		Value:    value,
+		Reason:   fmt.Sprintf(reasonFormat, args...),
+	}
+}
+
+// NotMatched returns a Decision indicating that an event does not satisfy a
+// trigger.
+func NotMatched(
+	selector string,
+	value string,
+	reasonFormat string,
+	args ...interface{},
+) Decision {
+	return Decision{
+		Selector: selector,
+		Value:    value,
+		Reason:   fmt.Sprintf(reasonFormat, args...),
+	}
 }
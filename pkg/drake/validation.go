@@ -0,0 +1,172 @@
+package drake
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Validator is implemented by Triggers that can check their own configuration
+// for problems that strict decoding alone cannot detect, such as malformed
+// patterns or selectors that could never match anything.
+type Validator interface {
+	// Validate returns an error describing every problem found with the
+	// Trigger's configuration, or nil if there are none.
+	Validate() error
+}
+
+// ValidationError describes every problem found with some configuration.
+type ValidationError struct {
+	Problems []string
+}
+
+// NewValidationError returns a *ValidationError listing the provided
+// problems, or nil if there are none.
+func NewValidationError(problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	return &ValidationError{Problems: problems}
+}
+
+func (v *ValidationError) Error() string {
+	msg := fmt.Sprintf(
+		"configuration is invalid; %d problem(s) found:",
+		len(v.Problems),
+	)
+	for _, problem := range v.Problems {
+		msg = fmt.Sprintf("%s\n- %s", msg, problem)
+	}
+	return msg
+}
+
+// UnmarshalStrict is like json.Unmarshal, but rejects any field that doesn't
+// correspond exactly, including by case, to a field of the value being
+// unmarshaled into. Every such field is reported in the *ValidationError that
+// is returned.
+func UnmarshalStrict(jsonBytes []byte, v interface{}) error {
+	var raw interface{}
+	if err := json.Unmarshal(jsonBytes, &raw); err != nil {
+		return err
+	}
+	problems := []string{}
+	checkFields(raw, reflect.TypeOf(v), "", &problems)
+	if err := NewValidationError(problems); err != nil {
+		return err
+	}
+	decoder := json.NewDecoder(bytes.NewReader(jsonBytes))
+	decoder.DisallowUnknownFields()
+	return decoder.Decode(v)
+}
+
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// checkFields walks generically unmarshaled JSON alongside the type it will
+// be unmarshaled into and records a problem for every object key that does not
+// exactly match the name of a field. Types that unmarshal themselves are not
+// examined.
+func checkFields(
+	raw interface{},
+	t reflect.Type,
+	path string,
+	problems *[]string,
+) {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		return
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := raw.(map[string]interface{})
+		if !ok {
+			return // Type mismatches are json.Unmarshal's to report
+		}
+		fields := jsonFields(t)
+		keys := make([]string, 0, len(obj))
+		for key := range obj {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			fieldType, ok := fields[key]
+			if !ok {
+				*problems = append(
+					*problems,
+					fmt.Sprintf("%s: unknown field", joinPath(path, key)),
+				)
+				continue
+			}
+			checkFields(obj[key], fieldType, joinPath(path, key), problems)
+		}
+	case reflect.Slice, reflect.Array:
+		if arr, ok := raw.([]interface{}); ok {
+			for i, elem := range arr {
+				checkFields(elem, t.Elem(), fmt.Sprintf("%s[%d]", path, i), problems)
+			}
+		}
+	case reflect.Map:
+		if obj, ok := raw.(map[string]interface{}); ok {
+			for key, val := range obj {
+				checkFields(val, t.Elem(), joinPath(path, key), problems)
+			}
+		}
+	}
+}
+
+// jsonFields returns the types of the provided struct type's fields, indexed
+// by the names they are represented by in JSON.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue // Unexported
+		}
+		tag := field.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+		if name == "" {
+			if field.Anonymous && indirect(field.Type).Kind() == reflect.Struct {
+				for embeddedName, embeddedType := range jsonFields(
+					indirect(field.Type),
+				) {
+					fields[embeddedName] = embeddedType
+				}
+				continue
+			}
+			name = field.Name
+		}
+		fields[name] = field.Type
+	}
+	return fields
+}
+
+func indirect(t reflect.Type) reflect.Type {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return t
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// ValidateTrigger runs the provided Trigger's Validate() method, if it has
+// one.
+func ValidateTrigger(trigger Trigger) error {
+	if validator, ok := trigger.(Validator); ok {
+		return validator.Validate()
+	}
+	return nil
+}
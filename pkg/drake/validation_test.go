@@ -0,0 +1,94 @@
+package drake
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+type strictTestInner struct {
+	Only []string `json:"only,omitempty"`
+}
+
+type strictTestOuter struct {
+	PullRequest *strictTestInner           `json:"pullRequest,omitempty"`
+	Lists       []strictTestInner          `json:"lists,omitempty"`
+	Named       map[string]strictTestInner `json:"named,omitempty"`
+	Untagged    string
+	Ignored     string                 `json:"-"`
+	Raw         map[string]interface{} `json:"raw,omitempty"`
+}
+
+func TestUnmarshalStrict(t *testing.T) {
+	testCases := []struct {
+		name       string
+		json       string
+		assertions func(*testing.T, strictTestOuter, error)
+	}{
+		{
+			name: "valid",
+			json: `{"pullRequest":{"only":["master"]},"lists":[{"only":["a"]}],` +
+				`"named":{"foo":{"only":["b"]}},"Untagged":"c","raw":{"anything":1}}`,
+			assertions: func(t *testing.T, v strictTestOuter, err error) {
+				require.NoError(t, err)
+				require.Equal(t, []string{"master"}, v.PullRequest.Only)
+				require.Equal(t, []string{"a"}, v.Lists[0].Only)
+				require.Equal(t, []string{"b"}, v.Named["foo"].Only)
+				require.Equal(t, "c", v.Untagged)
+			},
+		},
+		{
+			name: "unknown fields",
+			json: `{"pullrequest":{},"lists":[{"onyl":[]}],` +
+				`"named":{"foo":{"ignore":[]}},"-":"x"}`,
+			assertions: func(t *testing.T, _ strictTestOuter, err error) {
+				require.Error(t, err)
+				verr, ok := err.(*ValidationError)
+				require.True(t, ok)
+				require.Equal(
+					t,
+					[]string{
+						"-: unknown field",
+						"lists[0].onyl: unknown field",
+						"named.foo.ignore: unknown field",
+						"pullrequest: unknown field",
+					},
+					verr.Problems,
+				)
+			},
+		},
+		{
+			name: "type mismatch",
+			json: `{"pullRequest":[]}`,
+			assertions: func(t *testing.T, _ strictTestOuter, err error) {
+				require.Error(t, err)
+				_, ok := err.(*ValidationError)
+				require.False(t, ok)
+			},
+		},
+		{
+			name: "malformed",
+			json: `{`,
+			assertions: func(t *testing.T, _ strictTestOuter, err error) {
+				require.Error(t, err)
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			v := strictTestOuter{}
+			err := UnmarshalStrict([]byte(testCase.json), &v)
+			testCase.assertions(t, v, err)
+		})
+	}
+}
+
+func TestValidationError(t *testing.T) {
+	require.NoError(t, NewValidationError(nil))
+	err := NewValidationError([]string{"foo", "bar"})
+	require.Equal(
+		t,
+		"configuration is invalid; 2 problem(s) found:\n- foo\n- bar",
+		err.Error(),
+	)
+}
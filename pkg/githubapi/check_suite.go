@@ -0,0 +1,158 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+// BranchChecker determines whether commits are on the branches of
+// repositories.
+type BranchChecker interface {
+	// IsOnBranch returns true if the commit having the provided SHA is the head
+	// of, or an ancestor of the head of, the specified branch of the specified
+	// repository. The repository is identified by its full name (e.g.
+	// owner/name). If no such branch or commit exists, false is returned
+	// without error.
+	IsOnBranch(
+		ctx context.Context,
+		event brigade.Event,
+		repo string,
+		branch string,
+		sha string,
+	) (bool, error)
+}
+
+// gitHubBranchChecker is a BranchChecker that uses the GitHub API.
+type gitHubBranchChecker struct {
+	// baseURL, if non-empty, overrides the GitHub API's default base URL
+	baseURL string
+}
+
+// NewGitHubBranchChecker returns a BranchChecker that compares commits using
+// the GitHub API. See NewClient() for how it authenticates.
+func NewGitHubBranchChecker() BranchChecker {
+	return &gitHubBranchChecker{}
+}
+
+func (g *gitHubBranchChecker) IsOnBranch(
+	ctx context.Context,
+	event brigade.Event,
+	repo string,
+	branch string,
+	sha string,
+) (bool, error) {
+	repoParts := strings.SplitN(repo, "/", 2)
+	if len(repoParts) != 2 {
+		return false, errors.Errorf("%q is not a valid repository name", repo)
+	}
+	client, err := NewClient(event, g.baseURL)
+	if err != nil {
+		return false, err
+	}
+	comparison, resp, err := client.Repositories.CompareCommits(
+		ctx,
+		repoParts[0],
+		repoParts[1],
+		branch,
+		sha,
+	)
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return false, nil
+		}
+		return false, errors.Wrapf(
+			err,
+			"error comparing commit %s to branch %s of %s",
+			sha,
+			branch,
+			repo,
+		)
+	}
+	// The commit is on the branch if the branch is identical to or ahead of it
+	switch comparison.GetStatus() {
+	case "identical", "behind":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
+
+// IsCheckSuiteEvent returns true if the provided event pertains to a check
+// suite or to one of its check runs.
+func IsCheckSuiteEvent(event brigade.Event) bool {
+	return strings.HasPrefix(event.Type, "check_suite:") ||
+		strings.HasPrefix(event.Type, "check_run:")
+}
+
+// CheckSuiteOf returns the check suite that the provided check_suite or
+// check_run event pertains to along with the repository it belongs to.
+func CheckSuiteOf(
+	event brigade.Event,
+) (*github.CheckSuite, *github.Repository, error) {
+	payload := struct {
+		CheckSuite *github.CheckSuite `json:"check_suite"`
+		CheckRun   *github.CheckRun   `json:"check_run"`
+		Repository *github.Repository `json:"repository"`
+	}{}
+	if err := json.Unmarshal([]byte(event.Payload), &payload); err != nil {
+		return nil, nil, errors.Wrap(err, "error unmarshaling event payload")
+	}
+	checkSuite := payload.CheckSuite
+	if strings.HasPrefix(event.Type, "check_run:") {
+		checkSuite = payload.CheckRun.GetCheckSuite()
+	}
+	return checkSuite, payload.Repository, nil
+}
+
+// IsCheckSuiteTrusted returns true if the head commit of the check suite that
+// the provided check_suite or check_run event pertains to is known to belong to
+// the repository the event pertains to. Check suites are also requested for
+// pull requests from forks, whose head branches are named by their authors and
+// which don't list those pull requests, so neither the head branch nor an
+// absence of pull requests can be relied upon. Instead, the head commit must
+// be the head of a pull request from within the repository that the check
+// suite belongs to or, failing that, must be on the repository's branch of the
+// same name as the check suite's head branch, as determined by the provided
+// BranchChecker. If the BranchChecker is nil, only the former is considered.
+func IsCheckSuiteTrusted(
+	ctx context.Context,
+	event brigade.Event,
+	checker BranchChecker,
+) (bool, error) {
+	checkSuite, repo, err := CheckSuiteOf(event)
+	if err != nil {
+		return false, err
+	}
+	sha := checkSuite.GetHeadSHA()
+	if sha == "" || repo == nil {
+		return false, nil
+	}
+	for _, pr := range checkSuite.PullRequests {
+		if pr.GetHead().GetSHA() == sha &&
+			isSameRepository(pr.GetHead().GetRepo(), repo) &&
+			isSameRepository(pr.GetBase().GetRepo(), repo) {
+			return true, nil
+		}
+	}
+	branch := checkSuite.GetHeadBranch()
+	if branch == "" || repo.GetFullName() == "" || checker == nil {
+		return false, nil
+	}
+	return checker.IsOnBranch(ctx, event, repo.GetFullName(), branch, sha)
+}
+
+// isSameRepository returns true if the provided repositories are known to be
+// the same. The repositories listed by check suites' pull requests have only
+// IDs, URLs, and names.
+func isSameRepository(a, b *github.Repository) bool {
+	if a.GetID() != 0 && b.GetID() != 0 {
+		return a.GetID() == b.GetID()
+	}
+	return a.GetURL() != "" && a.GetURL() == b.GetURL()
+}
@@ -0,0 +1,178 @@
+package githubapi
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi/githubapitest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsCheckSuiteTrusted(t *testing.T) {
+	checker := &githubapitest.BranchChecker{
+		Branches: map[string][]string{"master": {"def456"}},
+	}
+	// nolint: lll
+	testCases := []struct {
+		name    string
+		event   brigade.Event
+		checker BranchChecker
+		trusted bool
+	}{
+		{
+			name: "check suite of pull request from within repository",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"feature/foo","head_sha":"abc123","pull_requests":[{"head":{"sha":"abc123","repo":{"id":1}},"base":{"repo":{"id":1}}}]},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+			trusted: true,
+		},
+		{
+			name: "check suite of pull request with different head commit",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"feature/foo","head_sha":"bad666","pull_requests":[{"head":{"sha":"abc123","repo":{"id":1}},"base":{"repo":{"id":1}}}]},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+		},
+		{
+			name: "check suite of pull request from another repository",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"feature/foo","head_sha":"abc123","pull_requests":[{"head":{"sha":"abc123","repo":{"id":2}},"base":{"repo":{"id":1}}}]},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+		},
+		{
+			name: "check suite for commit on branch",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"master","head_sha":"def456","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+			checker: checker,
+			trusted: true,
+		},
+		{
+			name: "check suite from fork with head branch master",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"master","head_sha":"bad666","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+			checker: checker,
+		},
+		{
+			name: "check suite for commit on branch without means of checking",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"master","head_sha":"def456","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+		},
+		{
+			name: "re-requested check run for commit on branch",
+			event: brigade.Event{
+				Type:    "check_run:rerequested",
+				Payload: `{"check_run":{"check_suite":{"head_branch":"master","head_sha":"def456","pull_requests":[]}},"repository":{"id":1,"full_name":"lovethedrake/canard"}}`,
+			},
+			checker: checker,
+			trusted: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.True(t, IsCheckSuiteEvent(testCase.event))
+			trusted, err := IsCheckSuiteTrusted(
+				context.Background(),
+				testCase.event,
+				testCase.checker,
+			)
+			require.NoError(t, err)
+			require.Equal(t, testCase.trusted, trusted)
+		})
+	}
+}
+
+func TestGitHubBranchChecker(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/lovethedrake/canard/compare/master...def456":
+				fmt.Fprint(w, `{"status":"behind"}`)
+			case "/api/v3/repos/lovethedrake/canard/compare/master...abc123":
+				fmt.Fprint(w, `{"status":"identical"}`)
+			case "/api/v3/repos/lovethedrake/canard/compare/master...bad666":
+				fmt.Fprint(w, `{"status":"diverged"}`)
+			case "/api/v3/repos/lovethedrake/canard/compare/master...forbidden":
+				w.WriteHeader(http.StatusForbidden)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer server.Close()
+	checker := &gitHubBranchChecker{baseURL: server.URL + "/"}
+
+	testCases := []struct {
+		sha        string
+		assertions func(*testing.T, bool, error)
+	}{
+		{
+			sha: "def456",
+			assertions: func(t *testing.T, onBranch bool, err error) {
+				require.NoError(t, err)
+				require.True(t, onBranch)
+			},
+		},
+		{
+			sha: "abc123",
+			assertions: func(t *testing.T, onBranch bool, err error) {
+				require.NoError(t, err)
+				require.True(t, onBranch)
+			},
+		},
+		{
+			sha: "bad666",
+			assertions: func(t *testing.T, onBranch bool, err error) {
+				require.NoError(t, err)
+				require.False(t, onBranch)
+			},
+		},
+		{
+			sha: "missing",
+			assertions: func(t *testing.T, onBranch bool, err error) {
+				require.NoError(t, err)
+				require.False(t, onBranch)
+			},
+		},
+		{
+			sha: "forbidden",
+			assertions: func(t *testing.T, _ bool, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error comparing commit forbidden")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.sha, func(t *testing.T) {
+			onBranch, err := checker.IsOnBranch(
+				context.Background(),
+				brigade.Event{},
+				"lovethedrake/canard",
+				"master",
+				testCase.sha,
+			)
+			testCase.assertions(t, onBranch, err)
+		})
+	}
+
+	_, err := checker.IsOnBranch(
+		context.Background(),
+		brigade.Event{},
+		"canard",
+		"master",
+		"abc123",
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "not a valid repository name")
+}
@@ -0,0 +1,40 @@
+package githubapi
+
+import (
+	"net/http"
+	"time"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+// TokenSecret is the name of the project secret that, if present, is used to
+// authenticate to the GitHub API.
+const TokenSecret = "githubToken"
+
+// timeout is how long any one request to the GitHub API may take.
+const timeout = 30 * time.Second
+
+// NewClient returns a GitHub API client for use on behalf of the project the
+// provided event belongs to. If the project has a TokenSecret secret, it is
+// used to authenticate. If baseURL is non-empty, it overrides the GitHub API's
+// default base URL, as is required for GitHub Enterprise.
+func NewClient(event brigade.Event, baseURL string) (*github.Client, error) {
+	httpClient := &http.Client{
+		Timeout: timeout,
+	}
+	if token := event.Project.Secrets[TokenSecret]; token != "" {
+		// Installation tokens of GitHub Apps are used as passwords with this
+		// username; personal access tokens work with any username.
+		httpClient.Transport = &github.BasicAuthTransport{
+			Username: "x-access-token",
+			Password: token,
+		}
+	}
+	if baseURL == "" {
+		return github.NewClient(httpClient), nil
+	}
+	client, err := github.NewEnterpriseClient(baseURL, baseURL, httpClient)
+	return client, errors.Wrap(err, "error creating GitHub client")
+}
@@ -0,0 +1,54 @@
+package githubapi
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewClient(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/api/v3/zen" {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			username, password, ok := r.BasicAuth()
+			if !ok {
+				w.WriteHeader(http.StatusUnauthorized)
+				return
+			}
+			require.Equal(t, "x-access-token", username)
+			require.Equal(t, "foo", password)
+		}),
+	)
+	defer server.Close()
+
+	client, err := NewClient(brigade.Event{}, "")
+	require.NoError(t, err)
+	require.Equal(t, "https://api.github.com/", client.BaseURL.String())
+
+	client, err = NewClient(
+		brigade.Event{
+			Project: brigade.Project{
+				Secrets: map[string]string{TokenSecret: "foo"},
+			},
+		},
+		server.URL+"/",
+	)
+	require.NoError(t, err)
+	_, _, err = client.Zen(context.Background())
+	require.NoError(t, err)
+
+	client, err = NewClient(brigade.Event{}, server.URL+"/")
+	require.NoError(t, err)
+	_, _, err = client.Zen(context.Background())
+	require.Error(t, err)
+
+	_, err = NewClient(brigade.Event{}, ":")
+	require.Error(t, err)
+}
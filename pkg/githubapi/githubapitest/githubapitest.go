@@ -0,0 +1,60 @@
+package githubapitest
+
+import (
+	"context"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+// BranchChecker is a githubapi.BranchChecker for use in tests that knows which
+// commits are on which branches of a single repository.
+type BranchChecker struct {
+	// Branches maps branch names to the SHAs of the commits on them.
+	Branches map[string][]string
+	// Err, if non-nil, is returned by every call to IsOnBranch().
+	Err error
+}
+
+// IsOnBranch returns whether the commit having the provided SHA is one of those
+// listed for the provided branch.
+func (b *BranchChecker) IsOnBranch(
+	_ context.Context,
+	_ brigade.Event,
+	_ string,
+	branch string,
+	sha string,
+) (bool, error) {
+	if b.Err != nil {
+		return false, b.Err
+	}
+	for _, branchSHA := range b.Branches[branch] {
+		if branchSHA == sha {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// PullRequestGetter is a githubapi.PullRequestGetter for use in tests that
+// knows the pull requests of a single repository.
+type PullRequestGetter struct {
+	// PullRequests maps pull request numbers to pull requests.
+	PullRequests map[int]*github.PullRequest
+}
+
+// GetPullRequest returns the pull request having the provided number, or an
+// error if there is none.
+func (p *PullRequestGetter) GetPullRequest(
+	_ context.Context,
+	_ brigade.Event,
+	_ string,
+	number int,
+) (*github.PullRequest, error) {
+	pr, ok := p.PullRequests[number]
+	if !ok {
+		return nil, errors.Errorf("pull request #%d not found", number)
+	}
+	return pr, nil
+}
@@ -0,0 +1,161 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+)
+
+// Head describes the head commit of the pull request or check suite that an
+// event pertains to. Since determining whether it can be trusted may require
+// use of the GitHub API, this is done just once per event, by HeadOf().
+type Head struct {
+	// Trusted indicates whether the head commit is known to belong to the
+	// repository the event pertains to. It is true for events that have no head
+	// commit, such as pushes, and false for the zero value.
+	Trusted bool
+	// SHA is the head commit's SHA, if known.
+	SHA string
+	// Fork is the full name of the repository an untrusted head commit comes
+	// from, if known.
+	Fork string
+	// BaseRepo is the full name of the repository the event pertains to.
+	BaseRepo string
+	// BaseRevision is the base commit of a pull request, if known, or its base
+	// branch. For check suites, it is the repository's default branch.
+	BaseRevision string
+}
+
+// String describes where the head commit comes from.
+func (h Head) String() string {
+	if h.Fork != "" {
+		return "fork " + h.Fork
+	}
+	if h.SHA != "" {
+		return "commit " + h.SHA
+	}
+	return fmt.Sprintf("an unknown repository other than %s", h.BaseRepo)
+}
+
+// HeadOf returns the head of the pull request or check suite that the provided
+// event pertains to. The provided BranchChecker, if non-nil, is used to check
+// whether the head commits of check suites are on the repository's branches
+// (see IsCheckSuiteTrusted()). The provided PullRequestGetter, if non-nil, is
+// used to retrieve the pull requests that comments are on. Errors are logged
+// and treated as the head commit not being trusted.
+func HeadOf(
+	ctx context.Context,
+	event brigade.Event,
+	checker BranchChecker,
+	getter PullRequestGetter,
+) Head {
+	switch {
+	case strings.HasPrefix(event.Type, "pull_request"):
+		pre := github.PullRequestEvent{}
+		if err := json.Unmarshal([]byte(event.Payload), &pre); err != nil {
+			log.Printf("error reading pull request: %s", err)
+		}
+		return pullRequestHeadOf(pre.GetPullRequest())
+	case IsCheckSuiteEvent(event):
+		return checkSuiteHeadOf(ctx, event, checker)
+	case strings.HasPrefix(event.Type, "issue_comment:"):
+		return issueCommentHeadOf(ctx, event, getter)
+	default:
+		return Head{Trusted: true}
+	}
+}
+
+// pullRequestHeadOf returns the head of the provided pull request, which is
+// trusted unless the pull request may originate from a fork (see IsFork()).
+func pullRequestHeadOf(pr *github.PullRequest) Head {
+	head := Head{
+		Trusted:      !IsFork(pr),
+		SHA:          pr.GetHead().GetSHA(),
+		BaseRepo:     pr.GetBase().GetRepo().GetFullName(),
+		BaseRevision: pr.GetBase().GetSHA(),
+	}
+	if !head.Trusted {
+		head.Fork = pr.GetHead().GetRepo().GetFullName()
+	}
+	if head.BaseRevision == "" {
+		head.BaseRevision = pr.GetBase().GetRef()
+	}
+	return head
+}
+
+// checkSuiteHeadOf returns the head of the check suite that the provided event
+// pertains to, which may be for a pull request from a fork and so has the
+// repository's default branch as its base.
+func checkSuiteHeadOf(
+	ctx context.Context,
+	event brigade.Event,
+	checker BranchChecker,
+) Head {
+	checkSuite, repo, err := CheckSuiteOf(event)
+	if err != nil {
+		log.Printf("error reading check suite: %s", err)
+	}
+	trusted, err := IsCheckSuiteTrusted(ctx, event, checker)
+	if err != nil {
+		log.Printf(
+			"error determining whether head commit of check suite belongs to "+
+				"the repository; assuming it does not: %s",
+			err,
+		)
+	}
+	return Head{
+		Trusted:      trusted && err == nil,
+		SHA:          checkSuite.GetHeadSHA(),
+		BaseRepo:     repo.GetFullName(),
+		BaseRevision: repo.GetDefaultBranch(),
+	}
+}
+
+// issueCommentHeadOf returns the head of the pull request, if any, that the
+// comment the provided event pertains to is on. Comments on pull requests don't
+// describe their heads, so the pull request is retrieved using the provided
+// PullRequestGetter. If that isn't possible, the repository's default branch
+// is treated as the base.
+func issueCommentHeadOf(
+	ctx context.Context,
+	event brigade.Event,
+	getter PullRequestGetter,
+) Head {
+	ice := github.IssueCommentEvent{}
+	if err := json.Unmarshal([]byte(event.Payload), &ice); err != nil {
+		log.Printf("error reading issue comment: %s", err)
+	}
+	if ice.GetIssue().GetPullRequestLinks() == nil {
+		// Comments on issues, which have no heads, are trusted. Payloads that
+		// can't be read, which might be on pull requests, aren't.
+		return Head{Trusted: ice.GetIssue() != nil}
+	}
+	repo := ice.GetRepo().GetFullName()
+	untrusted := Head{
+		BaseRepo:     repo,
+		BaseRevision: ice.GetRepo().GetDefaultBranch(),
+	}
+	if getter == nil {
+		return untrusted
+	}
+	pr, err := getter.GetPullRequest(
+		ctx,
+		event,
+		repo,
+		ice.GetIssue().GetNumber(),
+	)
+	if err != nil {
+		log.Printf(
+			"error determining head of pull request commented on; assuming it "+
+				"comes from a fork: %s",
+			err,
+		)
+		return untrusted
+	}
+	return pullRequestHeadOf(pr)
+}
@@ -0,0 +1,228 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/lovethedrake/canard/pkg/githubapi/githubapitest"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHeadOf(t *testing.T) {
+	checker := &githubapitest.BranchChecker{
+		Branches: map[string][]string{"master": {"def456"}},
+	}
+	// nolint: lll
+	getter := &githubapitest.PullRequestGetter{
+		PullRequests: map[int]*github.PullRequest{
+			1: testPullRequest(t, `{"head":{"sha":"def456","repo":{"full_name":"lovethedrake/canard"}},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}`),
+			2: testPullRequest(t, `{"head":{"sha":"bad666","repo":{"full_name":"mallory/canard"}},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}`),
+		},
+	}
+	// nolint: lll
+	testCases := []struct {
+		name    string
+		event   brigade.Event
+		checker BranchChecker
+		getter  PullRequestGetter
+		head    Head
+	}{
+		{
+			name: "push",
+			event: brigade.Event{
+				Type:    "push",
+				Payload: `{"ref":"refs/heads/master"}`,
+			},
+			head: Head{Trusted: true},
+		},
+		{
+			name: "pull request from same repository",
+			event: brigade.Event{
+				Type:    "pull_request:opened",
+				Payload: `{"pull_request":{"head":{"sha":"def456","repo":{"full_name":"lovethedrake/canard"}},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}}`,
+			},
+			head: Head{
+				Trusted:      true,
+				SHA:          "def456",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "abc123",
+			},
+		},
+		{
+			name: "pull request from fork",
+			event: brigade.Event{
+				Type:    "pull_request:opened",
+				Payload: `{"pull_request":{"head":{"sha":"bad666","repo":{"full_name":"mallory/canard"}},"base":{"ref":"master","repo":{"full_name":"lovethedrake/canard"}}}}`,
+			},
+			head: Head{
+				SHA:          "bad666",
+				Fork:         "mallory/canard",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "master",
+			},
+		},
+		{
+			name: "pull request from deleted fork",
+			event: brigade.Event{
+				Type:    "pull_request:opened",
+				Payload: `{"pull_request":{"head":{"sha":"bad666","repo":null},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}}`,
+			},
+			head: Head{
+				SHA:          "bad666",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "abc123",
+			},
+		},
+		{
+			name: "review of pull request from fork",
+			event: brigade.Event{
+				Type:    "pull_request_review:submitted",
+				Payload: `{"pull_request":{"head":{"sha":"bad666","repo":{"full_name":"mallory/canard"}},"base":{"ref":"master","sha":"abc123","repo":{"full_name":"lovethedrake/canard"}}}}`,
+			},
+			head: Head{
+				SHA:          "bad666",
+				Fork:         "mallory/canard",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "abc123",
+			},
+		},
+		{
+			name: "check suite for commit on branch",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"master","head_sha":"def456","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			checker: checker,
+			head: Head{
+				Trusted:      true,
+				SHA:          "def456",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "main",
+			},
+		},
+		{
+			name: "check suite from fork with head branch master",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"master","head_sha":"bad666","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			checker: checker,
+			head: Head{
+				SHA:          "bad666",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "main",
+			},
+		},
+		{
+			name: "check suite when checking fails",
+			event: brigade.Event{
+				Type:    "check_suite:requested",
+				Payload: `{"check_suite":{"head_branch":"master","head_sha":"def456","pull_requests":[]},"repository":{"id":1,"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			checker: &githubapitest.BranchChecker{Err: errors.New("not authorized")},
+			head: Head{
+				SHA:          "def456",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "main",
+			},
+		},
+		{
+			name: "comment on issue",
+			event: brigade.Event{
+				Type:    "issue_comment:created",
+				Payload: `{"issue":{"number":3},"repository":{"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			getter: getter,
+			head:   Head{Trusted: true},
+		},
+		{
+			name: "comment on pull request from same repository",
+			event: brigade.Event{
+				Type:    "issue_comment:created",
+				Payload: `{"issue":{"number":1,"pull_request":{}},"repository":{"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			getter: getter,
+			head: Head{
+				Trusted:      true,
+				SHA:          "def456",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "abc123",
+			},
+		},
+		{
+			name: "comment on pull request from fork",
+			event: brigade.Event{
+				Type:    "issue_comment:created",
+				Payload: `{"issue":{"number":2,"pull_request":{}},"repository":{"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			getter: getter,
+			head: Head{
+				SHA:          "bad666",
+				Fork:         "mallory/canard",
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "abc123",
+			},
+		},
+		{
+			name: "comment on pull request that can't be retrieved",
+			event: brigade.Event{
+				Type:    "issue_comment:created",
+				Payload: `{"issue":{"number":4,"pull_request":{}},"repository":{"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			getter: getter,
+			head: Head{
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "main",
+			},
+		},
+		{
+			name: "comment on pull request without getter",
+			event: brigade.Event{
+				Type:    "issue_comment:created",
+				Payload: `{"issue":{"number":1,"pull_request":{}},"repository":{"full_name":"lovethedrake/canard","default_branch":"main"}}`,
+			},
+			head: Head{
+				BaseRepo:     "lovethedrake/canard",
+				BaseRevision: "main",
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			require.Equal(
+				t,
+				testCase.head,
+				HeadOf(
+					context.Background(),
+					testCase.event,
+					testCase.checker,
+					testCase.getter,
+				),
+			)
+		})
+	}
+}
+
+func testPullRequest(t *testing.T, prJSON string) *github.PullRequest {
+	pr := &github.PullRequest{}
+	require.NoError(t, json.Unmarshal([]byte(prJSON), pr))
+	return pr
+}
+
+func TestHeadString(t *testing.T) {
+	require.Equal(
+		t,
+		"fork mallory/canard",
+		Head{Fork: "mallory/canard", SHA: "bad666"}.String(),
+	)
+	require.Equal(t, "commit bad666", Head{SHA: "bad666"}.String())
+	require.Equal(
+		t,
+		"an unknown repository other than lovethedrake/canard",
+		Head{BaseRepo: "lovethedrake/canard"}.String(),
+	)
+}
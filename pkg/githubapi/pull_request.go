@@ -0,0 +1,71 @@
+package githubapi
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/pkg/errors"
+)
+
+// PullRequestGetter retrieves pull requests.
+type PullRequestGetter interface {
+	// GetPullRequest returns the pull request having the provided number in the
+	// specified repository. The repository is identified by its full name (e.g.
+	// owner/name).
+	GetPullRequest(
+		ctx context.Context,
+		event brigade.Event,
+		repo string,
+		number int,
+	) (*github.PullRequest, error)
+}
+
+// gitHubPullRequestGetter is a PullRequestGetter that uses the GitHub API.
+type gitHubPullRequestGetter struct {
+	// baseURL, if non-empty, overrides the GitHub API's default base URL
+	baseURL string
+}
+
+// NewGitHubPullRequestGetter returns a PullRequestGetter that retrieves pull
+// requests using the GitHub API. See NewClient() for how it authenticates.
+func NewGitHubPullRequestGetter() PullRequestGetter {
+	return &gitHubPullRequestGetter{}
+}
+
+func (g *gitHubPullRequestGetter) GetPullRequest(
+	ctx context.Context,
+	event brigade.Event,
+	repo string,
+	number int,
+) (*github.PullRequest, error) {
+	repoParts := strings.SplitN(repo, "/", 2)
+	if len(repoParts) != 2 {
+		return nil, errors.Errorf("%q is not a valid repository name", repo)
+	}
+	client, err := NewClient(event, g.baseURL)
+	if err != nil {
+		return nil, err
+	}
+	pr, _, err := client.PullRequests.Get(
+		ctx,
+		repoParts[0],
+		repoParts[1],
+		number,
+	)
+	return pr, errors.Wrapf(
+		err,
+		"error retrieving pull request #%d of %s",
+		number,
+		repo,
+	)
+}
+
+// IsFork returns true unless the head and base of the provided pull request
+// are known to be the same repository. The head repository of a pull request
+// is unknown if, for instance, the fork it originates from has been deleted.
+func IsFork(pr *github.PullRequest) bool {
+	headRepo := pr.GetHead().GetRepo().GetFullName()
+	return headRepo == "" || headRepo != pr.GetBase().GetRepo().GetFullName()
+}
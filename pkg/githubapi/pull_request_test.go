@@ -0,0 +1,92 @@
+package githubapi
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/google/go-github/v33/github"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestIsFork(t *testing.T) {
+	// nolint: lll
+	testCases := []struct {
+		name    string
+		payload string
+		fork    bool
+	}{
+		{
+			name:    "pull request from same repository",
+			payload: `{"head":{"repo":{"full_name":"lovethedrake/canard"}},"base":{"repo":{"full_name":"lovethedrake/canard"}}}`,
+		},
+		{
+			name:    "pull request from fork",
+			payload: `{"head":{"repo":{"full_name":"mallory/canard"}},"base":{"repo":{"full_name":"lovethedrake/canard"}}}`,
+			fork:    true,
+		},
+		{
+			name:    "pull request from deleted fork",
+			payload: `{"head":{"repo":null},"base":{"repo":{"full_name":"lovethedrake/canard"}}}`,
+			fork:    true,
+		},
+		{
+			name:    "pull request without base repository",
+			payload: `{"head":{"repo":{"full_name":"lovethedrake/canard"}},"base":{}}`,
+			fork:    true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			pr := &github.PullRequest{}
+			require.NoError(t, json.Unmarshal([]byte(testCase.payload), pr))
+			require.Equal(t, testCase.fork, IsFork(pr))
+		})
+	}
+}
+
+func TestGitHubPullRequestGetter(t *testing.T) {
+	server := httptest.NewServer(
+		http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			switch r.URL.Path {
+			case "/api/v3/repos/lovethedrake/canard/pulls/42":
+				fmt.Fprint(w, `{"number":42,"head":{"sha":"bad666"}}`)
+			default:
+				w.WriteHeader(http.StatusNotFound)
+			}
+		}),
+	)
+	defer server.Close()
+	getter := &gitHubPullRequestGetter{baseURL: server.URL + "/"}
+
+	pr, err := getter.GetPullRequest(
+		context.Background(),
+		brigade.Event{},
+		"lovethedrake/canard",
+		42,
+	)
+	require.NoError(t, err)
+	require.Equal(t, "bad666", pr.GetHead().GetSHA())
+
+	_, err = getter.GetPullRequest(
+		context.Background(),
+		brigade.Event{},
+		"lovethedrake/canard",
+		43,
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "error retrieving pull request #43")
+
+	_, err = getter.GetPullRequest(
+		context.Background(),
+		brigade.Event{},
+		"canard",
+		42,
+	)
+	require.Error(t, err)
+	require.Contains(t, err.Error(), "is not a valid repository name")
+}
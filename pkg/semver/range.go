@@ -0,0 +1,307 @@
+package semver
+
+import (
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// operators enumerates comparison operators in an order such that no operator
+// is a prefix of any that follows it.
+var operators = []string{">=", "<=", "!=", ">", "<", "=", "^", "~"}
+
+// Range represents a set of semantic versions. See ParseRange() for syntax.
+type Range struct {
+	str string
+	// sets are alternatives. A version is within the range if it satisfies
+	// every comparator in any one of them.
+	sets [][]comparator
+}
+
+type comparator struct {
+	operator string
+	version  Version
+}
+
+// ParseRange parses the provided string as a range of semantic versions. A
+// range consists of one or more sets of comparators separated by "||". A
+// version is within the range if it satisfies every comparator in any one of
+// the sets. Comparators within a set are separated by whitespace or commas and
+// each consists of an operator followed by a version. Operators are:
+//
+//	=, !=, >, >=, <, <=  Compare precedence. = may be omitted.
+//	^                    Permits changes that don't modify the left-most
+//	                     non-zero component. ^1.2.3 is >=1.2.3 <2.0.0 and
+//	                     ^0.2.3 is >=0.2.3 <0.3.0.
+//	~                    Permits patch-level changes. ~1.2.3 is >=1.2.3
+//	                     <1.3.0.
+//
+// Versions may have a leading "v" and, in comparators, may omit the minor and
+// patch components. Such a partial version stands for every version it is a
+// prefix of, so 1 and =1 are >=1.0.0 <2.0.0, 1.2 is >=1.2.0 <1.3.0, >1 is
+// >=2.0.0, <=1 is <2.0.0, ~1 is >=1.0.0 <2.0.0, ^0 is >=0.0.0 <1.0.0, and ^0.0
+// is >=0.0.0 <0.1.0. Omitted components are otherwise assumed to be zero (e.g.
+// >=1 is >=1.0.0). Partial versions cannot be used with !=. A range of "*"
+// contains every version. Prerelease versions receive no special treatment;
+// they are within the range if their precedence allows.
+func ParseRange(str string) (Range, error) {
+	r := Range{str: str}
+	for _, setStr := range strings.Split(str, "||") {
+		set, err := parseComparators(setStr)
+		if err != nil {
+			return Range{}, errors.Wrapf(err, "error parsing version range %q", str)
+		}
+		r.sets = append(r.sets, set)
+	}
+	return r, nil
+}
+
+func parseComparators(str string) ([]comparator, error) {
+	tokens := strings.FieldsFunc(str, func(r rune) bool {
+		return r == ' ' || r == '\t' || r == ','
+	})
+	if len(tokens) == 0 {
+		return nil, errors.New("empty set of comparators")
+	}
+	comparators := []comparator{}
+	for i := 0; i < len(tokens); i++ {
+		token := tokens[i]
+		if token == "*" {
+			continue
+		}
+		operator := ""
+		for _, op := range operators {
+			if strings.HasPrefix(token, op) {
+				operator = op
+				break
+			}
+		}
+		versionStr := strings.TrimPrefix(token, operator)
+		// Permit whitespace between an operator and its version
+		if versionStr == "" && i+1 < len(tokens) {
+			i++
+			versionStr = tokens[i]
+		}
+		version, components, err := parseVersion(versionStr, true)
+		if err != nil {
+			return nil, errors.Wrapf(err, "error parsing comparator %q", token)
+		}
+		partial := components < 3
+		switch {
+		case operator == "^":
+			upper := Version{Major: version.Major + 1}
+			if version.Major == 0 && (version.Minor > 0 || components == 2) {
+				upper = Version{Minor: version.Minor + 1}
+			} else if version.Major == 0 && components == 3 {
+				upper = Version{Patch: version.Patch + 1}
+			}
+			comparators = append(
+				comparators,
+				comparator{operator: ">=", version: version},
+				comparator{operator: "<", version: upper},
+			)
+		case operator == "~":
+			upper := Version{Major: version.Major, Minor: version.Minor + 1}
+			if components == 1 {
+				upper = Version{Major: version.Major + 1}
+			}
+			comparators = append(
+				comparators,
+				comparator{operator: ">=", version: version},
+				comparator{operator: "<", version: upper},
+			)
+		case (operator == "" || operator == "=") && partial:
+			comparators = append(
+				comparators,
+				comparator{operator: ">=", version: version},
+				comparator{operator: "<", version: successor(version, components)},
+			)
+		case operator == "":
+			comparators = append(
+				comparators,
+				comparator{operator: "=", version: version},
+			)
+		case operator == ">" && partial:
+			comparators = append(
+				comparators,
+				comparator{operator: ">=", version: successor(version, components)},
+			)
+		case operator == "<=" && partial:
+			comparators = append(
+				comparators,
+				comparator{operator: "<", version: successor(version, components)},
+			)
+		case operator == "!=" && partial:
+			return nil, errors.Errorf(
+				"comparator %q requires a version having major, minor, and patch "+
+					"components",
+				token,
+			)
+		default:
+			comparators = append(
+				comparators,
+				comparator{operator: operator, version: version},
+			)
+		}
+	}
+	return comparators, nil
+}
+
+// successor returns the lowest version that is not among those that the
+// provided partial version, having the provided number of components, stands
+// for. Prereleases are disregarded.
+func successor(v Version, components int) Version {
+	if components == 1 {
+		return Version{Major: v.Major + 1}
+	}
+	return Version{Major: v.Major, Minor: v.Minor + 1}
+}
+
+// Contains returns true if the provided version is within the range.
+func (r Range) Contains(v Version) bool {
+	for _, set := range r.sets {
+		if satisfiesAll(v, set) {
+			return true
+		}
+	}
+	return false
+}
+
+func satisfiesAll(v Version, comparators []comparator) bool {
+	for _, c := range comparators {
+		if !c.satisfiedBy(v) {
+			return false
+		}
+	}
+	return true
+}
+
+func (c comparator) satisfiedBy(v Version) bool {
+	cmp := v.Compare(c.version)
+	switch c.operator {
+	case "=":
+		return cmp == 0
+	case "!=":
+		return cmp != 0
+	case ">":
+		return cmp > 0
+	case ">=":
+		return cmp >= 0
+	case "<":
+		return cmp < 0
+	case "<=":
+		return cmp <= 0
+	}
+	return false
+}
+
+// Narrower returns true if, around the provided version, the range is
+// narrower than the other range. Around a version, a range is bounded by the
+// first of its sets of comparators that the version satisfies. The range whose
+// lower bound is greater is narrower or, if their lower bounds are the same,
+// the range whose upper bound is lesser is. An exclusive bound (i.e. > or <)
+// is tighter than an inclusive one (i.e. >=, <=, or =) of the same version.
+func (r Range) Narrower(other Range, v Version) bool {
+	lower, upper := r.boundsAround(v)
+	otherLower, otherUpper := other.boundsAround(v)
+	if c := compareLowerBounds(lower, otherLower); c != 0 {
+		return c > 0
+	}
+	return compareUpperBounds(upper, otherUpper) < 0
+}
+
+// bound is the lower or upper bound of a set of comparators.
+type bound struct {
+	version Version
+	// exclusive is true if the version itself does not satisfy the set.
+	exclusive bool
+}
+
+// boundsAround returns the lower and upper bounds of the first set of
+// comparators in the range that the provided version satisfies. A nil bound
+// is unbounded. If the version satisfies none of the sets, both bounds are
+// nil.
+func (r Range) boundsAround(v Version) (*bound, *bound) {
+	for _, set := range r.sets {
+		if !satisfiesAll(v, set) {
+			continue
+		}
+		var lower, upper *bound
+		for _, c := range set {
+			b := &bound{
+				version:   c.version,
+				exclusive: c.operator == ">" || c.operator == "<",
+			}
+			switch c.operator {
+			case "=":
+				if compareLowerBounds(b, lower) > 0 {
+					lower = b
+				}
+				if compareUpperBounds(b, upper) < 0 {
+					upper = b
+				}
+			case ">", ">=":
+				if compareLowerBounds(b, lower) > 0 {
+					lower = b
+				}
+			case "<", "<=":
+				if compareUpperBounds(b, upper) < 0 {
+					upper = b
+				}
+			}
+		}
+		return lower, upper
+	}
+	return nil, nil
+}
+
+// compareLowerBounds returns -1, 0, or 1 if lower bound a is less than, equal
+// to, or greater than lower bound b, respectively. A nil bound is least.
+func compareLowerBounds(a, b *bound) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return -1
+	case b == nil:
+		return 1
+	}
+	if c := a.version.Compare(b.version); c != 0 {
+		return c
+	}
+	return compareExclusivity(a, b)
+}
+
+// compareUpperBounds returns -1, 0, or 1 if upper bound a is less than, equal
+// to, or greater than upper bound b, respectively. A nil bound is greatest.
+func compareUpperBounds(a, b *bound) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	if c := a.version.Compare(b.version); c != 0 {
+		return c
+	}
+	return -compareExclusivity(a, b)
+}
+
+// compareExclusivity returns 1 if only bound a is exclusive, -1 if only bound b
+// is, and 0 otherwise.
+func compareExclusivity(a, b *bound) int {
+	switch {
+	case a.exclusive == b.exclusive:
+		return 0
+	case a.exclusive:
+		return 1
+	default:
+		return -1
+	}
+}
+
+func (r Range) String() string {
+	return r.str
+}
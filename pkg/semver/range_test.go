@@ -0,0 +1,250 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestRange(t *testing.T) {
+	testCases := []struct {
+		rangeStr string
+		contains []string
+		excludes []string
+	}{
+		{
+			rangeStr: "*",
+			contains: []string{"0.0.1", "1.0.0", "99.0.0-rc.1"},
+		},
+		{
+			rangeStr: "1.2.3",
+			contains: []string{"1.2.3", "v1.2.3"},
+			excludes: []string{"1.2.4", "1.2.3-rc.1"},
+		},
+		{
+			rangeStr: ">=1.0.0 <2.0.0",
+			contains: []string{"1.0.0", "1.9.9", "2.0.0-rc.1"},
+			excludes: []string{"0.9.0", "1.0.0-rc.1", "2.0.0"},
+		},
+		{
+			rangeStr: ">= v1, < v2",
+			contains: []string{"1.0.0", "1.9.9"},
+			excludes: []string{"0.9.0", "2.0.0"},
+		},
+		{
+			rangeStr: "^1.2.3",
+			contains: []string{"1.2.3", "1.9.0"},
+			excludes: []string{"1.2.2", "2.0.0"},
+		},
+		{
+			rangeStr: "^0.2.3",
+			contains: []string{"0.2.3", "0.2.9"},
+			excludes: []string{"0.3.0"},
+		},
+		{
+			rangeStr: "^0.0.3",
+			contains: []string{"0.0.3"},
+			excludes: []string{"0.0.4"},
+		},
+		{
+			rangeStr: "~1.2.3",
+			contains: []string{"1.2.3", "1.2.9"},
+			excludes: []string{"1.3.0"},
+		},
+		{
+			rangeStr: "<1.0.0 || >=2.0.0 !=2.1.0",
+			contains: []string{"0.1.0", "2.0.0", "2.2.0"},
+			excludes: []string{"1.0.0", "2.1.0"},
+		},
+		{
+			rangeStr: ">1.0.0 <=1.1.0",
+			contains: []string{"1.0.1", "1.1.0"},
+			excludes: []string{"1.0.0", "1.1.1"},
+		},
+		{
+			rangeStr: "1",
+			contains: []string{"1.0.0", "1.9.9"},
+			excludes: []string{"0.9.9", "2.0.0"},
+		},
+		{
+			rangeStr: "=v1.2",
+			contains: []string{"1.2.0", "1.2.9"},
+			excludes: []string{"1.1.9", "1.3.0"},
+		},
+		{
+			rangeStr: ">1",
+			contains: []string{"2.0.0"},
+			excludes: []string{"1.0.0", "1.9.9"},
+		},
+		{
+			rangeStr: ">1.2",
+			contains: []string{"1.3.0"},
+			excludes: []string{"1.2.9"},
+		},
+		{
+			rangeStr: ">=1",
+			contains: []string{"1.0.0"},
+			excludes: []string{"0.9.9"},
+		},
+		{
+			rangeStr: "<1.2",
+			contains: []string{"1.1.9"},
+			excludes: []string{"1.2.0"},
+		},
+		{
+			rangeStr: "<=1",
+			contains: []string{"1.9.9"},
+			excludes: []string{"2.0.0"},
+		},
+		{
+			rangeStr: "<=1.2",
+			contains: []string{"1.2.9"},
+			excludes: []string{"1.3.0"},
+		},
+		{
+			rangeStr: "~1",
+			contains: []string{"1.0.0", "1.9.9"},
+			excludes: []string{"0.9.9", "2.0.0"},
+		},
+		{
+			rangeStr: "~1.2",
+			contains: []string{"1.2.0", "1.2.9"},
+			excludes: []string{"1.3.0"},
+		},
+		{
+			rangeStr: "^1",
+			contains: []string{"1.0.0", "1.9.9"},
+			excludes: []string{"2.0.0"},
+		},
+		{
+			rangeStr: "^0",
+			contains: []string{"0.0.0", "0.9.9"},
+			excludes: []string{"1.0.0"},
+		},
+		{
+			rangeStr: "^0.2",
+			contains: []string{"0.2.0", "0.2.9"},
+			excludes: []string{"0.3.0"},
+		},
+		{
+			rangeStr: "^0.0",
+			contains: []string{"0.0.0", "0.0.9"},
+			excludes: []string{"0.1.0"},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.rangeStr, func(t *testing.T) {
+			r, err := ParseRange(testCase.rangeStr)
+			require.NoError(t, err)
+			require.Equal(t, testCase.rangeStr, r.String())
+			for _, versionStr := range testCase.contains {
+				v, err := ParseVersion(versionStr)
+				require.NoError(t, err)
+				require.True(t, r.Contains(v), "%s should contain %s", r, v)
+			}
+			for _, versionStr := range testCase.excludes {
+				v, err := ParseVersion(versionStr)
+				require.NoError(t, err)
+				require.False(t, r.Contains(v), "%s should exclude %s", r, v)
+			}
+		})
+	}
+}
+
+func TestParseRangeErrors(t *testing.T) {
+	for _, rangeStr := range []string{
+		"",
+		">=1.0.0 ||",
+		">=foo",
+		"^1.x",
+		"!=1.2",
+	} {
+		t.Run(rangeStr, func(t *testing.T) {
+			_, err := ParseRange(rangeStr)
+			require.Error(t, err)
+			require.Contains(t, err.Error(), "error parsing version range")
+		})
+	}
+}
+
+func TestRangeNarrower(t *testing.T) {
+	testCases := []struct {
+		name     string
+		rangeStr string
+		otherStr string
+		version  string
+		narrower bool
+	}{
+		{
+			name:     "greater lower bound",
+			rangeStr: ">=1.5.0",
+			otherStr: "^1.0.0",
+			version:  "1.6.0",
+			narrower: true,
+		},
+		{
+			name:     "lesser lower bound",
+			rangeStr: "^1.0.0",
+			otherStr: ">=1.5.0",
+			version:  "1.6.0",
+		},
+		{
+			name:     "lesser upper bound",
+			rangeStr: ">=1.0.0 <1.5.0",
+			otherStr: "^1.0.0",
+			version:  "1.2.0",
+			narrower: true,
+		},
+		{
+			name:     "unbounded",
+			rangeStr: "*",
+			otherStr: "<2",
+			version:  "1.2.0",
+		},
+		{
+			name:     "exclusive lower bound",
+			rangeStr: ">1.0.0",
+			otherStr: ">=1.0.0",
+			version:  "1.2.0",
+			narrower: true,
+		},
+		{
+			name:     "exclusive upper bound",
+			rangeStr: "<1.5.0",
+			otherStr: "<=1.5.0",
+			version:  "1.2.0",
+			narrower: true,
+		},
+		{
+			name:     "exact version",
+			rangeStr: "1.2.0",
+			otherStr: ">=1.2.0 <=1.3.0",
+			version:  "1.2.0",
+			narrower: true,
+		},
+		{
+			name:     "equivalent ranges",
+			rangeStr: "^1.0.0",
+			otherStr: ">=1.0.0 <2.0.0",
+			version:  "1.2.0",
+		},
+		{
+			name:     "bounded by the set containing the version",
+			rangeStr: "<1.0.0 || >=1.1.0 <1.9.0",
+			otherStr: "^1.0.0",
+			version:  "1.2.0",
+			narrower: true,
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			r, err := ParseRange(testCase.rangeStr)
+			require.NoError(t, err)
+			other, err := ParseRange(testCase.otherStr)
+			require.NoError(t, err)
+			v, err := ParseVersion(testCase.version)
+			require.NoError(t, err)
+			require.Equal(t, testCase.narrower, r.Narrower(other, v))
+		})
+	}
+}
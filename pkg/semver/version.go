@@ -0,0 +1,190 @@
+package semver
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version represents a semantic version, as described by
+// https://semver.org/spec/v2.0.0.html.
+type Version struct {
+	Major uint64
+	Minor uint64
+	Patch uint64
+	// Prerelease contains the dot-separated identifiers that follow a hyphen,
+	// if any.
+	Prerelease []string
+	// Metadata is the build metadata that follows a plus sign, if any. It has no
+	// bearing upon precedence.
+	Metadata string
+}
+
+// ParseVersion parses the provided string as a semantic version. A leading "v",
+// as is common in git tags, is permitted and ignored.
+func ParseVersion(str string) (Version, error) {
+	v, _, err := parseVersion(str, false)
+	return v, errors.Wrapf(err, "error parsing version %q", str)
+}
+
+// parseVersion parses the provided string as a semantic version and returns it
+// along with the number of components (major, minor, and patch) that were
+// present. If partial is true, the minor and patch components may be omitted
+// and are assumed to be zero.
+func parseVersion(str string, partial bool) (Version, int, error) {
+	v := Version{}
+	str = strings.TrimPrefix(str, "v")
+	if i := strings.Index(str, "+"); i >= 0 {
+		v.Metadata = str[i+1:]
+		if err := validateIdentifiers(v.Metadata, false); err != nil {
+			return Version{}, 0, errors.Wrap(err, "invalid build metadata")
+		}
+		str = str[:i]
+	}
+	if i := strings.Index(str, "-"); i >= 0 {
+		prerelease := str[i+1:]
+		if err := validateIdentifiers(prerelease, true); err != nil {
+			return Version{}, 0, errors.Wrap(err, "invalid prerelease")
+		}
+		v.Prerelease = strings.Split(prerelease, ".")
+		str = str[:i]
+	}
+	parts := strings.Split(str, ".")
+	if len(parts) > 3 || (len(parts) < 3 && !partial) {
+		return Version{}, 0, errors.New(
+			"must consist of major, minor, and patch components",
+		)
+	}
+	components := []*uint64{&v.Major, &v.Minor, &v.Patch}
+	for i, part := range parts {
+		if !isNumeric(part) {
+			return Version{}, 0, errors.Errorf("component %q is not numeric", part)
+		}
+		if len(part) > 1 && part[0] == '0' {
+			return Version{}, 0, errors.Errorf(
+				"component %q has a leading zero",
+				part,
+			)
+		}
+		var err error
+		if *components[i], err = strconv.ParseUint(part, 10, 64); err != nil {
+			return Version{}, 0,
+				errors.Wrapf(err, "error parsing component %q", part)
+		}
+	}
+	return v, len(parts), nil
+}
+
+// validateIdentifiers checks that the provided string is a non-empty list of
+// dot-separated, non-empty, alphanumeric identifiers. If numeric is true,
+// numeric identifiers may not have leading zeros.
+func validateIdentifiers(str string, numeric bool) error {
+	for _, identifier := range strings.Split(str, ".") {
+		if identifier == "" {
+			return errors.New("contains an empty identifier")
+		}
+		for _, r := range identifier {
+			if !(r == '-' ||
+				(r >= '0' && r <= '9') ||
+				(r >= 'a' && r <= 'z') ||
+				(r >= 'A' && r <= 'Z')) {
+				return errors.Errorf("identifier %q is not alphanumeric", identifier)
+			}
+		}
+		if numeric && isNumeric(identifier) && len(identifier) > 1 &&
+			identifier[0] == '0' {
+			return errors.Errorf("identifier %q has a leading zero", identifier)
+		}
+	}
+	return nil
+}
+
+func isNumeric(str string) bool {
+	if str == "" {
+		return false
+	}
+	for _, r := range str {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// IsPrerelease returns true if the version is a prerelease.
+func (v Version) IsPrerelease() bool {
+	return len(v.Prerelease) > 0
+}
+
+// Compare returns -1, 0, or 1 if the version has lower, equal, or greater
+// precedence, respectively, than the provided version.
+func (v Version) Compare(other Version) int {
+	if c := compareUint(v.Major, other.Major); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Minor, other.Minor); c != 0 {
+		return c
+	}
+	if c := compareUint(v.Patch, other.Patch); c != 0 {
+		return c
+	}
+	// A version without a prerelease has greater precedence than one with
+	switch {
+	case !v.IsPrerelease() && !other.IsPrerelease():
+		return 0
+	case !v.IsPrerelease():
+		return 1
+	case !other.IsPrerelease():
+		return -1
+	}
+	for i := 0; i < len(v.Prerelease) && i < len(other.Prerelease); i++ {
+		if c := compareIdentifiers(v.Prerelease[i], other.Prerelease[i]); c != 0 {
+			return c
+		}
+	}
+	return compareUint(uint64(len(v.Prerelease)), uint64(len(other.Prerelease)))
+}
+
+// compareIdentifiers compares prerelease identifiers. Numeric identifiers are
+// compared numerically and have lower precedence than alphanumeric ones, which
+// are compared lexically.
+func compareIdentifiers(a, b string) int {
+	aNumeric, bNumeric := isNumeric(a), isNumeric(b)
+	switch {
+	case aNumeric && bNumeric:
+		if c := compareUint(uint64(len(a)), uint64(len(b))); c != 0 {
+			return c
+		}
+		return strings.Compare(a, b)
+	case aNumeric:
+		return -1
+	case bNumeric:
+		return 1
+	default:
+		return strings.Compare(a, b)
+	}
+}
+
+func compareUint(a, b uint64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	default:
+		return 0
+	}
+}
+
+func (v Version) String() string {
+	str := fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if v.IsPrerelease() {
+		str = fmt.Sprintf("%s-%s", str, strings.Join(v.Prerelease, "."))
+	}
+	if v.Metadata != "" {
+		str = fmt.Sprintf("%s+%s", str, v.Metadata)
+	}
+	return str
+}
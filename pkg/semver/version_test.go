@@ -0,0 +1,116 @@
+package semver
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseVersion(t *testing.T) {
+	testCases := []struct {
+		name       string
+		str        string
+		assertions func(*testing.T, Version, error)
+	}{
+		{
+			name: "release",
+			str:  "1.2.3",
+			assertions: func(t *testing.T, v Version, err error) {
+				require.NoError(t, err)
+				require.Equal(t, Version{Major: 1, Minor: 2, Patch: 3}, v)
+				require.False(t, v.IsPrerelease())
+			},
+		},
+		{
+			name: "v prefix, prerelease, and metadata",
+			str:  "v1.2.3-rc.1+build.5",
+			assertions: func(t *testing.T, v Version, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					Version{
+						Major:      1,
+						Minor:      2,
+						Patch:      3,
+						Prerelease: []string{"rc", "1"},
+						Metadata:   "build.5",
+					},
+					v,
+				)
+				require.True(t, v.IsPrerelease())
+				require.Equal(t, "1.2.3-rc.1+build.5", v.String())
+			},
+		},
+		{
+			name: "partial",
+			str:  "1.2",
+			assertions: func(t *testing.T, _ Version, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "major, minor, and patch")
+			},
+		},
+		{
+			name: "not numeric",
+			str:  "1.x.3",
+			assertions: func(t *testing.T, _ Version, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "not numeric")
+			},
+		},
+		{
+			name: "leading zero",
+			str:  "1.02.3",
+			assertions: func(t *testing.T, _ Version, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "leading zero")
+			},
+		},
+		{
+			name: "empty prerelease identifier",
+			str:  "1.2.3-rc..1",
+			assertions: func(t *testing.T, _ Version, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "empty identifier")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			v, err := ParseVersion(testCase.str)
+			testCase.assertions(t, v, err)
+		})
+	}
+}
+
+func TestCompare(t *testing.T) {
+	// In order of increasing precedence, per the example in the spec
+	versions := []string{
+		"1.0.0-alpha",
+		"1.0.0-alpha.1",
+		"1.0.0-alpha.beta",
+		"1.0.0-beta",
+		"1.0.0-beta.2",
+		"1.0.0-beta.11",
+		"1.0.0-rc.1",
+		"1.0.0",
+		"1.0.1",
+		"1.1.0",
+		"2.0.0",
+	}
+	for i := range versions {
+		a, err := ParseVersion(versions[i])
+		require.NoError(t, err)
+		require.Equal(t, 0, a.Compare(a))
+		for j := i + 1; j < len(versions); j++ {
+			b, err := ParseVersion(versions[j])
+			require.NoError(t, err)
+			require.Equal(t, -1, a.Compare(b), "%s < %s", versions[i], versions[j])
+			require.Equal(t, 1, b.Compare(a), "%s > %s", versions[j], versions[i])
+		}
+	}
+	a, err := ParseVersion("1.0.0+foo")
+	require.NoError(t, err)
+	b, err := ParseVersion("1.0.0+bar")
+	require.NoError(t, err)
+	require.Equal(t, 0, a.Compare(b))
+}
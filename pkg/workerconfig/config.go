@@ -0,0 +1,327 @@
+package workerconfig
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/ghodss/yaml"
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/pkg/errors"
+	"github.com/xeipuuv/gojsonschema"
+)
+
+// FileName is the name of the file from which worker configuration is loaded.
+const FileName = "canard.yaml"
+
+const (
+	// ReportingTargetLog is a reporting target that writes the build summary to
+	// the worker's log.
+	ReportingTargetLog = "log"
+	// ReportingTargetFile is a reporting target that writes the build summary,
+	// as JSON, to a file.
+	ReportingTargetFile = "file"
+)
+
+// Config represents per-project configuration for the worker.
+type Config struct {
+	// TLS contains settings for connections to the Brigade API server.
+	TLS TLSConfig `json:"tls"`
+	// Jobs contains settings for the execution of jobs.
+	Jobs JobsConfig `json:"jobs"`
+	// Drakefile contains settings for locating and pre-processing the
+	// Drakefile.
+	Drakefile DrakefileConfig `json:"drakefile"`
+	// Triggers contains settings for evaluating pipeline triggers.
+	Triggers TriggersConfig `json:"triggers"`
+	// Reporting contains settings for reporting a summary of the build.
+	Reporting ReportingConfig `json:"reporting"`
+	// Features contains toggles for optional features.
+	Features FeaturesConfig `json:"features"`
+}
+
+// TLSConfig represents settings for connections to the Brigade API server.
+type TLSConfig struct {
+	// AllowInsecureConnections indicates whether TLS errors should be ignored
+	// when connecting to the Brigade API server.
+	AllowInsecureConnections bool `json:"allowInsecureConnections"`
+}
+
+// JobsConfig represents settings for the execution of jobs.
+type JobsConfig struct {
+	// MaxConcurrency is the maximum number of jobs to execute concurrently,
+	// across all pipelines. Zero means there is no limit.
+	MaxConcurrency int `json:"maxConcurrency"`
+	// DefaultTimeout is how long to wait for a job that doesn't specify its own
+	// timeout to complete. Zero means there is no limit.
+	DefaultTimeout Duration `json:"defaultTimeout"`
+}
+
+// DrakefileConfig represents settings for locating and pre-processing the
+// Drakefile.
+type DrakefileConfig struct {
+	// FileNames, if non-empty, overrides the names of files that are recognized
+	// as Drakefiles.
+	FileNames []string `json:"fileNames"`
+	// TrustForkPullRequests indicates whether Drakefiles from the checkout may
+	// be used for pull requests originating from forks.
+	TrustForkPullRequests bool `json:"trustForkPullRequests"`
+	// TemplateEnv enumerates environment variables that are exposed to Drakefile
+	// templates.
+	TemplateEnv []string `json:"templateEnv"`
+	// Services, if non-empty, enumerates glob patterns, relative to the root of
+	// the checkout, matching directories that each contain a service's own
+	// Drakefile. Each such Drakefile is resolved and evaluated independently.
+	Services []string `json:"services"`
+}
+
+// TriggersConfig represents settings for evaluating pipeline triggers.
+type TriggersConfig struct {
+	// Plugins enumerates triggers implemented by executables in the worker
+	// image.
+	Plugins []TriggerPlugin `json:"plugins"`
+}
+
+// TriggerPlugin maps a trigger spec URI to an executable in the worker image
+// that implements it. Since plugins are executed by the worker itself, they
+// may only be specified by the project worker template's configuration and
+// must not be modifiable by the checkout. See Load().
+type TriggerPlugin struct {
+	// SpecURI identifies the trigger spec implemented by the plugin.
+	SpecURI string `json:"specUri"`
+	// SpecVersions is the range of versions of the trigger spec implemented by
+	// the plugin. If empty, all versions are implemented.
+	SpecVersions string `json:"specVersions,omitempty"`
+	// Path is the absolute path to the plugin.
+	Path string `json:"path"`
+	// Timeout is how long the plugin is given to reach a decision. Zero means
+	// the default timeout applies.
+	Timeout Duration `json:"timeout"`
+}
+
+// ReportingConfig represents settings for reporting a summary of the build.
+type ReportingConfig struct {
+	// Targets enumerates destinations to which the summary is reported.
+	Targets []ReportingTarget `json:"targets"`
+}
+
+// ReportingTarget represents a destination to which a summary of the build is
+// reported.
+type ReportingTarget struct {
+	// Type is the type of target. Valid values are "log" and "file".
+	Type string `json:"type"`
+	// Path is the path to which the summary is written, for targets of type
+	// "file".
+	Path string `json:"path,omitempty"`
+}
+
+// FeaturesConfig represents toggles for optional features.
+type FeaturesConfig struct {
+	// Includes indicates whether Drakefile includes are expanded.
+	Includes bool `json:"includes"`
+	// Templating indicates whether Drakefiles may opt into templating.
+	Templating bool `json:"templating"`
+	// BranchOverlays indicates whether branch overlays are applied.
+	BranchOverlays bool `json:"branchOverlays"`
+}
+
+// Duration is a time.Duration that is represented in JSON and YAML using
+// strings such as "90s" or "1h30m".
+type Duration struct {
+	time.Duration
+}
+
+// MarshalJSON implements json.Marshaler.
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+// UnmarshalJSON implements json.Unmarshaler.
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var str string
+	if err := json.Unmarshal(data, &str); err != nil {
+		return errors.Wrap(err, "duration must be a string")
+	}
+	var err error
+	d.Duration, err = time.ParseDuration(str)
+	return errors.Wrapf(err, "error parsing duration %q", str)
+}
+
+// Default returns the configuration used in the absence of a canard.yaml file.
+// Any field not specified in a canard.yaml file also retains its default
+// value.
+func Default() Config {
+	return Config{
+		TLS: TLSConfig{
+			AllowInsecureConnections: true,
+		},
+		Features: FeaturesConfig{
+			Includes:       true,
+			Templating:     true,
+			BranchOverlays: true,
+		},
+	}
+}
+
+// Load returns worker configuration for the provided event. If trustCheckout
+// is true, canard.yaml is first searched for in the worker's config files
+// directory within the checkout rooted at vcsRoot. Failing that, canard.yaml
+// from the project worker template's default config files is used. If
+// neither exists, Default() is returned. Trigger plugins are only ever taken
+// from the project worker template; an error is returned if canard.yaml in the
+// checkout specifies any, or if any plugin's path is within vcsRoot or is
+// writable by anyone.
+func Load(
+	event brigade.Event,
+	vcsRoot string,
+	trustCheckout bool,
+) (Config, error) {
+	cfg, err := load(event, vcsRoot, trustCheckout)
+	if err != nil {
+		return Config{}, err
+	}
+	if err = checkTriggerPlugins(cfg.Triggers.Plugins, vcsRoot); err != nil {
+		return Config{}, err
+	}
+	return cfg, nil
+}
+
+func load(
+	event brigade.Event,
+	vcsRoot string,
+	trustCheckout bool,
+) (Config, error) {
+	projectCfg, ok, err := loadFromProject(event)
+	if err != nil {
+		return Config{}, err
+	}
+	if trustCheckout {
+		configPath := filepath.Join(
+			vcsRoot,
+			event.Worker.ConfigFilesDirectory,
+			FileName,
+		)
+		configBytes, err := ioutil.ReadFile(configPath)
+		if err == nil {
+			log.Printf("loading worker configuration from %s", configPath)
+			cfg, err := NewConfigFromYAML(configBytes)
+			if err != nil {
+				return Config{}, errors.Wrapf(err, "error loading %s", configPath)
+			}
+			// Plugins are executed by the worker itself, so the checkout mustn't
+			// be able to choose them
+			if len(cfg.Triggers.Plugins) > 0 {
+				return Config{}, errors.Errorf(
+					"error loading %s: trigger plugins may only be specified by the "+
+						"project worker template",
+					configPath,
+				)
+			}
+			cfg.Triggers.Plugins = projectCfg.Triggers.Plugins
+			return cfg, nil
+		}
+		if !os.IsNotExist(err) {
+			return Config{}, errors.Wrapf(err, "error reading %s", configPath)
+		}
+	}
+	if ok {
+		log.Printf(
+			"loading worker configuration from project worker template",
+		)
+		return projectCfg, nil
+	}
+	log.Printf("no %s found; using default worker configuration", FileName)
+	return Default(), nil
+}
+
+// loadFromProject returns the configuration from the project worker template's
+// default config files, if it has any, and whether it does.
+func loadFromProject(event brigade.Event) (Config, bool, error) {
+	configStr, ok := event.Worker.DefaultConfigFiles[FileName]
+	if !ok {
+		return Default(), false, nil
+	}
+	cfg, err := NewConfigFromYAML([]byte(configStr))
+	return cfg, true, errors.Wrapf(
+		err,
+		"error loading %s from project worker template",
+		FileName,
+	)
+}
+
+// checkTriggerPlugins returns an error if the path of any of the provided
+// plugins, once symbolic links are resolved, is within vcsRoot or is writable
+// by anyone. Either would permit the plugin to be replaced by something other
+// than what the project intended.
+func checkTriggerPlugins(plugins []TriggerPlugin, vcsRoot string) error {
+	if len(plugins) == 0 {
+		return nil
+	}
+	root, err := filepath.EvalSymlinks(vcsRoot)
+	if os.IsNotExist(err) {
+		root = filepath.Clean(vcsRoot)
+	} else if err != nil {
+		return errors.Wrapf(err, "error resolving %s", vcsRoot)
+	}
+	for _, plugin := range plugins {
+		path, err := filepath.EvalSymlinks(plugin.Path)
+		if err != nil {
+			return errors.Wrapf(err, "error resolving trigger plugin %s", plugin.Path)
+		}
+		if rel, err := filepath.Rel(root, path); err == nil &&
+			rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return errors.Errorf(
+				"trigger plugin %s is within the checkout at %s",
+				plugin.Path,
+				vcsRoot,
+			)
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return errors.Wrapf(err, "error reading trigger plugin %s", plugin.Path)
+		}
+		if info.Mode().Perm()&0002 != 0 {
+			return errors.Errorf(
+				"trigger plugin %s is writable by anyone",
+				plugin.Path,
+			)
+		}
+	}
+	return nil
+}
+
+// NewConfigFromYAML validates the provided YAML and returns worker
+// configuration derived from it. Any field not specified retains its default
+// value.
+func NewConfigFromYAML(yamlBytes []byte) (Config, error) {
+	jsonBytes, err := yaml.YAMLToJSON(yamlBytes)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "error converting YAML to JSON")
+	}
+	// An empty file is valid and is equivalent to the default configuration
+	if string(jsonBytes) == "null" {
+		return Default(), nil
+	}
+	validationResult, err := gojsonschema.Validate(
+		gojsonschema.NewBytesLoader(jsonSchemaBytes),
+		gojsonschema.NewBytesLoader(jsonBytes),
+	)
+	if err != nil {
+		return Config{}, errors.Wrap(err, "error validating configuration")
+	}
+	if !validationResult.Valid() {
+		msg := "configuration is invalid:"
+		for _, resErr := range validationResult.Errors() {
+			msg = fmt.Sprintf("%s\n- %s", msg, resErr)
+		}
+		return Config{}, errors.New(msg)
+	}
+	cfg := Default()
+	err = json.Unmarshal(jsonBytes, &cfg)
+	return cfg, errors.Wrap(err, "error unmarshaling configuration")
+}
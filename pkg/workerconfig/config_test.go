@@ -0,0 +1,352 @@
+package workerconfig
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/lovethedrake/canard/pkg/brigade"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNewConfigFromYAML(t *testing.T) {
+	testCases := []struct {
+		name       string
+		yaml       string
+		assertions func(*testing.T, Config, error)
+	}{
+		{
+			name: "empty",
+			yaml: "",
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, Default(), cfg)
+			},
+		},
+		{
+			name: "partial",
+			yaml: "jobs:\n  maxConcurrency: 2\n",
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, cfg.Jobs.MaxConcurrency)
+				// Everything else retains its default value
+				require.True(t, cfg.TLS.AllowInsecureConnections)
+				require.True(t, cfg.Features.Includes)
+			},
+		},
+		{
+			name: "complete",
+			yaml: `
+tls:
+  allowInsecureConnections: false
+jobs:
+  maxConcurrency: 3
+  defaultTimeout: 1h30m
+drakefile:
+  fileNames:
+  - build.yaml
+  trustForkPullRequests: true
+  templateEnv:
+  - FOO
+  services:
+  - services/*
+triggers:
+  plugins:
+  - specUri: example.com/drakespec-foo
+    specVersions: ^1.0.0
+    path: /usr/local/bin/foo
+    timeout: 5s
+reporting:
+  targets:
+  - type: log
+  - type: file
+    path: /tmp/summary.json
+features:
+  includes: false
+  templating: false
+  branchOverlays: false
+`,
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(
+					t,
+					Config{
+						Jobs: JobsConfig{
+							MaxConcurrency: 3,
+							DefaultTimeout: Duration{90 * time.Minute},
+						},
+						Drakefile: DrakefileConfig{
+							FileNames:             []string{"build.yaml"},
+							TrustForkPullRequests: true,
+							TemplateEnv:           []string{"FOO"},
+							Services:              []string{"services/*"},
+						},
+						Triggers: TriggersConfig{
+							Plugins: []TriggerPlugin{
+								{
+									SpecURI:      "example.com/drakespec-foo",
+									SpecVersions: "^1.0.0",
+									Path:         "/usr/local/bin/foo",
+									Timeout:      Duration{5 * time.Second},
+								},
+							},
+						},
+						Reporting: ReportingConfig{
+							Targets: []ReportingTarget{
+								{Type: ReportingTargetLog},
+								{Type: ReportingTargetFile, Path: "/tmp/summary.json"},
+							},
+						},
+					},
+					cfg,
+				)
+			},
+		},
+		{
+			name: "invalid",
+			yaml: `
+jobs:
+  maxConcurrency: -1
+  defaultTimeout: forever
+reporting:
+  targets:
+  - type: file
+triggers:
+  plugins:
+  - specUri: example.com/drakespec-foo
+    path: foo
+bogus: true
+`,
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "configuration is invalid")
+				// All problems are listed
+				require.Contains(t, err.Error(), "jobs.maxConcurrency")
+				require.Contains(t, err.Error(), "jobs.defaultTimeout")
+				require.Contains(t, err.Error(), "path is required")
+				require.Contains(t, err.Error(), "triggers.plugins.0.path")
+				require.Contains(t, err.Error(), "bogus")
+			},
+		},
+		{
+			name: "not YAML",
+			yaml: "tls: [",
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error converting YAML to JSON")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg, err := NewConfigFromYAML([]byte(testCase.yaml))
+			testCase.assertions(t, cfg, err)
+		})
+	}
+}
+
+func TestLoad(t *testing.T) {
+	vcsRoot, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(vcsRoot)
+	require.NoError(t, os.MkdirAll(filepath.Join(vcsRoot, ".brigade"), 0755))
+	require.NoError(
+		t,
+		ioutil.WriteFile(
+			filepath.Join(vcsRoot, ".brigade", FileName),
+			[]byte("jobs:\n  maxConcurrency: 1\n"),
+			0644,
+		),
+	)
+	testCases := []struct {
+		name          string
+		event         brigade.Event
+		trustCheckout bool
+		assertions    func(*testing.T, Config, error)
+	}{
+		{
+			name:          "from checkout",
+			event:         eventWithConfig(".brigade", "jobs:\n  maxConcurrency: 2\n"),
+			trustCheckout: true,
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, cfg.Jobs.MaxConcurrency)
+			},
+		},
+		{
+			name:  "checkout not trusted",
+			event: eventWithConfig(".brigade", "jobs:\n  maxConcurrency: 2\n"),
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, cfg.Jobs.MaxConcurrency)
+			},
+		},
+		{
+			name:          "from project worker template",
+			event:         eventWithConfig("elsewhere", "jobs:\n  maxConcurrency: 2\n"),
+			trustCheckout: true,
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 2, cfg.Jobs.MaxConcurrency)
+			},
+		},
+		{
+			name:          "default",
+			event:         eventWithConfig("elsewhere", ""),
+			trustCheckout: true,
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, Default(), cfg)
+			},
+		},
+		{
+			name:  "invalid",
+			event: eventWithConfig("elsewhere", "jobs: []\n"),
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "from project worker template")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			cfg, err := Load(testCase.event, vcsRoot, testCase.trustCheckout)
+			testCase.assertions(t, cfg, err)
+		})
+	}
+}
+
+func TestLoadTriggerPlugins(t *testing.T) {
+	vcsRoot, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(vcsRoot)
+	pluginsDir, err := ioutil.TempDir("", "canard-test")
+	require.NoError(t, err)
+	defer os.RemoveAll(pluginsDir)
+	writeFile := func(path string, contents string, mode os.FileMode) {
+		require.NoError(t, os.MkdirAll(filepath.Dir(path), 0755))
+		require.NoError(t, ioutil.WriteFile(path, []byte(contents), mode))
+		// Unaffected by the umask
+		require.NoError(t, os.Chmod(path, mode))
+	}
+	writeFile(filepath.Join(pluginsDir, "trusted"), "#!/bin/sh\n", 0755)
+	writeFile(filepath.Join(pluginsDir, "writable"), "#!/bin/sh\n", 0777)
+	writeFile(filepath.Join(vcsRoot, "bin", "checkout"), "#!/bin/sh\n", 0755)
+	require.NoError(
+		t,
+		os.Symlink(
+			filepath.Join(vcsRoot, "bin", "checkout"),
+			filepath.Join(pluginsDir, "link"),
+		),
+	)
+	pluginConfig := func(path string) string {
+		return "triggers:\n  plugins:\n  - specUri: example.com/foo\n" +
+			"    path: " + path + "\n"
+	}
+	testCases := []struct {
+		name           string
+		checkoutConfig string
+		projectConfig  string
+		assertions     func(*testing.T, Config, error)
+	}{
+		{
+			name:          "from project worker template",
+			projectConfig: pluginConfig(filepath.Join(pluginsDir, "trusted")),
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Len(t, cfg.Triggers.Plugins, 1)
+			},
+		},
+		{
+			name:           "from project worker template despite checkout",
+			checkoutConfig: "jobs:\n  maxConcurrency: 1\n",
+			projectConfig:  pluginConfig(filepath.Join(pluginsDir, "trusted")),
+			assertions: func(t *testing.T, cfg Config, err error) {
+				require.NoError(t, err)
+				require.Equal(t, 1, cfg.Jobs.MaxConcurrency)
+				require.Len(t, cfg.Triggers.Plugins, 1)
+				require.Equal(
+					t,
+					filepath.Join(pluginsDir, "trusted"),
+					cfg.Triggers.Plugins[0].Path,
+				)
+			},
+		},
+		{
+			name:           "from checkout",
+			checkoutConfig: pluginConfig(filepath.Join(pluginsDir, "trusted")),
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(
+					t,
+					err.Error(),
+					"trigger plugins may only be specified by the project worker "+
+						"template",
+				)
+			},
+		},
+		{
+			name:          "within checkout",
+			projectConfig: pluginConfig(filepath.Join(vcsRoot, "bin", "checkout")),
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is within the checkout")
+			},
+		},
+		{
+			name:          "linked to within checkout",
+			projectConfig: pluginConfig(filepath.Join(pluginsDir, "link")),
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is within the checkout")
+			},
+		},
+		{
+			name:          "writable by anyone",
+			projectConfig: pluginConfig(filepath.Join(pluginsDir, "writable")),
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "is writable by anyone")
+			},
+		},
+		{
+			name:          "nonexistent",
+			projectConfig: pluginConfig(filepath.Join(pluginsDir, "missing")),
+			assertions: func(t *testing.T, _ Config, err error) {
+				require.Error(t, err)
+				require.Contains(t, err.Error(), "error resolving trigger plugin")
+			},
+		},
+	}
+	for _, testCase := range testCases {
+		t.Run(testCase.name, func(t *testing.T) {
+			configPath := filepath.Join(vcsRoot, ".brigade", FileName)
+			require.NoError(t, os.RemoveAll(configPath))
+			if testCase.checkoutConfig != "" {
+				writeFile(configPath, testCase.checkoutConfig, 0644)
+			}
+			cfg, err := Load(
+				eventWithConfig(".brigade", testCase.projectConfig),
+				vcsRoot,
+				true,
+			)
+			testCase.assertions(t, cfg, err)
+		})
+	}
+}
+
+func eventWithConfig(configFilesDirectory, defaultConfig string) brigade.Event {
+	event := brigade.Event{
+		Worker: brigade.Worker{
+			ConfigFilesDirectory: configFilesDirectory,
+		},
+	}
+	if defaultConfig != "" {
+		event.Worker.DefaultConfigFiles = map[string]string{
+			FileName: defaultConfig,
+		}
+	}
+	return event
+}
@@ -0,0 +1,180 @@
+package workerconfig
+
+// nolint: lll
+var jsonSchemaBytes = []byte(`
+{
+	"$schema": "http://json-schema.org/draft-07/schema#",
+	"$id": "github.com/lovethedrake/canard/workerconfig.schema.json",
+
+	"definitions": {
+
+		"duration": {
+			"type": "string",
+			"pattern": "^([0-9]+(\\.[0-9]+)?(ns|us|µs|ms|s|m|h))+$"
+		},
+
+		"triggerPlugin": {
+			"type": "object",
+			"description": "An executable in the worker image that implements a trigger spec",
+			"required": ["specUri", "path"],
+			"additionalProperties": false,
+			"properties": {
+				"specUri": {
+					"type": "string",
+					"description": "Identifies the trigger spec implemented by the plugin",
+					"minLength": 1
+				},
+				"specVersions": {
+					"type": "string",
+					"description": "The range of versions of the trigger spec implemented by the plugin",
+					"minLength": 1
+				},
+				"path": {
+					"type": "string",
+					"description": "The absolute path to the plugin",
+					"pattern": "^/"
+				},
+				"timeout": {
+					"allOf": [{ "$ref": "#/definitions/duration" }],
+					"description": "How long the plugin is given to reach a decision"
+				}
+			}
+		},
+
+		"reportingTarget": {
+			"type": "object",
+			"description": "A destination to which a summary of the build is reported",
+			"required": ["type"],
+			"additionalProperties": false,
+			"properties": {
+				"type": {
+					"type": "string",
+					"description": "The type of target",
+					"enum": [ "log", "file" ]
+				},
+				"path": {
+					"type": "string",
+					"description": "For targets of type file, the path to which the summary is written",
+					"minLength": 1
+				}
+			},
+			"if": {
+				"properties": { "type": { "const": "file" } }
+			},
+			"then": {
+				"required": ["path"]
+			}
+		}
+
+	},
+
+	"title": "Config",
+	"type": "object",
+	"additionalProperties": false,
+	"properties": {
+		"tls": {
+			"type": "object",
+			"description": "Settings for connections to the Brigade API server",
+			"additionalProperties": false,
+			"properties": {
+				"allowInsecureConnections": {
+					"type": "boolean",
+					"description": "Whether TLS errors should be ignored when connecting to the Brigade API server"
+				}
+			}
+		},
+		"jobs": {
+			"type": "object",
+			"description": "Settings for the execution of jobs",
+			"additionalProperties": false,
+			"properties": {
+				"maxConcurrency": {
+					"type": "integer",
+					"description": "The maximum number of jobs to execute concurrently, across all pipelines; zero means no limit",
+					"minimum": 0
+				},
+				"defaultTimeout": {
+					"allOf": [{ "$ref": "#/definitions/duration" }],
+					"description": "How long to wait for a job that doesn't specify its own timeout to complete"
+				}
+			}
+		},
+		"drakefile": {
+			"type": "object",
+			"description": "Settings for locating and pre-processing the Drakefile",
+			"additionalProperties": false,
+			"properties": {
+				"fileNames": {
+					"type": "array",
+					"description": "Names of files, relative to each directory searched, that are recognized as Drakefiles, in order of preference",
+					"items": {
+						"type": "string",
+						"minLength": 1
+					}
+				},
+				"trustForkPullRequests": {
+					"type": "boolean",
+					"description": "Whether Drakefiles from the checkout may be used for pull requests from forks"
+				},
+				"templateEnv": {
+					"type": "array",
+					"description": "Names of environment variables exposed to Drakefile templates",
+					"items": {
+						"type": "string",
+						"minLength": 1
+					}
+				},
+				"services": {
+					"type": "array",
+					"description": "Glob patterns, relative to the root of the checkout, matching directories that each contain a service's own Drakefile",
+					"items": {
+						"type": "string",
+						"minLength": 1
+					}
+				}
+			}
+		},
+		"triggers": {
+			"type": "object",
+			"description": "Settings for evaluating pipeline triggers",
+			"additionalProperties": false,
+			"properties": {
+				"plugins": {
+					"type": "array",
+					"items": { "$ref": "#/definitions/triggerPlugin" }
+				}
+			}
+		},
+		"reporting": {
+			"type": "object",
+			"description": "Settings for reporting a summary of the build",
+			"additionalProperties": false,
+			"properties": {
+				"targets": {
+					"type": "array",
+					"items": { "$ref": "#/definitions/reportingTarget" }
+				}
+			}
+		},
+		"features": {
+			"type": "object",
+			"description": "Toggles for optional features",
+			"additionalProperties": false,
+			"properties": {
+				"includes": {
+					"type": "boolean",
+					"description": "Whether Drakefile includes are expanded"
+				},
+				"templating": {
+					"type": "boolean",
+					"description": "Whether Drakefiles may opt into templating"
+				},
+				"branchOverlays": {
+					"type": "boolean",
+					"description": "Whether branch overlays are applied"
+				}
+			}
+		}
+	}
+}
+`)